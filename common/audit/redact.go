@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"one-api/common/config"
+	"one-api/common/logger"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type redactRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// 内置的脱敏规则，通过 AuditLogRedactRules 选择启用
+var builtinRedactRules = map[string][]redactRule{
+	"api_key": {
+		{regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`), "Bearer [REDACTED_KEY]"},
+		{regexp.MustCompile(`\b(sk|ak|pk|rk)-[A-Za-z0-9_\-]{16,}`), "[REDACTED_KEY]"},
+		{regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`), "[REDACTED_KEY]"},
+	},
+	"email": {
+		{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	},
+	"phone": {
+		{regexp.MustCompile(`(^|[^\d])(1[3-9]\d{9})([^\d]|$)`), "${1}[REDACTED_PHONE]${3}"},
+		{regexp.MustCompile(`\+\d{1,3}[\s\-]?\d{6,14}\b`), "[REDACTED_PHONE]"},
+	},
+}
+
+var redactor = struct {
+	sync.Mutex
+	source string
+	rules  []redactRule
+}{}
+
+// Redact 按当前配置的规则替换敏感内容
+func Redact(text string) string {
+	for _, rule := range getRedactRules() {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	return text
+}
+
+// getRedactRules 配置修改后重新编译规则
+func getRedactRules() []redactRule {
+	redactor.Lock()
+	defer redactor.Unlock()
+
+	source := config.AuditLogRedactRules + "\n" + config.AuditLogRedactPatterns
+	if redactor.rules != nil && redactor.source == source {
+		return redactor.rules
+	}
+
+	rules := make([]redactRule, 0)
+	for _, name := range strings.Split(config.AuditLogRedactRules, ",") {
+		rules = append(rules, builtinRedactRules[strings.TrimSpace(name)]...)
+	}
+	for _, line := range strings.Split(config.AuditLogRedactPatterns, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			logger.SysError("invalid audit log redact pattern: " + line)
+			continue
+		}
+		rules = append(rules, redactRule{pattern, "[REDACTED]"})
+	}
+
+	redactor.source = source
+	redactor.rules = rules
+	return rules
+}
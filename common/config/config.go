@@ -31,8 +31,21 @@ func defaultConfig() {
 	viper.SetDefault("sqlite_busy_timeout", 3000)
 	viper.SetDefault("sync_frequency", 600)
 	viper.SetDefault("batch_update_interval", 5)
+	viper.SetDefault("batch_update_max_records", 1000)
+	viper.SetDefault("batch_update_log_interval", 500)
+	viper.SetDefault("batch_update_log_size", 500)
+	viper.SetDefault("batch_update_queue_size", 10000)
 	viper.SetDefault("global.api_rate_limit", 180)
 	viper.SetDefault("global.web_rate_limit", 100)
 	viper.SetDefault("connect_timeout", 5)
 	viper.SetDefault("auto_price_updates", true)
+	viper.SetDefault("batch.max_file_size", 100)
+	viper.SetDefault("batch.chunk_size", 100)
+	viper.SetDefault("batch.concurrency", 2)
+	viper.SetDefault("otel.service_name", "one-hub")
+	viper.SetDefault("otel.sample_ratio", 1)
+	viper.SetDefault("notify.webhook.timeout", 10)
+	viper.SetDefault("notify.webhook.max_retries", 3)
+	viper.SetDefault("notify.webhook.retry_interval", 5)
+	viper.SetDefault("notify.webhook.delivery_retention_days", 30)
 }
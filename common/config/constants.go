@@ -44,6 +44,21 @@ var RegisterEnabled = true
 var ChatCacheEnabled = false
 var ChatCacheExpireMinute = 5 // 5 Minute
 
+// 语义缓存，使用嵌入模型计算最后一条用户消息的相似度
+// 嵌入模型的用量计入请求的令牌；向量索引只保存在当前节点，多节点之间不共享
+var ChatCacheSemanticEnabled = false
+var ChatCacheEmbeddingChannelId = 0
+var ChatCacheEmbeddingModel = "text-embedding-3-small"
+var ChatCacheSemanticMaxEntries = 10000
+
+// 请求审计日志，开启后对指定分组和令牌记录请求与响应内容
+var AuditLogEnabled = false
+var AuditLogGroups = ""                         // 逗号分隔，这些分组的请求全部记录
+var AuditLogMaxBodySize = 32                    // KB，请求和响应分别截断，MySQL 下最大 64KB
+var AuditLogRetentionDays = 7                   // 超过天数的审计日志会被定时清理
+var AuditLogRedactRules = "api_key,email,phone" // 内置脱敏规则
+var AuditLogRedactPatterns = ""                 // 自定义脱敏正则，每行一个
+
 // mj
 var MjNotifyEnabled = false
 
@@ -63,6 +78,7 @@ var EmailDomainWhitelist = []string{
 var MemoryCacheEnabled = false
 
 var LogConsumeEnabled = true
+var LogErrorEnabled = true
 
 var SMTPServer = ""
 var SMTPPort = 587
@@ -99,6 +115,27 @@ var RetryTimes = 0
 var DefaultChannelWeight = uint(1)
 var RetryCooldownSeconds = 5
 
+// 渠道的默认负载均衡策略
+var ChannelBalanceStrategy = "weight"
+
+// 渠道亲和：相同会话或用户的请求尽量分配到同一个渠道
+var ChannelAffinityEnabled = false
+var ChannelAffinityHeader = "X-Session-Id"
+var ChannelAffinityMessages = 2
+
+// 熔断器：窗口内失败次数达到阈值后熔断，阈值为 0 时不熔断
+var CircuitBreakerThreshold = 5
+var CircuitBreakerWindowSeconds = 60
+var CircuitBreakerOpenSeconds = 30
+var CircuitBreakerHalfOpenRequests = 1
+
+// 渠道达到并发、RPM、TPM 限制时的排队设置
+var ChannelLimitQueueSize = 100
+var ChannelLimitWaitSeconds = 3
+
+// 批处理请求的计费折扣
+var BatchDiscount = 0.5
+
 var CFWorkerImageUrl = ""
 var CFWorkerImageKey = ""
 
@@ -110,6 +147,10 @@ var RequestInterval time.Duration
 
 var BatchUpdateEnabled = false
 var BatchUpdateInterval = 5
+var BatchUpdateMaxRecords = 1000 // 待更新的记录达到数量时立即写入
+var BatchUpdateLogInterval = 500 // 毫秒
+var BatchUpdateLogSize = 500     // 日志达到条数时立即写入
+var BatchUpdateQueueSize = 10000 // 日志队列满时直接写入数据库
 
 const (
 	RoleGuestUser  = 0
@@ -251,6 +292,8 @@ const (
 	RelayModeAudioTranscription
 	RelayModeAudioTranslation
 	RelayModeSuno
+	RelayModeRerank
+	RelayModeBatch
 )
 
 type ContextKey string
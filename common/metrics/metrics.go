@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "one_hub"
+
+var (
+	relayRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Number of upstream relay requests.",
+	}, []string{"channel", "model", "group", "endpoint", "code"})
+
+	relayDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_request_duration_seconds",
+		Help:      "Total duration of upstream relay requests.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"channel", "model", "group", "endpoint"})
+
+	relayFirstToken = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_first_token_seconds",
+		Help:      "Time to first token of upstream relay requests.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"channel", "model", "group", "endpoint"})
+
+	relayRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_retries_total",
+		Help:      "Number of relay retries after an upstream error.",
+	}, []string{"model", "group", "endpoint"})
+
+	channelEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_events_total",
+		Help:      "Channel disable, enable and cooldown events.",
+	}, []string{"channel", "event"})
+
+	quotaConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Quota consumed by relay requests.",
+	}, []string{"channel", "model", "group"})
+
+	chatCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_cache_requests_total",
+		Help:      "Chat cache lookups by result.",
+	}, []string{"result"})
+
+	batchQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "batch_queue_depth",
+		Help:      "Number of logs and quota updates waiting to be written.",
+	}, []string{"queue"})
+
+	batchFlushed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batch_flushed_total",
+		Help:      "Number of logs and quota updates written by the batch writer.",
+	}, []string{"queue"})
+)
+
+const (
+	ChannelEventDisable  = "disable"
+	ChannelEventEnable   = "enable"
+	ChannelEventCooldown = "cooldown"
+)
+
+// RecordRelayRequest 记录一次上游请求，code 为响应的状态码
+func RecordRelayRequest(channelId int, model, group, endpoint string, code int, duration, firstToken time.Duration) {
+	channel := strconv.Itoa(channelId)
+	relayRequests.WithLabelValues(channel, model, group, endpoint, strconv.Itoa(code)).Inc()
+	relayDuration.WithLabelValues(channel, model, group, endpoint).Observe(duration.Seconds())
+	relayFirstToken.WithLabelValues(channel, model, group, endpoint).Observe(firstToken.Seconds())
+}
+
+func RecordRelayRetry(model, group, endpoint string) {
+	relayRetries.WithLabelValues(model, group, endpoint).Inc()
+}
+
+func RecordChannelEvent(channelId int, event string) {
+	channelEvents.WithLabelValues(strconv.Itoa(channelId), event).Inc()
+}
+
+func RecordQuotaConsumed(channelId int, model, group string, quota int) {
+	quotaConsumed.WithLabelValues(strconv.Itoa(channelId), model, group).Add(float64(quota))
+}
+
+func RecordChatCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	chatCacheRequests.WithLabelValues(result).Inc()
+}
+
+const (
+	BatchQueueLogs  = "logs"
+	BatchQueueQuota = "quota"
+)
+
+func SetBatchQueueDepth(queue string, depth int) {
+	batchQueueDepth.WithLabelValues(queue).Set(float64(depth))
+}
+
+func RecordBatchFlushed(queue string, count int) {
+	batchFlushed.WithLabelValues(queue).Add(float64(count))
+}
@@ -0,0 +1,85 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"one-api/common/requester"
+	"strconv"
+	"time"
+)
+
+type Webhook struct {
+	url     string
+	secret  string
+	timeout time.Duration
+}
+
+func NewWebhook(url, secret string, timeout int) *Webhook {
+	return &Webhook{
+		url:     url,
+		secret:  secret,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+}
+
+func (w *Webhook) Name() string {
+	return "Webhook"
+}
+
+func (w *Webhook) URL() string {
+	return w.url
+}
+
+// Post 发送事件，返回对方的状态码，非 2xx 视为失败
+func (w *Webhook) Post(ctx context.Context, eventType, deliveryId string, body []byte) (int, error) {
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := requester.GetJsonHeaders()
+	headers["User-Agent"] = "one-hub-webhook"
+	headers["X-One-Hub-Event"] = eventType
+	headers["X-One-Hub-Delivery"] = deliveryId
+	headers["X-One-Hub-Timestamp"] = timestamp
+	if w.secret != "" {
+		headers["X-One-Hub-Signature"] = "sha256=" + w.sign(timestamp, body)
+	}
+
+	client := requester.NewHTTPRequester("", nil)
+	client.Context = ctx
+	client.IsOpenAI = false
+
+	req, err := client.NewRequest(http.MethodPost, w.url, client.WithHeader(headers), client.WithBody(bytes.NewReader(body)))
+	if err != nil {
+		return 0, err
+	}
+
+	resp, errWithOP := client.SendRequestRaw(req)
+	if errWithOP != nil {
+		// 连接失败时没有收到响应，不记录状态码
+		if errWithOP.Code == "http_request_failed" {
+			return 0, fmt.Errorf("%s", errWithOP.Message)
+		}
+		return errWithOP.StatusCode, fmt.Errorf("%s", errWithOP.Message)
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// sign 对 "时间戳.请求体" 做 HMAC-SHA256，接收方用同样的方式校验
+func (w *Webhook) sign(timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(w.secret))
+	h.Write([]byte(timestamp + "."))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,106 @@
+package channel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"one-api/common/logger"
+	"one-api/common/requester"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWebhookSign(t *testing.T) {
+	cases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		expected  string
+	}{
+		{
+			name:      "json body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"event":"ping"}`,
+			expected:  "4d39bd2442f073b6bc62e95d0297ce25475582a17389ab860abdc778fe1d9f77",
+		},
+		{
+			name:      "empty body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      "",
+			expected:  "4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5",
+		},
+		{
+			name:      "different secret",
+			secret:    "other",
+			timestamp: "1700000000",
+			body:      `{"event":"ping"}`,
+			expected:  "0b745d77e8146ca45a844ad89177fce05067c8ba592d8a848a1837088acdd617",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook := NewWebhook("", tc.secret, 0)
+			assert.Equal(t, tc.expected, webhook.sign(tc.timestamp, []byte(tc.body)))
+		})
+	}
+}
+
+func TestWebhookPost(t *testing.T) {
+	requester.InitHttpClient()
+	defaultLogger := logger.Logger
+	logger.Logger = zap.NewNop()
+	defer func() { logger.Logger = defaultLogger }()
+
+	cases := []struct {
+		name       string
+		secret     string
+		statusCode int
+		hasError   bool
+	}{
+		{"signed", "secret", http.StatusOK, false},
+		{"unsigned", "", http.StatusNoContent, false},
+		{"server error", "secret", http.StatusInternalServerError, true},
+	}
+
+	body := []byte(`{"event":"ping"}`)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook := NewWebhook("", tc.secret, 5)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ := io.ReadAll(r.Body)
+				assert.Equal(t, body, received)
+				assert.Equal(t, "ping", r.Header.Get("X-One-Hub-Event"))
+				assert.Equal(t, "delivery-1", r.Header.Get("X-One-Hub-Delivery"))
+
+				signature := r.Header.Get("X-One-Hub-Signature")
+				if tc.secret == "" {
+					assert.Empty(t, signature)
+				} else {
+					// 接收方用时间戳和请求体重新计算签名
+					assert.Equal(t, "sha256="+webhook.sign(r.Header.Get("X-One-Hub-Timestamp"), received), signature)
+				}
+
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer server.Close()
+			webhook.url = server.URL
+
+			statusCode, err := webhook.Post(context.Background(), "ping", "delivery-1", body)
+			assert.Equal(t, tc.statusCode, statusCode)
+			assert.Equal(t, tc.hasError, err != nil)
+		})
+	}
+
+	// 连接失败时没有状态码
+	statusCode, err := NewWebhook("http://127.0.0.1:1", "", 5).Post(context.Background(), "ping", "delivery-1", body)
+	assert.Equal(t, 0, statusCode)
+	assert.NotNil(t, err)
+}
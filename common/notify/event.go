@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/notify/channel"
+	"one-api/common/utils"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventChannelDisabled  EventType = "channel_disabled"
+	EventChannelRecovered EventType = "channel_recovered"
+	EventBalanceLow       EventType = "balance_low"
+	EventQuotaExhausted   EventType = "quota_exhausted"
+	EventPaymentSucceeded EventType = "payment_succeeded"
+	EventTaskFailed       EventType = "task_failed"
+)
+
+// Event webhook 推送的请求体
+type Event struct {
+	Id        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt int64     `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type ChannelEventData struct {
+	ChannelId   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type QuotaEventData struct {
+	UserId      int    `json:"user_id"`
+	TokenId     int    `json:"token_id"`
+	TokenName   string `json:"token_name"`
+	RemainQuota int    `json:"remain_quota"`
+	Threshold   int    `json:"threshold"`
+}
+
+type PaymentEventData struct {
+	TradeNo        string  `json:"trade_no"`
+	UserId         int     `json:"user_id"`
+	OrganizationId int     `json:"organization_id,omitempty"`
+	Amount         int     `json:"amount"`
+	Quota          int     `json:"quota"`
+	OrderAmount    float64 `json:"order_amount"`
+	OrderCurrency  string  `json:"order_currency"`
+}
+
+type TaskEventData struct {
+	Platform   string `json:"platform"`
+	TaskId     string `json:"task_id"`
+	Action     string `json:"action"`
+	UserId     int    `json:"user_id"`
+	ChannelId  int    `json:"channel_id"`
+	FailReason string `json:"fail_reason"`
+	// 退还给用户的额度
+	RefundQuota int `json:"refund_quota"`
+}
+
+// Delivery 一次事件投递的结果，包含所有重试
+type Delivery struct {
+	EventId    string
+	EventType  EventType
+	URL        string
+	StatusCode int
+	Attempts   int
+	Success    bool
+	Error      string
+	Payload    string
+	Duration   int64
+}
+
+type eventWebhook struct {
+	webhook    *channel.Webhook
+	events     map[EventType]bool
+	maxRetries int
+	retryDelay time.Duration
+}
+
+var eventNotifier *eventWebhook
+
+var deliveryRecorder func(*Delivery)
+
+// SetDeliveryRecorder 设置投递记录的保存方式，避免 notify 依赖 model
+func SetDeliveryRecorder(recorder func(*Delivery)) {
+	deliveryRecorder = recorder
+}
+
+// SendEvent 异步推送事件到 webhook，未配置或未订阅该事件时忽略
+func SendEvent(eventType EventType, data any) {
+	if eventNotifier == nil || !eventNotifier.subscribed(eventType) {
+		return
+	}
+
+	event := &Event{
+		Id:        utils.GetUUID(),
+		Type:      eventType,
+		CreatedAt: time.Now().Unix(),
+		Data:      data,
+	}
+
+	go eventNotifier.deliver(event)
+}
+
+func (e *eventWebhook) subscribed(eventType EventType) bool {
+	return len(e.events) == 0 || e.events[eventType]
+}
+
+func (e *eventWebhook) deliver(event *Event) {
+	//lint:ignore SA1029 reason: 需要使用该类型作为错误处理
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, "NotifyTask")
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("webhook event %s marshal err: %s", event.Type, err.Error()))
+		return
+	}
+
+	delivery := &Delivery{
+		EventId:   event.Id,
+		EventType: event.Type,
+		URL:       e.webhook.URL(),
+		Payload:   string(body),
+	}
+
+	startTime := time.Now()
+	for attempt := 0; attempt <= e.maxRetries; attempt++ {
+		if attempt > 0 {
+			// 指数退避
+			time.Sleep(e.retryDelay * time.Duration(1<<(attempt-1)))
+		}
+
+		delivery.Attempts = attempt + 1
+		delivery.StatusCode, err = e.webhook.Post(ctx, string(event.Type), event.Id, body)
+		if err == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		logger.LogError(ctx, fmt.Sprintf("webhook event %s attempt %d err: %s", event.Type, delivery.Attempts, err.Error()))
+	}
+	delivery.Duration = time.Since(startTime).Milliseconds()
+
+	if deliveryRecorder != nil {
+		deliveryRecorder(delivery)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"one-api/common/logger"
 	"one-api/common/notify/channel"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -19,6 +21,7 @@ func InitNotifier() {
 	InitLarkNotifier()
 	InitPushdeerNotifier()
 	InitTelegramNotifier()
+	InitWebhookNotifier()
 }
 
 func InitEmailNotifier() {
@@ -97,3 +100,27 @@ func InitTelegramNotifier() {
 	AddNotifiers(telegramNotifier)
 	logger.SysLog("telegram notifier enable")
 }
+
+// InitWebhookNotifier webhook 只接收结构化事件，不接收文本通知
+func InitWebhookNotifier() {
+	url := viper.GetString("notify.webhook.url")
+	if url == "" {
+		return
+	}
+
+	events := make(map[EventType]bool)
+	for _, event := range viper.GetStringSlice("notify.webhook.events") {
+		event = strings.TrimSpace(event)
+		if event != "" {
+			events[EventType(event)] = true
+		}
+	}
+
+	eventNotifier = &eventWebhook{
+		webhook:    channel.NewWebhook(url, viper.GetString("notify.webhook.secret"), viper.GetInt("notify.webhook.timeout")),
+		events:     events,
+		maxRetries: viper.GetInt("notify.webhook.max_retries"),
+		retryDelay: time.Duration(viper.GetInt("notify.webhook.retry_interval")) * time.Second,
+	}
+	logger.SysLog("webhook notifier enable")
+}
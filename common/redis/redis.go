@@ -70,6 +70,26 @@ func RedisDel(key string) error {
 	return RDB.Del(ctx, key).Err()
 }
 
+// RedisScanKeys 使用 SCAN 遍历匹配的 key，避免 KEYS 阻塞
+func RedisScanKeys(pattern string) ([]string, error) {
+	ctx := context.Background()
+	var keys []string
+	iter := RDB.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// RedisDelKeys 删除多个 key，返回删除的数量
+func RedisDelKeys(keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	ctx := context.Background()
+	return RDB.Del(ctx, keys...).Result()
+}
+
 func RedisDecrease(key string, value int64) error {
 	ctx := context.Background()
 	return RDB.DecrBy(ctx, key, value).Err()
@@ -73,7 +73,12 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 }
 
 // 发送请求
-func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	req, span := startUpstreamSpan(req)
+	defer func() {
+		endUpstreamSpan(span, resp, errWithCode)
+	}()
+
 	resp, err := HTTPClient.Do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
@@ -112,7 +117,12 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 }
 
 // 发送请求 RAW
-func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+func (r *HTTPRequester) SendRequestRaw(req *http.Request) (resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	req, span := startUpstreamSpan(req)
+	defer func() {
+		endUpstreamSpan(span, resp, errWithCode)
+	}()
+
 	// 发送请求
 	resp, err := HTTPClient.Do(req)
 	if err != nil {
@@ -0,0 +1,35 @@
+package requester
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common/telemetry"
+	"one-api/types"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startUpstreamSpan 每次向上游发起请求都会创建一个 span，重试时为同级的多个 span
+func startUpstreamSpan(req *http.Request) (*http.Request, trace.Span) {
+	ctx, span := telemetry.StartSpan(req.Context(), "relay.upstream",
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.path", req.URL.Path),
+	)
+
+	return req.WithContext(ctx), span
+}
+
+func endUpstreamSpan(span trace.Span, resp *http.Response, errWithCode *types.OpenAIErrorWithStatusCode) {
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+
+	var err error
+	if errWithCode != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", errWithCode.StatusCode))
+		err = errors.New(errWithCode.Message)
+	}
+	telemetry.EndSpan(span, err)
+}
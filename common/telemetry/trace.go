@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"context"
+	"one-api/common/logger"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "one-api"
+
+var tracerProvider *sdktrace.TracerProvider
+
+// InitTracer 配置了 otel.endpoint 时才会导出链路数据，否则使用默认的空实现
+func InitTracer() {
+	// 即使不导出也解析客户端传入的 traceparent，保证日志中的链路 id 一致
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	endpoint := viper.GetString("otel.endpoint")
+	if endpoint == "" {
+		return
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+	if headers := viper.GetStringMapString("otel.headers"); len(headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		logger.SysError("failed to create otlp exporter: " + err.Error())
+		return
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(viper.GetString("otel.service_name")),
+	))
+	if err != nil {
+		logger.SysError("failed to create otlp resource: " + err.Error())
+		return
+	}
+
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(viper.GetFloat64("otel.sample_ratio")))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	logger.SysLog("OpenTelemetry tracing is enabled, endpoint: " + endpoint)
+}
+
+// ShutdownTracer 退出前导出剩余的链路数据
+func ShutdownTracer(ctx context.Context) {
+	if tracerProvider == nil {
+		return
+	}
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		logger.SysError("failed to shutdown tracer provider: " + err.Error())
+	}
+}
+
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServerSpan 创建入站请求的 span
+func StartServerSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// EndSpan 结束 span，err 不为空时标记为错误
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// DetachContext 只保留链路信息，不继承请求的取消和超时
+func DetachContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
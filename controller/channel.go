@@ -24,6 +24,10 @@ func GetChannelsList(c *gin.Context) {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
 	}
+
+	for _, channel := range *channels.Data {
+		channel.CircuitBreaker = model.ChannelGroup.GetCircuitBreakerStatus(channel.Id)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -48,6 +52,7 @@ func GetChannel(c *gin.Context) {
 		})
 		return
 	}
+	channel.CircuitBreaker = model.ChannelGroup.GetCircuitBreakerStatus(channel.Id)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -55,6 +60,20 @@ func GetChannel(c *gin.Context) {
 	})
 }
 
+func ResetChannelCircuitBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	model.ChannelGroup.ResetCircuitBreaker(id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
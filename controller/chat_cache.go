@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetChatCacheList(c *gin.Context) {
+	var params model.SearchChatCacheParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	caches, err := relay_util.GetChatCacheManager().List(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    caches,
+	})
+}
+
+func DeleteChatCache(c *gin.Context) {
+	count, err := relay_util.GetChatCacheManager().Delete(c.Param("hash"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+func DeleteUserChatCache(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	count, err := relay_util.GetChatCacheManager().DeleteByUser(userId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+func FlushChatCache(c *gin.Context) {
+	count, err := relay_util.GetChatCacheManager().Flush()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+func GetChatCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    relay_util.GetChatCacheStats(),
+	})
+}
+
+func ResetChatCacheStats(c *gin.Context) {
+	relay_util.ResetChatCacheStats()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
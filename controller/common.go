@@ -97,6 +97,12 @@ func ShouldDisableChannel(channelType int, err *types.OpenAIErrorWithStatusCode)
 // disable & notify
 func DisableChannel(channelId int, channelName string, reason string, sendNotify bool) {
 	model.UpdateChannelStatusById(channelId, config.ChannelStatusAutoDisabled)
+	// 测速时文本通知会汇总发送，事件仍然逐个推送
+	notify.SendEvent(notify.EventChannelDisabled, &notify.ChannelEventData{
+		ChannelId:   channelId,
+		ChannelName: channelName,
+		Reason:      reason,
+	})
 	if !sendNotify {
 		return
 	}
@@ -109,6 +115,10 @@ func DisableChannel(channelId int, channelName string, reason string, sendNotify
 // enable & notify
 func EnableChannel(channelId int, channelName string, sendNotify bool) {
 	model.UpdateChannelStatusById(channelId, config.ChannelStatusEnabled)
+	notify.SendEvent(notify.EventChannelRecovered, &notify.ChannelEventData{
+		ChannelId:   channelId,
+		ChannelName: channelName,
+	})
 	if !sendNotify {
 		return
 	}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/common/logger"
+	"one-api/common/notify"
 	"one-api/model"
 	"one-api/providers"
 	provider "one-api/providers/midjourney"
@@ -200,6 +201,15 @@ func MjTaskHandler(ctx context.Context, mjProvider *provider.MidjourneyProvider,
 					model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
 				}
 			}
+			notify.SendEvent(notify.EventTaskFailed, &notify.TaskEventData{
+				Platform:    "midjourney",
+				TaskId:      task.MjId,
+				Action:      task.Action,
+				UserId:      task.UserId,
+				ChannelId:   task.ChannelId,
+				FailReason:  task.FailReason,
+				RefundQuota: task.Quota,
+			})
 		}
 		err = task.Update()
 		if err != nil {
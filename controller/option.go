@@ -75,6 +75,38 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "ChannelBalanceStrategy":
+		if !model.IsValidBalanceStrategy(option.Value) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无效的负载均衡策略",
+			})
+			return
+		}
+	case "ModelFallbacks":
+		if err := model.UpdateModelFallbacksByJSONString(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "备用模型配置格式错误：" + err.Error(),
+			})
+			return
+		}
+	case "BatchDiscount":
+		if err := model.ValidateBatchDiscount(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "ChannelBalanceRules":
+		if err := model.UpdateBalanceRulesByJSONString(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "负载均衡规则格式错误：" + err.Error(),
+			})
+			return
+		}
 	}
 	err = model.UpdateOption(option.Key, option.Value)
 	if err != nil {
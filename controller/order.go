@@ -10,6 +10,7 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/notify"
 	"one-api/common/utils"
 	"one-api/model"
 	"one-api/payment"
@@ -21,6 +22,8 @@ import (
 type OrderRequest struct {
 	UUID   string `json:"uuid" binding:"required"`
 	Amount int    `json:"amount" binding:"required"`
+	// 充值到组织，需要是组织的所有者或管理员
+	OrganizationId int `json:"organization_id"`
 }
 
 type OrderResponse struct {
@@ -44,6 +47,12 @@ func CreateOrder(c *gin.Context) {
 	}
 
 	userId := c.GetInt("id")
+	if orderReq.OrganizationId > 0 {
+		if err := checkOrganizationManager(orderReq.OrganizationId, userId); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+	}
 	// 关闭用户未完成的订单
 	go model.CloseUnfinishedOrder()
 
@@ -65,16 +74,17 @@ func CreateOrder(c *gin.Context) {
 
 	// 创建订单
 	order := &model.Order{
-		UserId:        userId,
-		GatewayId:     paymentService.Payment.ID,
-		TradeNo:       tradeNo,
-		Amount:        orderReq.Amount,
-		OrderAmount:   payMoney,
-		OrderCurrency: paymentService.Payment.Currency,
-		Fee:           fee,
-		Discount:      discount,
-		Status:        model.OrderStatusPending,
-		Quota:         orderReq.Amount * int(config.QuotaPerUnit),
+		UserId:         userId,
+		GatewayId:      paymentService.Payment.ID,
+		TradeNo:        tradeNo,
+		Amount:         orderReq.Amount,
+		OrderAmount:    payMoney,
+		OrderCurrency:  paymentService.Payment.Currency,
+		Fee:            fee,
+		Discount:       discount,
+		Status:         model.OrderStatusPending,
+		Quota:          orderReq.Amount * int(config.QuotaPerUnit),
+		OrganizationId: orderReq.OrganizationId,
 	}
 
 	err = order.Insert()
@@ -157,6 +167,16 @@ func PaymentCallback(c *gin.Context) {
 		return
 	}
 
+	if order.OrganizationId > 0 {
+		err = model.TopUpOrganization(order.OrganizationId, order.UserId, order.Quota, "在线充值成功，")
+		if err != nil {
+			logger.SysError(fmt.Sprintf("gateway callback failed to increase organization quota, trade_no: %s,", payNotify.TradeNo))
+			return
+		}
+		sendPaymentSucceededEvent(order)
+		return
+	}
+
 	err = model.IncreaseUserQuota(order.UserId, order.Quota)
 	if err != nil {
 		logger.SysError(fmt.Sprintf("gateway callback failed to increase user quota, trade_no: %s,", payNotify.TradeNo))
@@ -164,7 +184,19 @@ func PaymentCallback(c *gin.Context) {
 	}
 
 	model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线充值成功，充值积分: %d，支付金额：%.2f %s", order.Quota, order.OrderAmount, order.OrderCurrency))
+	sendPaymentSucceededEvent(order)
+}
 
+func sendPaymentSucceededEvent(order *model.Order) {
+	notify.SendEvent(notify.EventPaymentSucceeded, &notify.PaymentEventData{
+		TradeNo:        order.TradeNo,
+		UserId:         order.UserId,
+		OrganizationId: order.OrganizationId,
+		Amount:         order.Amount,
+		Quota:          order.Quota,
+		OrderAmount:    order.OrderAmount,
+		OrderCurrency:  string(order.OrderCurrency),
+	})
 }
 
 func CheckOrderStatus(c *gin.Context) {
@@ -0,0 +1,345 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkOrganizationManager 只有组织的所有者和管理员可以为组织充值
+func checkOrganizationManager(organizationId, userId int) error {
+	member, err := model.GetOrganizationMember(organizationId, userId)
+	if err != nil {
+		return err
+	}
+	if !model.CanManageOrganization(member.Role) {
+		return errors.New("无权管理该组织")
+	}
+	return nil
+}
+
+func getOrganizationMemberByParam(c *gin.Context) (*model.OrganizationMember, error) {
+	organizationId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	return model.GetOrganizationMember(organizationId, c.GetInt("id"))
+}
+
+func GetUserOrganizations(c *gin.Context) {
+	organizations, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organizations,
+	})
+}
+
+func GetOrganization(c *gin.Context) {
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	organization, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	organization.Role = member.Role
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organization,
+	})
+}
+
+type organizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func CreateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if len(req.Name) > 64 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("组织名称过长"))
+		return
+	}
+
+	organization, err := model.CreateOrganization(req.Name, c.GetInt("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	organization.Role = model.OrganizationRoleOwner
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organization,
+	})
+}
+
+func UpdateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if len(req.Name) > 64 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("组织名称过长"))
+		return
+	}
+
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if !model.CanManageOrganization(member.Role) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无权管理该组织"))
+		return
+	}
+
+	organization := &model.Organization{Id: member.OrganizationId, Name: req.Name}
+	if err := organization.UpdateName(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	members, err := model.GetOrganizationMembers(member.OrganizationId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    members,
+	})
+}
+
+type organizationMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func AddOrganizationMember(c *gin.Context) {
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.OrganizationRoleMember
+	}
+	if !model.IsValidOrganizationRole(req.Role) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的角色"))
+		return
+	}
+
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	// 只有所有者可以添加管理员
+	if !model.CanManageOrganization(member.Role) || (req.Role == model.OrganizationRoleAdmin && member.Role != model.OrganizationRoleOwner) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无权管理该组织"))
+		return
+	}
+
+	newMember, err := model.AddOrganizationMember(member.OrganizationId, req.Username, req.Role)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    newMember,
+	})
+}
+
+func UpdateOrganizationMember(c *gin.Context) {
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if !model.IsValidOrganizationRole(req.Role) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的角色"))
+		return
+	}
+
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("只有组织所有者可以修改成员角色"))
+		return
+	}
+
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	target, err := model.GetOrganizationMember(member.OrganizationId, userId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if target.Role == model.OrganizationRoleOwner {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("不能修改组织所有者的角色"))
+		return
+	}
+
+	if err := target.UpdateRole(req.Role); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RemoveOrganizationMember 所有者和管理员可以移除成员，成员也可以自己退出
+func RemoveOrganizationMember(c *gin.Context) {
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	target, err := model.GetOrganizationMember(member.OrganizationId, userId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if target.Role == model.OrganizationRoleOwner {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("不能移除组织所有者"))
+		return
+	}
+	if target.UserId != member.UserId {
+		// 管理员只能移除普通成员
+		if !model.CanManageOrganization(member.Role) || (target.Role == model.OrganizationRoleAdmin && member.Role != model.OrganizationRoleOwner) {
+			common.APIRespondWithError(c, http.StatusOK, errors.New("无权管理该组织"))
+			return
+		}
+	}
+
+	if err := target.Delete(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetOrganizationStatistics(c *gin.Context) {
+	member, err := getOrganizationMemberByParam(c)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if !model.CanManageOrganization(member.Role) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无权管理该组织"))
+		return
+	}
+
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	startDate := time.Unix(startTimestamp, 0).Format("2006-01-02")
+	endDate := time.Unix(endTimestamp, 0).Format("2006-01-02")
+
+	statistics, err := model.GetOrganizationMemberStatisticsByPeriod(member.OrganizationId, startDate, endDate)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    statistics,
+	})
+}
+
+func GetOrganizationsList(c *gin.Context) {
+	var params model.SearchOrganizationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	organizations, err := model.GetOrganizationsList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organizations,
+	})
+}
+
+type organizationQuotaRequest struct {
+	Quota int `json:"quota"`
+}
+
+// UpdateOrganizationQuota 管理员设置组织额度
+func UpdateOrganizationQuota(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	var req organizationQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if req.Quota < 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("额度不能小于 0"))
+		return
+	}
+
+	if _, err := model.GetOrganizationById(id); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if err := model.UpdateOrganizationQuota(id, req.Quota); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, "管理员将组织 #"+strconv.Itoa(id)+" 的额度设置为 "+common.LogQuota(req.Quota))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"one-api/common/utils"
 	"one-api/model"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -107,16 +108,65 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := token.TokenLimits.Validate(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := token.ValidateAllowlist(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := token.ValidateBudget(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if token.ChatCacheThreshold < 0 || token.ChatCacheThreshold > 1 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "缓存相似度阈值需要在 0 到 1 之间",
+		})
+		return
+	}
+	// 使用组织额度的令牌需要是组织成员
+	if token.OrganizationId > 0 {
+		if _, err := model.GetOrganizationMember(token.OrganizationId, c.GetInt("id")); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 	cleanToken := model.Token{
-		UserId:         c.GetInt("id"),
-		Name:           token.Name,
-		Key:            utils.GenerateKey(),
-		CreatedTime:    utils.GetTimestamp(),
-		AccessedTime:   utils.GetTimestamp(),
-		ExpiredTime:    token.ExpiredTime,
-		RemainQuota:    token.RemainQuota,
-		UnlimitedQuota: token.UnlimitedQuota,
-		ChatCache:      token.ChatCache,
+		UserId:             c.GetInt("id"),
+		Name:               token.Name,
+		Key:                utils.GenerateKey(),
+		CreatedTime:        utils.GetTimestamp(),
+		AccessedTime:       utils.GetTimestamp(),
+		ExpiredTime:        token.ExpiredTime,
+		RemainQuota:        token.RemainQuota,
+		UnlimitedQuota:     token.UnlimitedQuota,
+		ChatCache:          token.ChatCache,
+		ChatCacheThreshold: token.ChatCacheThreshold,
+		AuditLog:           token.AuditLog,
+		Models:             token.Models,
+		AllowIPs:           token.AllowIPs,
+		OrganizationId:     token.OrganizationId,
+		TokenLimits:        token.TokenLimits,
+		TokenBudget: model.TokenBudget{
+			BudgetPeriod:    token.BudgetPeriod,
+			BudgetQuota:     token.BudgetQuota,
+			BudgetResetTime: model.GetNextBudgetResetTime(token.BudgetPeriod, time.Now()),
+		},
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -168,6 +218,44 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := token.TokenLimits.Validate(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := token.ValidateAllowlist(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := token.ValidateBudget(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if token.ChatCacheThreshold < 0 || token.ChatCacheThreshold > 1 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "缓存相似度阈值需要在 0 到 1 之间",
+		})
+		return
+	}
+	// 使用组织额度的令牌需要是组织成员
+	if token.OrganizationId > 0 {
+		if _, err := model.GetOrganizationMember(token.OrganizationId, c.GetInt("id")); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -201,6 +289,25 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.RemainQuota = token.RemainQuota
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.ChatCache = token.ChatCache
+		cleanToken.ChatCacheThreshold = token.ChatCacheThreshold
+		cleanToken.AuditLog = token.AuditLog
+		cleanToken.TokenLimits = token.TokenLimits
+		cleanToken.Models = token.Models
+		cleanToken.AllowIPs = token.AllowIPs
+		cleanToken.OrganizationId = token.OrganizationId
+		// 修改预算周期时保留已用额度，只在原周期已经结束时清零，避免通过切换周期清空已用额度
+		if cleanToken.BudgetPeriod != token.BudgetPeriod {
+			now := time.Now()
+			if cleanToken.BudgetResetTime > 0 && cleanToken.BudgetResetTime <= now.Unix() {
+				cleanToken.BudgetUsed = 0
+			}
+			cleanToken.BudgetPeriod = token.BudgetPeriod
+			// 关闭预算时保留原重置时间，重新开启时仍按原周期判断
+			if token.BudgetPeriod != "" {
+				cleanToken.BudgetResetTime = model.GetNextBudgetResetTime(token.BudgetPeriod, now)
+			}
+		}
+		cleanToken.BudgetQuota = token.BudgetQuota
 	}
 	err = cleanToken.Update()
 	if err != nil {
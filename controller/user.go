@@ -681,6 +681,8 @@ func EmailBind(c *gin.Context) {
 
 type topUpRequest struct {
 	Key string `json:"key"`
+	// 充值到组织，需要是组织的所有者或管理员
+	OrganizationId int `json:"organization_id"`
 }
 
 func TopUp(c *gin.Context) {
@@ -694,7 +696,19 @@ func TopUp(c *gin.Context) {
 		return
 	}
 	id := c.GetInt("id")
-	quota, err := model.Redeem(req.Key, id)
+	var quota int
+	if req.OrganizationId > 0 {
+		if err = checkOrganizationManager(req.OrganizationId, id); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		quota, err = model.RedeemToOrganization(req.Key, id, req.OrganizationId)
+	} else {
+		quota, err = model.Redeem(req.Key, id)
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"fmt"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
@@ -56,6 +57,65 @@ func InitCron() {
 		return
 	}
 
+	// 清理超过保留天数的审计日志
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 10, 0),
+			)),
+		gocron.NewTask(func() {
+			count, err := model.DeleteExpiredAuditLogs()
+			if err != nil {
+				logger.SysError("删除过期审计日志失败: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("删除过期审计日志 %d 条", count))
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 清理超过保留天数的 webhook 投递记录
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 20, 0),
+			)),
+		gocron.NewTask(func() {
+			count, err := model.DeleteExpiredWebhookDeliveries()
+			if err != nil {
+				logger.SysError("删除过期 webhook 投递记录失败: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("删除过期 webhook 投递记录 %d 条", count))
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 重置令牌的周期预算
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 0, 10),
+			)),
+		gocron.NewTask(func() {
+			model.ResetTokenBudgets()
+			logger.SysLog("重置令牌周期预算")
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
 	// 每十分钟更新一次统计数据
 	_, err = scheduler.NewJob(
 		gocron.DurationJob(10*time.Minute),
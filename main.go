@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
+	"net/http"
 	"one-api/cli"
 	"one-api/common"
 	"one-api/common/cache"
@@ -13,6 +16,7 @@ import (
 	"one-api/common/requester"
 	"one-api/common/storage"
 	"one-api/common/telegram"
+	"one-api/common/telemetry"
 	"one-api/controller"
 	"one-api/cron"
 	"one-api/middleware"
@@ -20,6 +24,9 @@ import (
 	"one-api/relay/relay_util"
 	"one-api/relay/task"
 	"one-api/router"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -43,6 +50,8 @@ func main() {
 
 	logger.SetupLogger()
 	logger.SysLog("One Hub " + config.Version + " started")
+	telemetry.InitTracer()
+	defer telemetry.ShutdownTracer(context.Background())
 	// Initialize SQL Database
 	model.SetupDB()
 	defer model.CloseDB()
@@ -62,6 +71,7 @@ func main() {
 
 	controller.InitMidjourneyTask()
 	task.InitTask()
+	notify.SetDeliveryRecorder(model.RecordWebhookDelivery)
 	notify.InitNotifier()
 	cron.InitCron()
 	storage.InitStorage()
@@ -100,6 +110,7 @@ func initHttpServer() {
 	server := gin.New()
 	server.Use(gin.Recovery())
 	server.Use(middleware.RequestId())
+	server.Use(middleware.Tracing())
 	middleware.SetUpLogger(server)
 
 	trustedHeader := viper.GetString("trusted_header")
@@ -113,10 +124,34 @@ func initHttpServer() {
 	router.SetRouter(server, buildFS, indexPage)
 	port := viper.GetString("port")
 
-	err := server.Run(":" + port)
-	if err != nil {
-		logger.FatalLog("failed to start HTTP server: " + err.Error())
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: server.Handler(),
 	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.FatalLog("failed to start HTTP server: " + err.Error())
+		}
+	}()
+
+	// 收到退出信号后等待进行中的请求结束，再写入队列中的日志和额度
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.SysLog("shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.SysError("failed to shutdown HTTP server: " + err.Error())
+	}
+	// 扣费在请求结束后异步进行，需要等待完成后再写入批量更新
+	if !relay_util.WaitPostConsume(ctx) {
+		logger.SysError("timed out waiting for quota consumption to finish")
+	}
+	model.StopBatchUpdater()
+	logger.SysLog("server exited")
 }
 
 func SyncChannelCache(frequency int) {
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"one-api/common/audit"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 流式响应原始数据包含大量事件格式，按内容上限的倍数截取后再拼接
+const auditStreamCaptureRatio = 4
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body      *bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(data []byte) {
+	limit := w.limit
+	if isEventStream(w.Header().Get("Content-Type")) {
+		limit *= auditStreamCaptureRatio
+	}
+
+	remain := limit - w.body.Len()
+	if remain <= 0 {
+		w.truncated = w.truncated || len(data) > 0
+		return
+	}
+	if len(data) > remain {
+		data = data[:remain]
+		w.truncated = true
+	}
+	w.body.Write(data)
+}
+
+// AuditLog 对开启审计的令牌和分组记录请求与响应内容
+func AuditLog() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !isAuditLogEnabled(c) {
+			c.Next()
+			return
+		}
+
+		limit := model.AuditLogBodyLimit()
+		requestBody, requestTruncated := readAuditRequestBody(c, limit)
+		writer := &auditResponseWriter{
+			ResponseWriter: c.Writer,
+			body:           &bytes.Buffer{},
+			limit:          limit,
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		isStream := isEventStream(writer.Header().Get("Content-Type"))
+		response := writer.body.String()
+		auditLog := &model.AuditLog{
+			RequestId:  c.GetString(logger.RequestIdKey),
+			UserId:     c.GetInt("id"),
+			TokenId:    c.GetInt("token_id"),
+			TokenName:  c.GetString("token_name"),
+			ChannelId:  c.GetInt("channel_id"),
+			ModelName:  c.GetString("original_model"),
+			Path:       c.Request.URL.Path,
+			StatusCode: writer.Status(),
+			IsStream:   isStream,
+			Truncated:  requestTruncated || writer.truncated,
+		}
+
+		go func() {
+			if isStream {
+				response = reassembleStreamResponse(response)
+			}
+			if len(response) > limit {
+				response = response[:limit]
+				auditLog.Truncated = true
+			}
+			auditLog.Request = strings.ToValidUTF8(audit.Redact(requestBody), "")
+			auditLog.Response = strings.ToValidUTF8(audit.Redact(response), "")
+			model.RecordAuditLog(auditLog)
+		}()
+	}
+}
+
+func isAuditLogEnabled(c *gin.Context) bool {
+	if !config.AuditLogEnabled || c.IsWebsocket() {
+		return false
+	}
+	if c.GetBool("audit_log") {
+		return true
+	}
+
+	group := c.GetString("group")
+	for _, auditGroup := range strings.Split(config.AuditLogGroups, ",") {
+		if group != "" && strings.TrimSpace(auditGroup) == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readAuditRequestBody 读取请求体后放回，文件上传等二进制内容不记录
+func readAuditRequestBody(c *gin.Context, limit int) (string, bool) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		return "[multipart body omitted]", false
+	}
+
+	requestBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", false
+	}
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+
+	if len(requestBody) > limit {
+		return string(requestBody[:limit]), true
+	}
+	return string(requestBody), false
+}
+
+func isEventStream(contentType string) bool {
+	return strings.Contains(contentType, "text/event-stream")
+}
+
+// reassembleStreamResponse 将 OpenAI 和 Claude 格式的流式响应拼接为完整文本，无法识别时保留原始内容
+func reassembleStreamResponse(raw string) string {
+	var builder strings.Builder
+	for _, line := range strings.Split(raw, "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var chunk struct {
+			types.ChatCompletionStreamResponse
+			Delta struct {
+				Text string `json:"text"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		builder.WriteString(chunk.GetResponseText())
+		builder.WriteString(chunk.Delta.Text)
+	}
+
+	if builder.Len() == 0 {
+		return raw
+	}
+	return builder.String()
+}
@@ -3,12 +3,16 @@ package middleware
 import (
 	"net/http"
 	"one-api/common/config"
+	"one-api/common/telemetry"
 	"one-api/common/utils"
 	"one-api/model"
 	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func authHelper(c *gin.Context, minRole int) {
@@ -85,12 +89,24 @@ func RootAuth() func(c *gin.Context) {
 }
 
 func tokenAuth(c *gin.Context, key string) {
+	_, span := telemetry.StartSpan(c.Request.Context(), "relay.auth")
+	if !authenticateToken(c, key) {
+		span.SetStatus(codes.Error, "authentication failed")
+		span.End()
+		return
+	}
+	span.SetAttributes(attribute.Int("user_id", c.GetInt("id")), attribute.Int("token_id", c.GetInt("token_id")))
+	span.End()
+	c.Next()
+}
+
+func authenticateToken(c *gin.Context, key string) bool {
 	key = strings.TrimPrefix(key, "Bearer ")
 	key = strings.TrimPrefix(key, "sk-")
 
 	if len(key) < 48 {
 		abortWithMessage(c, http.StatusUnauthorized, "无效的令牌")
-		return
+		return false
 	}
 
 	parts := strings.Split(key, "-")
@@ -98,21 +114,22 @@ func tokenAuth(c *gin.Context, key string) {
 	token, err := model.ValidateUserToken(key)
 	if err != nil {
 		abortWithMessage(c, http.StatusUnauthorized, err.Error())
-		return
+		return false
+	}
+	if !token.IsIPAllowed(c.ClientIP()) {
+		abortWithMessage(c, http.StatusForbidden, "该令牌不允许从当前 IP 访问")
+		return false
 	}
 	userEnabled, err := model.CacheIsUserEnabled(token.UserId)
 	if err != nil {
 		abortWithMessage(c, http.StatusInternalServerError, err.Error())
-		return
+		return false
 	}
 	if !userEnabled {
 		abortWithMessage(c, http.StatusForbidden, "用户已被封禁")
-		return
+		return false
 	}
-	c.Set("id", token.UserId)
-	c.Set("token_id", token.Id)
-	c.Set("token_name", token.Name)
-	c.Set("chat_cache", token.ChatCache)
+	SetTokenContext(c, token)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			if strings.HasPrefix(parts[1], "!") {
@@ -122,7 +139,7 @@ func tokenAuth(c *gin.Context, key string) {
 				channelId := utils.String2Int(parts[1])
 				if channelId == 0 {
 					abortWithMessage(c, http.StatusForbidden, "无效的渠道 Id")
-					return
+					return false
 				}
 				c.Set("specific_channel_id", channelId)
 				if len(parts) == 3 && parts[2] == "ignore" {
@@ -131,19 +148,47 @@ func tokenAuth(c *gin.Context, key string) {
 			}
 		} else {
 			abortWithMessage(c, http.StatusForbidden, "普通用户不支持指定渠道")
-			return
+			return false
 		}
 	}
-	c.Next()
+	return true
+}
+
+// SetTokenContext 写入令牌相关的上下文，不经过鉴权中间件的请求（如批处理）也要调用
+func SetTokenContext(c *gin.Context, token *model.Token) {
+	c.Set("id", token.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_name", token.Name)
+	c.Set("organization_id", token.OrganizationId)
+	c.Set("chat_cache", token.ChatCache)
+	c.Set("chat_cache_threshold", token.ChatCacheThreshold)
+	c.Set("audit_log", token.AuditLog)
+	c.Set("token_limits", token.TokenLimits)
+	c.Set("token_budget", token.HasBudget())
+	c.Set("token_models", token.GetAllowedModels())
 }
 
 func OpenaiAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		key := c.Request.Header.Get("Authorization")
+		if key == "" {
+			key = getWebSocketProtocolKey(c)
+		}
 		tokenAuth(c, key)
 	}
 }
 
+// 浏览器无法为 WebSocket 设置请求头，Realtime API 通过子协议传递密钥
+func getWebSocketProtocolKey(c *gin.Context) string {
+	for _, protocol := range websocket.Subprotocols(c.Request) {
+		if strings.HasPrefix(protocol, "openai-insecure-api-key.") {
+			return strings.TrimPrefix(protocol, "openai-insecure-api-key.")
+		}
+	}
+
+	return ""
+}
+
 func ClaudeAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		key := c.Request.Header.Get("x-api-key")
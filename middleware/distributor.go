@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"one-api/model"
+	"one-api/relay"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +12,8 @@ func Distribute() func(c *gin.Context) {
 		userId := c.GetInt("id")
 		userGroup, _ := model.CacheGetUserGroup(userId)
 		c.Set("group", userGroup)
+		// 选中渠道后没有发出请求时退还占用的名额
+		defer relay.ReleaseChannelReservation(c)
 		c.Next()
 	}
 }
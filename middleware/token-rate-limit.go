@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var tokenLimitMessages = map[string]string{
+	model.TokenLimitRequests:      "令牌已达到每分钟请求数限制 %d，请稍后再试",
+	model.TokenLimitTokens:        "令牌已达到每分钟 token 数限制 %d，请稍后再试",
+	model.TokenLimitConcurrency:   "令牌已达到最大并发请求数 %d，请稍后再试",
+	model.TokenLimitDailyRequests: "令牌已达到每日请求数限制 %d，请明天再试",
+}
+
+// TokenRateLimit 令牌的 RPM、TPM、并发和每日请求数限制，需要放在鉴权之后
+func TokenRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		value, ok := c.Get("token_limits")
+		if !ok {
+			c.Next()
+			return
+		}
+		limits := value.(model.TokenLimits)
+		if limits.IsEmpty() {
+			c.Next()
+			return
+		}
+
+		tokenId := c.GetInt("token_id")
+		status := model.TokenRequestStart(tokenId, limits)
+		setRateLimitHeaders(c, limits, status)
+		if status.Exceeded != "" {
+			abortWithRateLimit(c, limits, status)
+			return
+		}
+
+		defer func() {
+			model.TokenRequestDone(tokenId, limits, c.GetInt("consumed_tokens"))
+		}()
+
+		c.Next()
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, limits model.TokenLimits, status *model.TokenLimitStatus) {
+	if limits.RPM > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(limits.RPM))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(status.RemainingRequests))
+		c.Header("x-ratelimit-reset-requests", status.ResetRequests.String())
+	}
+
+	if limits.TPM > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.Itoa(limits.TPM))
+		c.Header("x-ratelimit-remaining-tokens", strconv.Itoa(status.RemainingTokens))
+		c.Header("x-ratelimit-reset-tokens", status.ResetTokens.String())
+	}
+}
+
+func abortWithRateLimit(c *gin.Context, limits model.TokenLimits, status *model.TokenLimitStatus) {
+	var limit int
+	switch status.Exceeded {
+	case model.TokenLimitRequests:
+		limit = limits.RPM
+	case model.TokenLimitTokens:
+		limit = limits.TPM
+	case model.TokenLimitConcurrency:
+		limit = limits.MaxConcurrency
+	case model.TokenLimitDailyRequests:
+		limit = limits.DailyRequests
+	}
+	message := fmt.Sprintf(tokenLimitMessages[status.Exceeded], limit)
+
+	c.Header("Retry-After", strconv.Itoa(int(status.RetryAfter/time.Second)))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": utils.MessageWithRequestId(message, c.GetString(logger.RequestIdKey)),
+			"type":    status.Exceeded,
+			"param":   nil,
+			"code":    "rate_limit_exceeded",
+		},
+	})
+	c.Abort()
+	logger.LogWarn(c.Request.Context(), message)
+}
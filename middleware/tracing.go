@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"one-api/common/logger"
+	"one-api/common/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Tracing 为每个请求创建根 span，客户端传入 traceparent 时作为其子 span
+func Tracing() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		name := c.FullPath()
+		if name == "" {
+			name = c.Request.URL.Path
+		}
+		ctx, span := telemetry.StartServerSpan(ctx, c.Request.Method+" "+name,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String("request_id", c.GetString(logger.RequestIdKey)),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", status),
+			attribute.Int("user_id", c.GetInt("id")),
+			attribute.Int("channel_id", c.GetInt("channel_id")),
+		)
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
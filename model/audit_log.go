@@ -0,0 +1,133 @@
+package model
+
+import (
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"strings"
+	"time"
+)
+
+// AuditLog 请求和响应的完整内容，单独建表避免影响消费日志的查询
+type AuditLog struct {
+	Id         int    `json:"id"`
+	RequestId  string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId     int    `json:"user_id" gorm:"index"`
+	TokenId    int    `json:"token_id" gorm:"index"`
+	TokenName  string `json:"token_name" gorm:"type:varchar(255);default:''"`
+	ChannelId  int    `json:"channel_id" gorm:"index"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(255);index;default:''"`
+	Path       string `json:"path" gorm:"type:varchar(255);default:''"`
+	StatusCode int    `json:"status_code"`
+	IsStream   bool   `json:"is_stream"`
+	Request    string `json:"request" gorm:"type:text"`
+	Response   string `json:"response" gorm:"type:text"`
+	// 超过大小限制被截断
+	Truncated bool  `json:"truncated"`
+	CreatedAt int64 `json:"created_at" gorm:"bigint;index"`
+}
+
+// MySQL 的 text 列最多存 65535 字节，超出会写入失败
+const mysqlTextMaxSize = 65535
+
+// AuditLogBodyLimit 请求和响应内容的截断大小，MySQL 下不超过 text 列的容量
+func AuditLogBodyLimit() int {
+	limit := config.AuditLogMaxBodySize * 1024
+	if isMySQL() && limit > mysqlTextMaxSize {
+		return mysqlTextMaxSize
+	}
+	return limit
+}
+
+func isMySQL() bool {
+	return !common.UsingPostgreSQL && !common.UsingSQLite
+}
+
+// truncateAuditBody 按字节截断，去掉被截断的不完整字符
+func truncateAuditBody(body string, limit int) (string, bool) {
+	if len(body) <= limit {
+		return body, false
+	}
+	return strings.ToValidUTF8(body[:limit], ""), true
+}
+
+func RecordAuditLog(auditLog *AuditLog) {
+	// 脱敏后内容可能变长，写入前再按列的容量截断
+	if isMySQL() {
+		var requestTruncated, responseTruncated bool
+		auditLog.Request, requestTruncated = truncateAuditBody(auditLog.Request, mysqlTextMaxSize)
+		auditLog.Response, responseTruncated = truncateAuditBody(auditLog.Response, mysqlTextMaxSize)
+		auditLog.Truncated = auditLog.Truncated || requestTruncated || responseTruncated
+	}
+	auditLog.CreatedAt = time.Now().Unix()
+	err := DB.Create(auditLog).Error
+	if err != nil {
+		logger.SysError("failed to record audit log: " + err.Error())
+	}
+}
+
+type AuditLogsListParams struct {
+	PaginationParams
+	RequestId      string `form:"request_id"`
+	UserId         int    `form:"user_id"`
+	TokenId        int    `form:"token_id"`
+	ChannelId      int    `form:"channel_id"`
+	ModelName      string `form:"model_name"`
+	StartTimestamp int64  `form:"start_timestamp"`
+	EndTimestamp   int64  `form:"end_timestamp"`
+}
+
+var allowedAuditLogsOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"user_id":    true,
+	"channel_id": true,
+	"model_name": true,
+}
+
+// GetAuditLogsList 列表中不返回请求和响应内容，查看详情时再单独获取
+func GetAuditLogsList(params *AuditLogsListParams) (*DataResult[AuditLog], error) {
+	var auditLogs []*AuditLog
+
+	tx := DB.Omit("request", "response")
+	if params.RequestId != "" {
+		tx = tx.Where("request_id = ?", params.RequestId)
+	}
+	if params.UserId != 0 {
+		tx = tx.Where("user_id = ?", params.UserId)
+	}
+	if params.TokenId != 0 {
+		tx = tx.Where("token_id = ?", params.TokenId)
+	}
+	if params.ChannelId != 0 {
+		tx = tx.Where("channel_id = ?", params.ChannelId)
+	}
+	if params.ModelName != "" {
+		tx = tx.Where("model_name = ?", params.ModelName)
+	}
+	if params.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", params.StartTimestamp)
+	}
+	if params.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", params.EndTimestamp)
+	}
+
+	return PaginateAndOrder(tx, &params.PaginationParams, &auditLogs, allowedAuditLogsOrderFields)
+}
+
+func GetAuditLogById(id int) (*AuditLog, error) {
+	var auditLog AuditLog
+	err := DB.First(&auditLog, "id = ?", id).Error
+	return &auditLog, err
+}
+
+// DeleteExpiredAuditLogs 按保留天数清理审计日志，保留天数小于等于 0 时不清理
+func DeleteExpiredAuditLogs() (int64, error) {
+	if config.AuditLogRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	targetTimestamp := time.Now().AddDate(0, 0, -config.AuditLogRetentionDays).Unix()
+	result := DB.Where("created_at < ?", targetTimestamp).Delete(&AuditLog{})
+	return result.RowsAffected, result.Error
+}
@@ -2,9 +2,9 @@ package model
 
 import (
 	"errors"
-	"math/rand"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"one-api/common/utils"
 	"strings"
 	"sync"
@@ -12,9 +12,218 @@ import (
 )
 
 type ChannelChoice struct {
-	Channel       *Channel
-	CooldownsTime int64
-	Disable       bool
+	Channel  *Channel
+	Disable  bool
+	Stats    *ChannelStats
+	Breakers *ChannelBreakers
+}
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreaker 熔断器
+// 窗口内失败次数达到阈值后打开，冷却结束后进入半开状态放行少量探测请求，探测成功则关闭，失败则重新打开
+type CircuitBreaker struct {
+	State         string `json:"state"`
+	Failures      int    `json:"failures"`
+	WindowStart   int64  `json:"window_start"`
+	OpenedAt      int64  `json:"opened_at"`
+	CooldownsTime int64  `json:"cooldowns_time"`
+
+	probes    int
+	probeTime int64
+}
+
+func newCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{State: CircuitClosed}
+}
+
+func (b *CircuitBreaker) available(now int64) bool {
+	if b.CooldownsTime >= now {
+		return false
+	}
+
+	openSeconds := int64(config.CircuitBreakerOpenSeconds)
+	switch b.State {
+	case CircuitOpen:
+		return now >= b.OpenedAt+openSeconds
+	case CircuitHalfOpen:
+		// 探测请求长时间没有结果时，允许新的探测
+		return b.probes < max(config.CircuitBreakerHalfOpenRequests, 1) || now >= b.probeTime+openSeconds
+	}
+
+	return true
+}
+
+// acquire 渠道被选中时调用，半开状态下占用一个探测名额
+func (b *CircuitBreaker) acquire(now int64) {
+	if b.State == CircuitOpen {
+		b.State = CircuitHalfOpen
+		b.probes = 0
+	}
+
+	if b.State == CircuitHalfOpen {
+		if now >= b.probeTime+int64(config.CircuitBreakerOpenSeconds) {
+			b.probes = 0
+		}
+		b.probes++
+		b.probeTime = now
+	}
+}
+
+func (b *CircuitBreaker) success() {
+	if b.State == CircuitClosed {
+		return
+	}
+
+	b.State = CircuitClosed
+	b.Failures = 0
+	b.WindowStart = 0
+	b.OpenedAt = 0
+	b.probes = 0
+}
+
+func (b *CircuitBreaker) failure(now int64) {
+	switch b.State {
+	case CircuitOpen:
+		return
+	case CircuitHalfOpen:
+		b.open(now)
+		return
+	}
+
+	if now-b.WindowStart >= int64(config.CircuitBreakerWindowSeconds) {
+		b.WindowStart = now
+		b.Failures = 0
+	}
+
+	b.Failures++
+	if config.CircuitBreakerThreshold > 0 && b.Failures >= config.CircuitBreakerThreshold {
+		b.open(now)
+	}
+}
+
+func (b *CircuitBreaker) open(now int64) {
+	b.State = CircuitOpen
+	b.OpenedAt = now
+	b.probes = 0
+}
+
+// ChannelBreakers 渠道的熔断器，Channel 作用于整个渠道，Models 只作用于对应的模型
+type ChannelBreakers struct {
+	sync.Mutex
+	Channel *CircuitBreaker
+	Models  map[string]*CircuitBreaker
+}
+
+func newChannelBreakers() *ChannelBreakers {
+	return &ChannelBreakers{
+		Channel: newCircuitBreaker(),
+		Models:  make(map[string]*CircuitBreaker),
+	}
+}
+
+func (cb *ChannelBreakers) get(modelName string, create bool) *CircuitBreaker {
+	if modelName == "" {
+		return cb.Channel
+	}
+
+	breaker, ok := cb.Models[modelName]
+	if !ok && create {
+		breaker = newCircuitBreaker()
+		cb.Models[modelName] = breaker
+	}
+
+	return breaker
+}
+
+func (cb *ChannelBreakers) available(modelName string, now int64) bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if !cb.Channel.available(now) {
+		return false
+	}
+
+	if breaker := cb.get(modelName, false); breaker != nil {
+		return breaker.available(now)
+	}
+
+	return true
+}
+
+func (cb *ChannelBreakers) acquire(modelName string, now int64) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.Channel.acquire(now)
+	if breaker := cb.get(modelName, false); breaker != nil {
+		breaker.acquire(now)
+	}
+}
+
+func (cb *ChannelBreakers) record(modelName string, result RequestResult, now int64) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch result {
+	case RequestChannelFailure:
+		cb.Channel.failure(now)
+	case RequestModelFailure:
+		cb.get(modelName, true).failure(now)
+	default:
+		cb.Channel.success()
+		if breaker := cb.get(modelName, false); breaker != nil {
+			breaker.success()
+		}
+	}
+}
+
+// reset 原地重置，选择渠道时可能在读锁外使用熔断器
+func (cb *ChannelBreakers) reset() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.Channel = newCircuitBreaker()
+	cb.Models = make(map[string]*CircuitBreaker)
+}
+
+func (cb *ChannelBreakers) cooldowns(modelName string, until int64) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.get(modelName, true).CooldownsTime = until
+}
+
+// CircuitBreakerStatus 熔断器状态，用于管理接口展示
+type CircuitBreakerStatus struct {
+	Channel CircuitBreaker            `json:"channel"`
+	Models  map[string]CircuitBreaker `json:"models,omitempty"`
+}
+
+func (cb *ChannelBreakers) status() *CircuitBreakerStatus {
+	cb.Lock()
+	defer cb.Unlock()
+
+	status := &CircuitBreakerStatus{
+		Channel: *cb.Channel,
+	}
+
+	for modelName, breaker := range cb.Models {
+		// 只展示非正常状态的模型
+		if breaker.State == CircuitClosed && breaker.Failures == 0 {
+			continue
+		}
+		if status.Models == nil {
+			status.Models = make(map[string]CircuitBreaker)
+		}
+		status.Models[modelName] = *breaker
+	}
+
+	return status
 }
 
 type ChannelsChooser struct {
@@ -38,20 +247,50 @@ func FilterOnlyChat() ChannelsFilterFunc {
 	}
 }
 
-func (cc *ChannelsChooser) Cooldowns(channelId int) bool {
+func FilterChannelTypes(channelTypes []int) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		return !utils.Contains(choice.Channel.Type, channelTypes)
+	}
+}
+
+// Cooldowns 冻结渠道的某个模型，modelName 为空时冻结整个渠道
+func (cc *ChannelsChooser) Cooldowns(channelId int, modelName string) bool {
 	if config.RetryCooldownSeconds == 0 {
 		return false
 	}
-	cc.Lock()
-	defer cc.Unlock()
-	if _, ok := cc.Channels[channelId]; !ok {
+	cc.RLock()
+	defer cc.RUnlock()
+	choice, ok := cc.Channels[channelId]
+	if !ok {
 		return false
 	}
 
-	cc.Channels[channelId].CooldownsTime = time.Now().Unix() + int64(config.RetryCooldownSeconds)
+	choice.Breakers.cooldowns(modelName, time.Now().Unix()+int64(config.RetryCooldownSeconds))
+	metrics.RecordChannelEvent(channelId, metrics.ChannelEventCooldown)
 	return true
 }
 
+// GetCircuitBreakerStatus 获取渠道的熔断器状态，渠道未启用时返回 nil
+func (cc *ChannelsChooser) GetCircuitBreakerStatus(channelId int) *CircuitBreakerStatus {
+	cc.RLock()
+	defer cc.RUnlock()
+	choice, ok := cc.Channels[channelId]
+	if !ok {
+		return nil
+	}
+
+	return choice.Breakers.status()
+}
+
+// ResetCircuitBreaker 重置渠道的所有熔断器
+func (cc *ChannelsChooser) ResetCircuitBreaker(channelId int) {
+	cc.RLock()
+	defer cc.RUnlock()
+	if choice, ok := cc.Channels[channelId]; ok {
+		choice.Breakers.reset()
+	}
+}
+
 func (cc *ChannelsChooser) Disable(channelId int) {
 	cc.Lock()
 	defer cc.Unlock()
@@ -60,6 +299,7 @@ func (cc *ChannelsChooser) Disable(channelId int) {
 	}
 
 	cc.Channels[channelId].Disable = true
+	metrics.RecordChannelEvent(channelId, metrics.ChannelEventDisable)
 }
 
 func (cc *ChannelsChooser) Enable(channelId int) {
@@ -70,6 +310,7 @@ func (cc *ChannelsChooser) Enable(channelId int) {
 	}
 
 	cc.Channels[channelId].Disable = false
+	metrics.RecordChannelEvent(channelId, metrics.ChannelEventEnable)
 }
 
 func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
@@ -80,81 +321,162 @@ func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	}
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc) *Channel {
+// candidates 按优先级列出可用的渠道，渠道限制在选中时才检查，避免持有读锁时访问 Redis
+func (cc *ChannelsChooser) candidates(group, modelName string, filters []ChannelsFilterFunc) (priorities [][]*ChannelChoice, strategy string, err error) {
+	cc.RLock()
+	defer cc.RUnlock()
+	if _, ok := cc.Rule[group]; !ok {
+		return nil, "", errors.New("group not found")
+	}
+
+	channelsPriority, ok := cc.Rule[group][modelName]
+	if !ok {
+		matchModel := utils.GetModelsWithMatch(&cc.Match, modelName)
+		channelsPriority, ok = cc.Rule[group][matchModel]
+		if !ok {
+			return nil, "", errors.New("model not found")
+		}
+	}
+
+	if len(channelsPriority) == 0 {
+		return nil, "", errors.New("channel not found")
+	}
+
 	nowTime := time.Now().Unix()
-	totalWeight := 0
+	priorities = make([][]*ChannelChoice, 0, len(channelsPriority))
+	for _, channelIds := range channelsPriority {
+		validChannels := make([]*ChannelChoice, 0, len(channelIds))
+		for _, channelId := range channelIds {
+			choice, ok := cc.Channels[channelId]
+			if !ok || choice.Disable || !choice.Breakers.available(modelName, nowTime) {
+				continue
+			}
 
-	validChannels := make([]*ChannelChoice, 0, len(channelIds))
-	for _, channelId := range channelIds {
-		choice, ok := cc.Channels[channelId]
-		if !ok || choice.Disable || choice.CooldownsTime >= nowTime {
-			continue
+			isSkip := false
+			for _, filter := range filters {
+				if filter(channelId, choice) {
+					isSkip = true
+					break
+				}
+			}
+			if isSkip {
+				continue
+			}
+
+			validChannels = append(validChannels, choice)
 		}
+		priorities = append(priorities, validChannels)
+	}
+
+	return priorities, GetBalanceStrategy(group, modelName, cc.Match), nil
+}
 
-		isSkip := false
-		for _, filter := range filters {
-			if filter(channelId, choice) {
-				isSkip = true
-				break
+// balancer 从同一优先级的渠道中选择一个并占用名额，limited 表示是否有渠道因为并发、RPM、TPM 限制被跳过
+func balancer(validChannels []*ChannelChoice, strategy string, modelName string, affinityKey string) (channel *Channel, reservation *ChannelReservation, limited bool) {
+	for len(validChannels) > 0 {
+		choice := validChannels[0]
+		if len(validChannels) > 1 {
+			if affinityKey != "" {
+				// 亲和的渠道不可用时，会自动落到排名其次的渠道
+				choice = pickAffinityChannel(affinityKey, validChannels)
+			} else {
+				choice = pickChannel(strategy, validChannels)
 			}
 		}
-		if isSkip {
-			continue
+
+		reservation, ok := reserveChannel(choice.Channel)
+		if ok {
+			choice.Breakers.acquire(modelName, time.Now().Unix())
+			return choice.Channel, reservation, limited
 		}
 
-		weight := int(*choice.Channel.Weight)
-		totalWeight += weight
-		validChannels = append(validChannels, choice)
+		// 达到限制的渠道不再参与本次选择
+		limited = true
+		validChannels = removeChoice(validChannels, choice)
 	}
 
-	if len(validChannels) == 0 {
-		return nil
+	return nil, nil, limited
+}
+
+func removeChoice(choices []*ChannelChoice, target *ChannelChoice) []*ChannelChoice {
+	remain := make([]*ChannelChoice, 0, len(choices)-1)
+	for _, choice := range choices {
+		if choice != target {
+			remain = append(remain, choice)
+		}
 	}
+	return remain
+}
 
-	if len(validChannels) == 1 {
-		return validChannels[0].Channel
+func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, *ChannelReservation, error) {
+	return cc.NextWithAffinity("", group, modelName, filters...)
+}
+
+// NextWithAffinity 相同 affinityKey 的请求在同一优先级内总是选择同一个渠道
+// 返回的渠道已经占用了名额，调用方需要在请求开始时交给 RequestStart，或者调用 Release 退还
+func (cc *ChannelsChooser) NextWithAffinity(affinityKey, group, modelName string, filters ...ChannelsFilterFunc) (*Channel, *ChannelReservation, error) {
+	priorities, strategy, err := cc.candidates(group, modelName, filters)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
-		weight := int(*choice.Channel.Weight)
-		choiceWeight -= weight
-		if choiceWeight < 0 {
-			return choice.Channel
+	busy := false
+	for _, validChannels := range priorities {
+		channel, reservation, limited := balancer(validChannels, strategy, modelName, affinityKey)
+		if channel != nil {
+			return channel, reservation, nil
 		}
+		busy = busy || limited
 	}
 
-	return nil
+	if busy {
+		return nil, nil, ErrChannelBusy
+	}
+
+	return nil, nil, errors.New("channel not found")
 }
 
-func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
+// RequestStart 记录渠道开始处理请求，promptTokens 计入渠道的 TPM
+// reservation 为选择渠道时占用的名额，没有时直接占用
+func (cc *ChannelsChooser) RequestStart(channelId int, promptTokens int, reservation *ChannelReservation) {
 	cc.RLock()
-	defer cc.RUnlock()
-	if _, ok := cc.Rule[group]; !ok {
-		return nil, errors.New("group not found")
-	}
-
-	channelsPriority, ok := cc.Rule[group][modelName]
+	choice, ok := cc.Channels[channelId]
+	cc.RUnlock()
 	if !ok {
-		matchModel := utils.GetModelsWithMatch(&cc.Match, modelName)
-		channelsPriority, ok = cc.Rule[group][matchModel]
-		if !ok {
-			return nil, errors.New("model not found")
-		}
+		reservation.Release()
+		return
 	}
 
-	if len(channelsPriority) == 0 {
-		return nil, errors.New("channel not found")
+	choice.Stats.start()
+	if !reservation.start(channelId) {
+		reservation.Release()
+		channelLimitAcquire(choice.Channel)
 	}
+	channelLimitAddTokens(choice.Channel, promptTokens)
+}
 
-	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters)
-		if channel != nil {
-			return channel, nil
-		}
+type RequestResult int
+
+const (
+	RequestSuccess RequestResult = iota
+	// 只影响当前模型的错误
+	RequestModelFailure
+	// 影响整个渠道的错误，例如密钥失效
+	RequestChannelFailure
+)
+
+// RequestDone 记录渠道请求结束，ttft 为首字延迟，completionTokens 计入渠道的 TPM
+func (cc *ChannelsChooser) RequestDone(channelId int, modelName string, ttft time.Duration, result RequestResult, completionTokens int) {
+	cc.RLock()
+	choice, ok := cc.Channels[channelId]
+	cc.RUnlock()
+	if !ok {
+		return
 	}
 
-	return nil, errors.New("channel not found")
+	choice.Stats.done(ttft, result == RequestSuccess)
+	channelLimitRelease(choice.Channel, completionTokens)
+	choice.Breakers.record(modelName, result, time.Now().Unix())
 }
 
 func (cc *ChannelsChooser) GetGroupModels(group string) ([]string, error) {
@@ -200,15 +522,26 @@ func (cc *ChannelsChooser) Load() {
 	newChannels := make(map[int]*ChannelChoice)
 	newMatch := make(map[string]bool)
 
+	// 重新加载时保留渠道的实时统计和熔断状态
+	cc.RLock()
+	oldChannels := cc.Channels
+	cc.RUnlock()
+
 	for _, channel := range channels {
 		if *channel.Weight == 0 {
 			channel.Weight = &config.DefaultChannelWeight
 		}
-		newChannels[channel.Id] = &ChannelChoice{
-			Channel:       channel,
-			CooldownsTime: 0,
-			Disable:       false,
+		choice := &ChannelChoice{
+			Channel:  channel,
+			Disable:  false,
+			Stats:    &ChannelStats{},
+			Breakers: newChannelBreakers(),
+		}
+		if oldChoice, ok := oldChannels[channel.Id]; ok {
+			choice.Stats = oldChoice.Stats
+			choice.Breakers = oldChoice.Breakers
 		}
+		newChannels[channel.Id] = choice
 	}
 
 	for _, ability := range abilities {
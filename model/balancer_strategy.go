@@ -0,0 +1,289 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 按权重随机，默认策略
+	BalanceStrategyWeight = "weight"
+	// 优先选择进行中请求最少的渠道
+	BalanceStrategyLeastInflight = "least_inflight"
+	// 按首字延迟的指数加权平均调整权重
+	BalanceStrategyEWMATTFT = "ewma_ttft"
+	// 按错误率降低权重
+	BalanceStrategyErrorRate = "error_rate"
+)
+
+const (
+	// 指数加权平均的平滑系数
+	balanceEWMAAlpha = 0.2
+	// 权重的最低比例，保证表现差的渠道仍有少量流量用于探测恢复
+	balanceMinWeightFactor = 0.05
+)
+
+// ChannelStats 渠道的实时统计，只保存在内存中
+type ChannelStats struct {
+	inflight int64
+
+	mu        sync.Mutex
+	ewmaTTFT  float64 // 毫秒
+	errorRate float64
+	samples   int64
+}
+
+func (s *ChannelStats) Inflight() int64 {
+	return atomic.LoadInt64(&s.inflight)
+}
+
+func (s *ChannelStats) Snapshot() (ewmaTTFT float64, errorRate float64, samples int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ewmaTTFT, s.errorRate, s.samples
+}
+
+func (s *ChannelStats) start() {
+	atomic.AddInt64(&s.inflight, 1)
+}
+
+func (s *ChannelStats) done(ttft time.Duration, success bool) {
+	atomic.AddInt64(&s.inflight, -1)
+
+	failure := 0.0
+	if !success {
+		failure = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.samples == 0 {
+		s.errorRate = failure
+	} else {
+		s.errorRate = balanceEWMAAlpha*failure + (1-balanceEWMAAlpha)*s.errorRate
+	}
+
+	// 失败的请求没有有效的首字延迟
+	if success && ttft > 0 {
+		ms := float64(ttft.Milliseconds())
+		if s.ewmaTTFT == 0 {
+			s.ewmaTTFT = ms
+		} else {
+			s.ewmaTTFT = balanceEWMAAlpha*ms + (1-balanceEWMAAlpha)*s.ewmaTTFT
+		}
+	}
+	s.samples++
+}
+
+func IsValidBalanceStrategy(strategy string) bool {
+	switch strategy {
+	case BalanceStrategyWeight, BalanceStrategyLeastInflight, BalanceStrategyEWMATTFT, BalanceStrategyErrorRate:
+		return true
+	}
+
+	return false
+}
+
+// BalanceRules 按分组或模型指定负载均衡策略，模型优先
+type BalanceRules struct {
+	Groups map[string]string `json:"groups"`
+	Models map[string]string `json:"models"`
+}
+
+var balanceRules = BalanceRules{}
+var balanceRulesLock sync.RWMutex
+
+func BalanceRules2JSONString() string {
+	balanceRulesLock.RLock()
+	defer balanceRulesLock.RUnlock()
+
+	jsonBytes, err := json.Marshal(balanceRules)
+	if err != nil {
+		logger.SysError("error marshalling balance rules: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateBalanceRulesByJSONString(jsonStr string) error {
+	rules := BalanceRules{}
+	if jsonStr != "" {
+		if err := json.Unmarshal([]byte(jsonStr), &rules); err != nil {
+			return err
+		}
+	}
+
+	for _, strategies := range []map[string]string{rules.Groups, rules.Models} {
+		for _, strategy := range strategies {
+			if !IsValidBalanceStrategy(strategy) {
+				return fmt.Errorf("unknown balance strategy: %s", strategy)
+			}
+		}
+	}
+
+	balanceRulesLock.Lock()
+	balanceRules = rules
+	balanceRulesLock.Unlock()
+
+	return nil
+}
+
+// GetBalanceStrategy 获取分组和模型对应的负载均衡策略
+func GetBalanceStrategy(group, modelName string, match []string) string {
+	balanceRulesLock.RLock()
+	defer balanceRulesLock.RUnlock()
+
+	if strategy, ok := balanceRules.Models[modelName]; ok {
+		return strategy
+	}
+
+	if len(balanceRules.Models) > 0 {
+		matchModel := utils.GetModelsWithMatch(&match, modelName)
+		if strategy, ok := balanceRules.Models[matchModel]; ok {
+			return strategy
+		}
+	}
+
+	if strategy, ok := balanceRules.Groups[group]; ok {
+		return strategy
+	}
+
+	if config.ChannelBalanceStrategy != "" {
+		return config.ChannelBalanceStrategy
+	}
+
+	return BalanceStrategyWeight
+}
+
+// pickChannel 按策略从可用渠道中选择一个
+func pickChannel(strategy string, choices []*ChannelChoice) *ChannelChoice {
+	switch strategy {
+	case BalanceStrategyLeastInflight:
+		return pickLeastInflight(choices)
+	case BalanceStrategyEWMATTFT:
+		return pickWeighted(choices, ewmaTTFTFactors(choices))
+	case BalanceStrategyErrorRate:
+		return pickWeighted(choices, errorRateFactors(choices))
+	default:
+		return pickWeighted(choices, nil)
+	}
+}
+
+// pickLeastInflight 进行中的请求数按权重折算，最少的优先，相同时随机
+func pickLeastInflight(choices []*ChannelChoice) *ChannelChoice {
+	var best []*ChannelChoice
+	bestScore := math.MaxFloat64
+
+	for _, choice := range choices {
+		score := float64(choice.Stats.Inflight()+1) / float64(*choice.Channel.Weight)
+		if score < bestScore {
+			bestScore = score
+			best = []*ChannelChoice{choice}
+		} else if score == bestScore {
+			best = append(best, choice)
+		}
+	}
+
+	return best[rand.Intn(len(best))]
+}
+
+// ewmaTTFTFactors 延迟越高权重越低，没有样本的渠道按最快的计算
+func ewmaTTFTFactors(choices []*ChannelChoice) []float64 {
+	factors := make([]float64, len(choices))
+	latencies := make([]float64, len(choices))
+	minLatency := 0.0
+
+	for i, choice := range choices {
+		latencies[i], _, _ = choice.Stats.Snapshot()
+		if latencies[i] > 0 && (minLatency == 0 || latencies[i] < minLatency) {
+			minLatency = latencies[i]
+		}
+	}
+
+	for i := range choices {
+		if latencies[i] == 0 || minLatency == 0 {
+			factors[i] = 1
+			continue
+		}
+		factors[i] = minLatency / latencies[i]
+	}
+
+	return factors
+}
+
+// errorRateFactors 错误率越高权重越低
+func errorRateFactors(choices []*ChannelChoice) []float64 {
+	factors := make([]float64, len(choices))
+
+	for i, choice := range choices {
+		_, errorRate, _ := choice.Stats.Snapshot()
+		factors[i] = (1 - errorRate) * (1 - errorRate)
+	}
+
+	return factors
+}
+
+func pickWeighted(choices []*ChannelChoice, factors []float64) *ChannelChoice {
+	weights := make([]float64, len(choices))
+	totalWeight := 0.0
+
+	for i, choice := range choices {
+		weight := float64(*choice.Channel.Weight)
+		if factors != nil {
+			weight *= math.Max(factors[i], balanceMinWeightFactor)
+		}
+		weights[i] = weight
+		totalWeight += weight
+	}
+
+	choiceWeight := rand.Float64() * totalWeight
+	for i, choice := range choices {
+		choiceWeight -= weights[i]
+		if choiceWeight < 0 {
+			return choice
+		}
+	}
+
+	return choices[len(choices)-1]
+}
+
+// pickAffinityChannel 加权的最高随机权重哈希（Rendezvous Hashing）
+// 渠道增减时只有落在该渠道上的 key 会重新分配
+func pickAffinityChannel(affinityKey string, choices []*ChannelChoice) *ChannelChoice {
+	var best *ChannelChoice
+	bestScore := math.Inf(-1)
+
+	for _, choice := range choices {
+		hash := fnv.New64a()
+		hash.Write([]byte(affinityKey + ":" + strconv.Itoa(choice.Channel.Id)))
+		// 映射到 (0, 1) 区间
+		u := (float64(mixHash(hash.Sum64())>>11) + 0.5) / float64(1<<53)
+		score := -float64(*choice.Channel.Weight) / math.Log(u)
+		if score > bestScore {
+			bestScore = score
+			best = choice
+		}
+	}
+
+	return best
+}
+
+// mixHash fnv 的高位分布不够均匀，再做一次混淆
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
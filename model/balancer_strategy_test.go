@@ -0,0 +1,329 @@
+package model
+
+import (
+	"one-api/common/config"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChoice(id int, weight uint) *ChannelChoice {
+	return &ChannelChoice{
+		Channel: &Channel{Id: id, Weight: &weight},
+		Stats:   &ChannelStats{},
+	}
+}
+
+type statsSample struct {
+	ttft    time.Duration
+	success bool
+}
+
+func TestChannelStatsDone(t *testing.T) {
+	cases := []struct {
+		name      string
+		samples   []statsSample
+		ewmaTTFT  float64
+		errorRate float64
+	}{
+		{
+			name:      "first sample is taken as is",
+			samples:   []statsSample{{100 * time.Millisecond, true}},
+			ewmaTTFT:  100,
+			errorRate: 0,
+		},
+		{
+			name:      "first failure",
+			samples:   []statsSample{{0, false}},
+			ewmaTTFT:  0,
+			errorRate: 1,
+		},
+		{
+			name:      "moving average",
+			samples:   []statsSample{{100 * time.Millisecond, true}, {200 * time.Millisecond, true}},
+			ewmaTTFT:  0.2*200 + 0.8*100,
+			errorRate: 0,
+		},
+		{
+			name:      "failure does not change latency",
+			samples:   []statsSample{{100 * time.Millisecond, true}, {500 * time.Millisecond, false}},
+			ewmaTTFT:  100,
+			errorRate: 0.2,
+		},
+		{
+			name:      "success without ttft",
+			samples:   []statsSample{{0, false}, {0, true}, {0, true}},
+			ewmaTTFT:  0,
+			errorRate: 0.8 * 0.8,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := &ChannelStats{}
+			for _, sample := range tc.samples {
+				stats.start()
+				stats.done(sample.ttft, sample.success)
+			}
+
+			ewmaTTFT, errorRate, samples := stats.Snapshot()
+			assert.InDelta(t, tc.ewmaTTFT, ewmaTTFT, 1e-9)
+			assert.InDelta(t, tc.errorRate, errorRate, 1e-9)
+			assert.Equal(t, int64(len(tc.samples)), samples)
+			assert.Equal(t, int64(0), stats.Inflight())
+		})
+	}
+}
+
+func TestEWMATTFTFactors(t *testing.T) {
+	cases := []struct {
+		name      string
+		latencies []float64
+		factors   []float64
+	}{
+		{
+			name:      "no samples",
+			latencies: []float64{0, 0},
+			factors:   []float64{1, 1},
+		},
+		{
+			name:      "relative to the fastest",
+			latencies: []float64{100, 200, 400},
+			factors:   []float64{1, 0.5, 0.25},
+		},
+		{
+			name:      "channel without samples counts as the fastest",
+			latencies: []float64{0, 300, 100},
+			factors:   []float64{1, 1.0 / 3, 1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			choices := make([]*ChannelChoice, len(tc.latencies))
+			for i, latency := range tc.latencies {
+				choices[i] = newTestChoice(i+1, 1)
+				choices[i].Stats.ewmaTTFT = latency
+			}
+
+			assert.InDeltaSlice(t, tc.factors, ewmaTTFTFactors(choices), 1e-9)
+		})
+	}
+}
+
+func TestErrorRateFactors(t *testing.T) {
+	rates := []float64{0, 0.5, 1}
+	choices := make([]*ChannelChoice, len(rates))
+	for i, rate := range rates {
+		choices[i] = newTestChoice(i+1, 1)
+		choices[i].Stats.errorRate = rate
+	}
+
+	assert.InDeltaSlice(t, []float64{1, 0.25, 0}, errorRateFactors(choices), 1e-9)
+}
+
+func TestPickLeastInflight(t *testing.T) {
+	cases := []struct {
+		name     string
+		weights  []uint
+		inflight []int64
+		expected int
+	}{
+		{
+			name:     "fewest inflight",
+			weights:  []uint{1, 1, 1},
+			inflight: []int64{3, 1, 2},
+			expected: 2,
+		},
+		{
+			name:     "scaled by weight",
+			weights:  []uint{1, 4},
+			inflight: []int64{1, 3},
+			expected: 2,
+		},
+		{
+			name:     "idle channel",
+			weights:  []uint{10, 1},
+			inflight: []int64{20, 0},
+			expected: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			choices := make([]*ChannelChoice, len(tc.weights))
+			for i, weight := range tc.weights {
+				choices[i] = newTestChoice(i+1, weight)
+				choices[i].Stats.inflight = tc.inflight[i]
+			}
+
+			assert.Equal(t, tc.expected, pickLeastInflight(choices).Channel.Id)
+		})
+	}
+}
+
+func TestPickChannelDistribution(t *testing.T) {
+	cases := []struct {
+		name       string
+		strategy   string
+		weights    []uint
+		latencies  []float64
+		errorRates []float64
+		shares     []float64
+	}{
+		{
+			name:     "weight",
+			strategy: BalanceStrategyWeight,
+			weights:  []uint{1, 3},
+			shares:   []float64{0.25, 0.75},
+		},
+		{
+			name:      "ewma ttft",
+			strategy:  BalanceStrategyEWMATTFT,
+			weights:   []uint{1, 1},
+			latencies: []float64{100, 300},
+			shares:    []float64{0.75, 0.25},
+		},
+		{
+			name:       "error rate keeps the minimum share",
+			strategy:   BalanceStrategyErrorRate,
+			weights:    []uint{1, 1},
+			errorRates: []float64{0, 1},
+			shares:     []float64{1 / (1 + balanceMinWeightFactor), balanceMinWeightFactor / (1 + balanceMinWeightFactor)},
+		},
+	}
+
+	const rounds = 20000
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			choices := make([]*ChannelChoice, len(tc.weights))
+			for i, weight := range tc.weights {
+				choices[i] = newTestChoice(i, weight)
+				if tc.latencies != nil {
+					choices[i].Stats.ewmaTTFT = tc.latencies[i]
+				}
+				if tc.errorRates != nil {
+					choices[i].Stats.errorRate = tc.errorRates[i]
+				}
+			}
+
+			counts := make([]int, len(choices))
+			for i := 0; i < rounds; i++ {
+				counts[pickChannel(tc.strategy, choices).Channel.Id]++
+			}
+
+			for i, share := range tc.shares {
+				assert.InDelta(t, share, float64(counts[i])/rounds, 0.02)
+			}
+		})
+	}
+}
+
+func TestGetBalanceStrategy(t *testing.T) {
+	defaultStrategy := config.ChannelBalanceStrategy
+	defer func() {
+		config.ChannelBalanceStrategy = defaultStrategy
+		UpdateBalanceRulesByJSONString("")
+	}()
+
+	err := UpdateBalanceRulesByJSONString(`{"groups":{"vip":"least_inflight"},"models":{"gpt-4o":"ewma_ttft","claude-*":"error_rate"}}`)
+	assert.Nil(t, err)
+	config.ChannelBalanceStrategy = ""
+
+	cases := []struct {
+		group     string
+		modelName string
+		expected  string
+	}{
+		{"vip", "gpt-4o", BalanceStrategyEWMATTFT},
+		{"default", "claude-3-opus", BalanceStrategyErrorRate},
+		{"vip", "gpt-3.5-turbo", BalanceStrategyLeastInflight},
+		{"default", "gpt-3.5-turbo", BalanceStrategyWeight},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, GetBalanceStrategy(tc.group, tc.modelName, []string{"claude-*"}), tc.group+"/"+tc.modelName)
+	}
+
+	config.ChannelBalanceStrategy = BalanceStrategyLeastInflight
+	assert.Equal(t, BalanceStrategyLeastInflight, GetBalanceStrategy("default", "gpt-3.5-turbo", nil))
+
+	assert.NotNil(t, UpdateBalanceRulesByJSONString(`{"groups":{"vip":"fastest"}}`))
+}
+
+func TestPickAffinityChannel(t *testing.T) {
+	choices := []*ChannelChoice{
+		newTestChoice(1, 1),
+		newTestChoice(2, 1),
+		newTestChoice(3, 1),
+	}
+
+	cases := []struct {
+		name    string
+		choices []*ChannelChoice
+	}{
+		{"empty", nil},
+		{"single", choices[:1]},
+		{"multiple", choices},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			choice := pickAffinityChannel("session-1", tc.choices)
+			if len(tc.choices) == 0 {
+				assert.Nil(t, choice)
+				return
+			}
+
+			// 同一个 key 总是选中同一个渠道
+			for i := 0; i < 10; i++ {
+				assert.Same(t, choice, pickAffinityChannel("session-1", tc.choices))
+			}
+		})
+	}
+}
+
+func TestPickAffinityChannelRemoval(t *testing.T) {
+	choices := []*ChannelChoice{
+		newTestChoice(1, 1),
+		newTestChoice(2, 1),
+		newTestChoice(3, 1),
+		newTestChoice(4, 1),
+	}
+	removed := choices[3]
+	remaining := choices[:3]
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := "user-" + strconv.Itoa(i)
+		before := pickAffinityChannel(key, choices)
+		after := pickAffinityChannel(key, remaining)
+
+		// 只有原本落在被移除渠道上的 key 会重新分配
+		if before != removed {
+			assert.Same(t, before, after, key)
+		} else {
+			moved++
+		}
+	}
+
+	assert.InDelta(t, 250, moved, 60)
+}
+
+func TestPickAffinityChannelWeight(t *testing.T) {
+	choices := []*ChannelChoice{
+		newTestChoice(0, 1),
+		newTestChoice(1, 3),
+	}
+
+	const rounds = 20000
+	counts := make([]int, len(choices))
+	for i := 0; i < rounds; i++ {
+		counts[pickAffinityChannel("key-"+strconv.Itoa(i), choices).Channel.Id]++
+	}
+
+	assert.InDelta(t, 0.25, float64(counts[0])/rounds, 0.02)
+	assert.InDelta(t, 0.75, float64(counts[1])/rounds, 0.02)
+}
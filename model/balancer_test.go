@@ -0,0 +1,220 @@
+package model
+
+import (
+	"one-api/common/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setCircuitBreakerConfig(t *testing.T, threshold, windowSeconds, openSeconds, halfOpenRequests int) {
+	threshold, config.CircuitBreakerThreshold = config.CircuitBreakerThreshold, threshold
+	windowSeconds, config.CircuitBreakerWindowSeconds = config.CircuitBreakerWindowSeconds, windowSeconds
+	openSeconds, config.CircuitBreakerOpenSeconds = config.CircuitBreakerOpenSeconds, openSeconds
+	halfOpenRequests, config.CircuitBreakerHalfOpenRequests = config.CircuitBreakerHalfOpenRequests, halfOpenRequests
+
+	t.Cleanup(func() {
+		config.CircuitBreakerThreshold = threshold
+		config.CircuitBreakerWindowSeconds = windowSeconds
+		config.CircuitBreakerOpenSeconds = openSeconds
+		config.CircuitBreakerHalfOpenRequests = halfOpenRequests
+	})
+}
+
+type breakerStep struct {
+	op        string // failure, success, acquire
+	now       int64
+	state     string
+	available bool
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	// 阈值 3，窗口 60 秒，打开 30 秒，半开时放行 1 个探测请求
+	setCircuitBreakerConfig(t, 3, 60, 30, 1)
+
+	cases := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "opens at the threshold",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 101, CircuitClosed, true},
+				{"failure", 102, CircuitOpen, false},
+			},
+		},
+		{
+			name: "failures outside the window are not counted",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 101, CircuitClosed, true},
+				{"failure", 160, CircuitClosed, true},
+				{"failure", 161, CircuitClosed, true},
+				{"failure", 162, CircuitOpen, false},
+			},
+		},
+		{
+			name: "success does not clear failures in the window",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 101, CircuitClosed, true},
+				{"success", 102, CircuitClosed, true},
+				{"failure", 103, CircuitOpen, false},
+			},
+		},
+		{
+			name: "half open probe succeeds",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitOpen, false},
+				{"acquire", 129, CircuitOpen, false},
+				{"acquire", 130, CircuitHalfOpen, false},
+				{"success", 131, CircuitClosed, true},
+				{"failure", 132, CircuitClosed, true},
+			},
+		},
+		{
+			name: "half open probe fails",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitOpen, false},
+				{"acquire", 130, CircuitHalfOpen, false},
+				{"failure", 131, CircuitOpen, false},
+				{"acquire", 160, CircuitOpen, false},
+				{"acquire", 161, CircuitHalfOpen, false},
+			},
+		},
+		{
+			name: "stuck probe is replaced after the open time",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitOpen, false},
+				{"acquire", 130, CircuitHalfOpen, false},
+				{"acquire", 159, CircuitHalfOpen, false},
+				{"acquire", 160, CircuitHalfOpen, false},
+			},
+		},
+		{
+			name: "failures while open are ignored",
+			steps: []breakerStep{
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitClosed, true},
+				{"failure", 100, CircuitOpen, false},
+				{"failure", 120, CircuitOpen, false},
+				{"acquire", 130, CircuitHalfOpen, false},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			breaker := newCircuitBreaker()
+			for i, step := range tc.steps {
+				// acquire 只在可用时才会被调用，先检查可用性
+				if step.op == "acquire" {
+					if !breaker.available(step.now) {
+						assert.Equal(t, step.state, breaker.State, "step %d", i)
+						continue
+					}
+					breaker.acquire(step.now)
+				}
+
+				switch step.op {
+				case "failure":
+					breaker.failure(step.now)
+				case "success":
+					breaker.success()
+				}
+
+				assert.Equal(t, step.state, breaker.State, "step %d", i)
+				assert.Equal(t, step.available, breaker.available(step.now), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	setCircuitBreakerConfig(t, 0, 60, 30, 1)
+
+	breaker := newCircuitBreaker()
+	for i := int64(0); i < 10; i++ {
+		breaker.failure(100 + i)
+	}
+
+	assert.Equal(t, CircuitClosed, breaker.State)
+	assert.True(t, breaker.available(110))
+}
+
+func TestCircuitBreakerHalfOpenRequests(t *testing.T) {
+	setCircuitBreakerConfig(t, 1, 60, 30, 2)
+
+	breaker := newCircuitBreaker()
+	breaker.failure(100)
+	assert.False(t, breaker.available(129))
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, breaker.available(130))
+		breaker.acquire(130)
+	}
+	assert.Equal(t, CircuitHalfOpen, breaker.State)
+	assert.False(t, breaker.available(130))
+}
+
+func TestChannelBreakers(t *testing.T) {
+	setCircuitBreakerConfig(t, 2, 60, 30, 1)
+
+	cases := []struct {
+		name      string
+		results   []RequestResult
+		available map[string]bool
+	}{
+		{
+			name:      "model failures only affect the model",
+			results:   []RequestResult{RequestModelFailure, RequestModelFailure},
+			available: map[string]bool{"gpt-4o": false, "gpt-4o-mini": true, "": true},
+		},
+		{
+			name:      "channel failures affect every model",
+			results:   []RequestResult{RequestChannelFailure, RequestChannelFailure},
+			available: map[string]bool{"gpt-4o": false, "gpt-4o-mini": false, "": false},
+		},
+		{
+			name:      "failures in the window are counted across successes",
+			results:   []RequestResult{RequestModelFailure, RequestSuccess, RequestModelFailure},
+			available: map[string]bool{"gpt-4o": false, "gpt-4o-mini": true, "": true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			breakers := newChannelBreakers()
+			for _, result := range tc.results {
+				breakers.record("gpt-4o", result, 100)
+			}
+
+			for modelName, available := range tc.available {
+				assert.Equal(t, available, breakers.available(modelName, 101), modelName)
+			}
+		})
+	}
+}
+
+func TestChannelBreakersCooldowns(t *testing.T) {
+	breakers := newChannelBreakers()
+	breakers.cooldowns("gpt-4o", 110)
+
+	assert.False(t, breakers.available("gpt-4o", 110))
+	assert.True(t, breakers.available("gpt-4o-mini", 110))
+	assert.True(t, breakers.available("gpt-4o", 111))
+
+	breakers.cooldowns("", 120)
+	assert.False(t, breakers.available("gpt-4o-mini", 115))
+
+	breakers.reset()
+	assert.True(t, breakers.available("gpt-4o", 105))
+	assert.Nil(t, breakers.status().Models)
+}
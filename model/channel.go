@@ -34,8 +34,14 @@ type Channel struct {
 	TestModel          string  `json:"test_model" form:"test_model" gorm:"type:varchar(50);default:''"`
 	OnlyChat           bool    `json:"only_chat" form:"only_chat" gorm:"default:false"`
 	PreCost            int     `json:"pre_cost" form:"pre_cost" gorm:"default:1"`
+	MaxConcurrency     int     `json:"max_concurrency" form:"max_concurrency" gorm:"default:0"` // 0 不限制
+	RPM                int     `json:"rpm" form:"rpm" gorm:"default:0"`
+	TPM                int     `json:"tpm" form:"tpm" gorm:"default:0"`
 
 	Plugin *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
+
+	// 熔断器状态，只在管理接口中返回
+	CircuitBreaker *CircuitBreakerStatus `json:"circuit_breaker,omitempty" form:"-" gorm:"-"`
 }
 
 type PluginType map[string]map[string]interface{}
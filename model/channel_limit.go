@@ -0,0 +1,491 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var ErrChannelBusy = errors.New("channels are busy")
+
+// 正在排队等待渠道的请求数
+var channelWaiting int64
+
+func (channel *Channel) HasLimit() bool {
+	return channel.MaxConcurrency > 0 || channel.RPM > 0 || channel.TPM > 0
+}
+
+// channelLimiter 渠道的并发、RPM、TPM 限制
+// RPM 和 TPM 使用前后两个分钟的计数估算滑动窗口
+type channelLimiter interface {
+	// reserve 检查限制并占用一个并发名额和一次 RPM 计数，检查和占用是原子的，超过限制时不占用
+	reserve(channel *Channel) bool
+	// acquire 不检查限制直接占用，用于指定渠道的请求
+	acquire(channel *Channel)
+	addTokens(channel *Channel, tokens int)
+	// release 退还并发名额，已计入的 RPM 不退还
+	release(channel *Channel, completionTokens int)
+}
+
+var memoryLimiter = &memoryChannelLimiter{
+	counters: make(map[int]*rateCounter),
+}
+
+var redisLimiter = &redisChannelLimiter{}
+
+func getChannelLimiter() channelLimiter {
+	if config.RedisEnabled {
+		return redisLimiter
+	}
+
+	return memoryLimiter
+}
+
+func (channel *Channel) rateLimits() rateLimits {
+	return rateLimits{
+		concurrency: channel.MaxConcurrency,
+		rpm:         channel.RPM,
+		tpm:         channel.TPM,
+	}
+}
+
+// ChannelReservation 选中渠道时占用的名额，请求发出后由 RequestStart 接管，没有发出请求时需要调用 Release 退还
+type ChannelReservation struct {
+	channel *Channel
+	// 0 已占用，1 已发出请求，2 已退还
+	state int32
+}
+
+func reserveChannel(channel *Channel) (*ChannelReservation, bool) {
+	if !channel.HasLimit() {
+		return nil, true
+	}
+
+	if !getChannelLimiter().reserve(channel) {
+		return nil, false
+	}
+
+	return &ChannelReservation{channel: channel}, true
+}
+
+func (r *ChannelReservation) ChannelId() int {
+	return r.channel.Id
+}
+
+// Release 退还未使用的名额，可以重复调用
+func (r *ChannelReservation) Release() {
+	if r == nil || !atomic.CompareAndSwapInt32(&r.state, 0, 2) {
+		return
+	}
+
+	getChannelLimiter().release(r.channel, 0)
+}
+
+// start 名额转为进行中的请求，由 RequestDone 释放
+func (r *ChannelReservation) start(channelId int) bool {
+	return r != nil && r.channel.Id == channelId && atomic.CompareAndSwapInt32(&r.state, 0, 1)
+}
+
+func channelLimitAcquire(channel *Channel) {
+	if !channel.HasLimit() {
+		return
+	}
+
+	getChannelLimiter().acquire(channel)
+}
+
+func channelLimitAddTokens(channel *Channel, tokens int) {
+	if !channel.HasLimit() {
+		return
+	}
+
+	getChannelLimiter().addTokens(channel, tokens)
+}
+
+func channelLimitRelease(channel *Channel, completionTokens int) {
+	if !channel.HasLimit() {
+		return
+	}
+
+	getChannelLimiter().release(channel, completionTokens)
+}
+
+// rateLimits 并发、RPM、TPM、每日请求数的上限，0 表示不限制
+type rateLimits struct {
+	concurrency int
+	rpm         int
+	tpm         int
+	daily       int
+}
+
+// rateUsage 占用名额之前的用量
+type rateUsage struct {
+	inflight int64
+	requests float64
+	tokens   float64
+	daily    int64
+}
+
+// exceeded 返回触发的限制，为空表示未超限
+func (l rateLimits) exceeded(usage rateUsage) string {
+	switch {
+	case l.concurrency > 0 && usage.inflight >= int64(l.concurrency):
+		return TokenLimitConcurrency
+	case l.daily > 0 && usage.daily >= int64(l.daily):
+		return TokenLimitDailyRequests
+	case l.rpm > 0 && usage.requests >= float64(l.rpm):
+		return TokenLimitRequests
+	case l.tpm > 0 && usage.tokens >= float64(l.tpm):
+		return TokenLimitTokens
+	}
+
+	return ""
+}
+
+// slidingCount 按上一分钟剩余的比例估算最近一分钟的计数
+func slidingCount(now time.Time, previous, current int64) float64 {
+	return float64(previous)*previousMinuteWeight(now) + float64(current)
+}
+
+func previousMinuteWeight(now time.Time) float64 {
+	return 1 - float64(now.Unix()%60)/60
+}
+
+type minuteCounter struct {
+	minute   int64
+	previous int64
+	current  int64
+}
+
+func (m *minuteCounter) rotate(now time.Time) {
+	minute := now.Unix() / 60
+	if minute == m.minute {
+		return
+	}
+
+	if minute == m.minute+1 {
+		m.previous = m.current
+	} else {
+		m.previous = 0
+	}
+	m.current = 0
+	m.minute = minute
+}
+
+func (m *minuteCounter) count(now time.Time) float64 {
+	m.rotate(now)
+	return slidingCount(now, m.previous, m.current)
+}
+
+func (m *minuteCounter) add(now time.Time, n int64) {
+	m.rotate(now)
+	m.current += n
+}
+
+// rateCounter 内存中的限流计数，调用方需要持有锁
+type rateCounter struct {
+	inflight int64
+	requests minuteCounter
+	tokens   minuteCounter
+	day      string
+	daily    int64
+}
+
+func (r *rateCounter) rotateDay(now time.Time) {
+	day := now.Format("20060102")
+	if r.day != day {
+		r.day = day
+		r.daily = 0
+	}
+}
+
+func (r *rateCounter) usage(now time.Time) rateUsage {
+	r.rotateDay(now)
+	return rateUsage{
+		inflight: r.inflight,
+		requests: r.requests.count(now),
+		tokens:   r.tokens.count(now),
+		daily:    r.daily,
+	}
+}
+
+func (r *rateCounter) acquire(now time.Time) {
+	r.rotateDay(now)
+	r.inflight++
+	r.requests.add(now, 1)
+	r.daily++
+}
+
+// reserve 未超过限制时占用名额，返回占用之前的用量和触发的限制
+func (r *rateCounter) reserve(limits rateLimits, now time.Time) (rateUsage, string) {
+	usage := r.usage(now)
+	exceeded := limits.exceeded(usage)
+	if exceeded == "" {
+		r.acquire(now)
+	}
+
+	return usage, exceeded
+}
+
+func (r *rateCounter) release(now time.Time, tokens int) {
+	if r.inflight > 0 {
+		r.inflight--
+	}
+	r.tokens.add(now, int64(tokens))
+}
+
+func rateCounterOf(counters map[int]*rateCounter, id int) *rateCounter {
+	counter, ok := counters[id]
+	if !ok {
+		counter = &rateCounter{}
+		counters[id] = counter
+	}
+	return counter
+}
+
+type memoryChannelLimiter struct {
+	sync.Mutex
+	counters map[int]*rateCounter
+}
+
+func (l *memoryChannelLimiter) reserve(channel *Channel) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	_, exceeded := rateCounterOf(l.counters, channel.Id).reserve(channel.rateLimits(), time.Now())
+	return exceeded == ""
+}
+
+func (l *memoryChannelLimiter) acquire(channel *Channel) {
+	l.Lock()
+	defer l.Unlock()
+
+	rateCounterOf(l.counters, channel.Id).acquire(time.Now())
+}
+
+func (l *memoryChannelLimiter) addTokens(channel *Channel, tokens int) {
+	l.Lock()
+	defer l.Unlock()
+
+	rateCounterOf(l.counters, channel.Id).tokens.add(time.Now(), int64(tokens))
+}
+
+func (l *memoryChannelLimiter) release(channel *Channel, completionTokens int) {
+	l.Lock()
+	defer l.Unlock()
+
+	rateCounterOf(l.counters, channel.Id).release(time.Now(), completionTokens)
+}
+
+// redisChannelLimiter 多节点部署时共享计数
+type redisChannelLimiter struct{}
+
+// 并发计数的过期时间，避免节点异常退出后计数无法释放
+const inflightExpiration = 10 * time.Minute
+
+// 每日计数的过期时间
+const dailyExpiration = 25 * time.Hour
+
+// rateReserveScript 检查用量和占用名额在同一个脚本中完成，避免并发请求同时通过检查
+// KEYS: 并发、上一分钟 RPM、当前分钟 RPM、上一分钟 TPM、当前分钟 TPM、每日请求数
+// ARGV: 并发上限、RPM、TPM、每日上限、上一分钟的权重、并发计数过期秒数、每日计数过期秒数
+// 返回: 是否占用成功、占用之前的并发、RPM、TPM、每日请求数
+var rateReserveScript = goredis.NewScript(`
+local function get(key)
+	return tonumber(redis.call('GET', key) or '0') or 0
+end
+
+local concurrency, rpm, tpm, daily = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local weight = tonumber(ARGV[5])
+local inflight, requests, tokens, days = 0, 0, 0, 0
+if concurrency > 0 then inflight = get(KEYS[1]) end
+if rpm > 0 then requests = get(KEYS[2]) * weight + get(KEYS[3]) end
+if tpm > 0 then tokens = get(KEYS[4]) * weight + get(KEYS[5]) end
+if daily > 0 then days = get(KEYS[6]) end
+
+local usage = {tostring(inflight), tostring(requests), tostring(tokens), tostring(days)}
+if (concurrency > 0 and inflight >= concurrency) or (daily > 0 and days >= daily)
+	or (rpm > 0 and requests >= rpm) or (tpm > 0 and tokens >= tpm) then
+	return {0, unpack(usage)}
+end
+
+if concurrency > 0 then
+	redis.call('INCR', KEYS[1])
+	redis.call('EXPIRE', KEYS[1], ARGV[6])
+end
+if rpm > 0 then
+	redis.call('INCR', KEYS[3])
+	redis.call('EXPIRE', KEYS[3], 120)
+end
+if daily > 0 then
+	redis.call('INCR', KEYS[6])
+	redis.call('EXPIRE', KEYS[6], ARGV[7])
+end
+return {1, unpack(usage)}
+`)
+
+// redisRateReserve 执行 rateReserveScript，Redis 出错时放行，避免影响正常请求
+func redisRateReserve(ctx context.Context, key, dailyKey string, limits rateLimits, now time.Time) (rateUsage, string) {
+	minute := now.Unix() / 60
+	keys := []string{
+		key + ":inflight",
+		minuteKey(key+":rpm", minute-1),
+		minuteKey(key+":rpm", minute),
+		minuteKey(key+":tpm", minute-1),
+		minuteKey(key+":tpm", minute),
+		dailyKey,
+	}
+	args := []any{
+		limits.concurrency,
+		limits.rpm,
+		limits.tpm,
+		limits.daily,
+		previousMinuteWeight(now),
+		int(inflightExpiration / time.Second),
+		int(dailyExpiration / time.Second),
+	}
+
+	result, err := rateReserveScript.Run(ctx, redis.RDB, keys, args...).Slice()
+	if err != nil || len(result) != 5 {
+		if err != nil {
+			logger.SysError("reserve rate limit error: " + err.Error())
+		}
+		return rateUsage{}, ""
+	}
+
+	values := make([]float64, 4)
+	for i := range values {
+		if str, ok := result[i+1].(string); ok {
+			values[i], _ = strconv.ParseFloat(str, 64)
+		}
+	}
+	usage := rateUsage{
+		inflight: int64(values[0]),
+		requests: values[1],
+		tokens:   values[2],
+		daily:    int64(values[3]),
+	}
+
+	if reserved, _ := result[0].(int64); reserved == 1 {
+		return usage, ""
+	}
+
+	exceeded := limits.exceeded(usage)
+	if exceeded == "" {
+		// 浮点数转换的精度问题，按脚本的结果处理
+		exceeded = TokenLimitRequests
+	}
+	return usage, exceeded
+}
+
+func channelLimitKey(channelId int) string {
+	return fmt.Sprintf("channel_limit:%d", channelId)
+}
+
+func redisMinuteAdd(ctx context.Context, key string, now time.Time, n int64) {
+	if n <= 0 {
+		return
+	}
+
+	redisCounterAdd(ctx, minuteKey(key, now.Unix()/60), n, 2*time.Minute)
+}
+
+func redisCounterAdd(ctx context.Context, key string, n int64, expiration time.Duration) {
+	pipe := redis.RDB.TxPipeline()
+	pipe.IncrBy(ctx, key, n)
+	pipe.Expire(ctx, key, expiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.SysError("update rate limit counter error: " + err.Error())
+	}
+}
+
+// redisCounterRelease 计数减一，不会小于 0
+func redisCounterRelease(ctx context.Context, key string, expiration time.Duration) {
+	value, err := redis.RDB.Decr(ctx, key).Result()
+	if err != nil {
+		logger.SysError("update rate limit counter error: " + err.Error())
+	} else if value < 0 {
+		redis.RDB.Set(ctx, key, 0, expiration)
+	}
+}
+
+func minuteKey(key string, minute int64) string {
+	return key + ":" + strconv.FormatInt(minute, 10)
+}
+
+func (l *redisChannelLimiter) reserve(channel *Channel) bool {
+	_, exceeded := redisRateReserve(context.Background(), channelLimitKey(channel.Id), "", channel.rateLimits(), time.Now())
+	return exceeded == ""
+}
+
+func (l *redisChannelLimiter) acquire(channel *Channel) {
+	ctx := context.Background()
+	key := channelLimitKey(channel.Id)
+
+	if channel.MaxConcurrency > 0 {
+		redisCounterAdd(ctx, key+":inflight", 1, inflightExpiration)
+	}
+	if channel.RPM > 0 {
+		redisMinuteAdd(ctx, key+":rpm", time.Now(), 1)
+	}
+}
+
+func (l *redisChannelLimiter) addTokens(channel *Channel, tokens int) {
+	if channel.TPM > 0 {
+		redisMinuteAdd(context.Background(), channelLimitKey(channel.Id)+":tpm", time.Now(), int64(tokens))
+	}
+}
+
+func (l *redisChannelLimiter) release(channel *Channel, completionTokens int) {
+	ctx := context.Background()
+	key := channelLimitKey(channel.Id)
+
+	if channel.MaxConcurrency > 0 {
+		redisCounterRelease(ctx, key+":inflight", inflightExpiration)
+	}
+	if channel.TPM > 0 {
+		redisMinuteAdd(ctx, key+":tpm", time.Now(), int64(completionTokens))
+	}
+}
+
+// WaitNext 渠道都达到限制时排队等待，队列已满或等待超时返回 ErrChannelBusy
+func (cc *ChannelsChooser) WaitNext(ctx context.Context, affinityKey, group, modelName string, filters ...ChannelsFilterFunc) (*Channel, *ChannelReservation, error) {
+	channel, reservation, err := cc.NextWithAffinity(affinityKey, group, modelName, filters...)
+	if !errors.Is(err, ErrChannelBusy) || config.ChannelLimitWaitSeconds <= 0 {
+		return channel, reservation, err
+	}
+
+	if atomic.AddInt64(&channelWaiting, 1) > int64(config.ChannelLimitQueueSize) {
+		atomic.AddInt64(&channelWaiting, -1)
+		return nil, nil, err
+	}
+	defer atomic.AddInt64(&channelWaiting, -1)
+
+	timer := time.NewTimer(time.Duration(config.ChannelLimitWaitSeconds) * time.Second)
+	defer timer.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, err
+		case <-timer.C:
+			return nil, nil, err
+		case <-ticker.C:
+			channel, reservation, err = cc.NextWithAffinity(affinityKey, group, modelName, filters...)
+			if !errors.Is(err, ErrChannelBusy) {
+				return channel, reservation, err
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingCount(t *testing.T) {
+	minute := time.Unix(1700000040, 0) // 整分钟
+
+	cases := []struct {
+		name     string
+		now      time.Time
+		previous int64
+		current  int64
+		expected float64
+	}{
+		{"start of the minute", minute, 60, 10, 70},
+		{"quarter of the minute", minute.Add(15 * time.Second), 60, 10, 55},
+		{"half of the minute", minute.Add(30 * time.Second), 60, 10, 40},
+		{"end of the minute", minute.Add(59 * time.Second), 60, 10, 11},
+		{"no previous", minute.Add(30 * time.Second), 0, 10, 10},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, slidingCount(tc.now, tc.previous, tc.current), 1e-9)
+		})
+	}
+}
+
+func TestMinuteCounter(t *testing.T) {
+	minute := time.Unix(1700000040, 0)
+
+	type step struct {
+		offset time.Duration
+		add    int64
+		count  float64
+	}
+
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "same minute",
+			steps: []step{
+				{0, 3, 3},
+				{30 * time.Second, 2, 5},
+			},
+		},
+		{
+			name: "next minute keeps the previous count",
+			steps: []step{
+				{0, 10, 10},
+				{time.Minute, 0, 10},
+				{time.Minute + 30*time.Second, 4, 9},
+				{2 * time.Minute, 0, 4},
+			},
+		},
+		{
+			name: "skipped minutes are cleared",
+			steps: []step{
+				{0, 10, 10},
+				{2 * time.Minute, 1, 1},
+				{5*time.Minute + 59*time.Second, 0, 0},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := &minuteCounter{}
+			for i, s := range tc.steps {
+				now := minute.Add(s.offset)
+				if s.add > 0 {
+					counter.add(now, s.add)
+				}
+				assert.InDelta(t, s.count, counter.count(now), 1e-9, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestRateCounterReserve(t *testing.T) {
+	now := time.Unix(1700000040, 0)
+
+	cases := []struct {
+		name     string
+		limits   rateLimits
+		admitted int
+		exceeded string
+	}{
+		{"no limit", rateLimits{}, 10, ""},
+		{"concurrency", rateLimits{concurrency: 3}, 3, TokenLimitConcurrency},
+		{"rpm", rateLimits{rpm: 4}, 4, TokenLimitRequests},
+		{"daily", rateLimits{daily: 2, rpm: 4}, 2, TokenLimitDailyRequests},
+		{"concurrency before rpm", rateLimits{concurrency: 2, rpm: 2}, 2, TokenLimitConcurrency},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := &rateCounter{}
+			admitted := 0
+			exceeded := ""
+			for i := 0; i < 10; i++ {
+				usage, result := counter.reserve(tc.limits, now)
+				if result != "" {
+					exceeded = result
+					continue
+				}
+				assert.Equal(t, int64(admitted), usage.inflight)
+				admitted++
+			}
+
+			assert.Equal(t, tc.admitted, admitted)
+			assert.Equal(t, tc.exceeded, exceeded)
+			// 超限时不占用名额
+			assert.Equal(t, int64(tc.admitted), counter.inflight)
+		})
+	}
+}
+
+func TestRateCounterTokens(t *testing.T) {
+	now := time.Unix(1700000040, 0)
+	limits := rateLimits{tpm: 100}
+	counter := &rateCounter{}
+
+	_, exceeded := counter.reserve(limits, now)
+	assert.Equal(t, "", exceeded)
+	counter.tokens.add(now, 60)
+	counter.release(now, 40)
+	assert.Equal(t, int64(0), counter.inflight)
+
+	_, exceeded = counter.reserve(limits, now.Add(time.Second))
+	assert.Equal(t, TokenLimitTokens, exceeded)
+
+	// 下一分钟过半时，上一分钟的计数只算一半
+	usage, exceeded := counter.reserve(limits, now.Add(90*time.Second))
+	assert.Equal(t, "", exceeded)
+	assert.InDelta(t, 50, usage.tokens, 1e-9)
+
+	// 退还名额时并发数不会小于 0
+	counter.release(now, 0)
+	counter.release(now, 0)
+	assert.Equal(t, int64(0), counter.inflight)
+}
+
+func TestRateCounterDailyRotation(t *testing.T) {
+	day := time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local)
+	limits := rateLimits{daily: 1}
+	counter := &rateCounter{}
+
+	_, exceeded := counter.reserve(limits, day)
+	assert.Equal(t, "", exceeded)
+	_, exceeded = counter.reserve(limits, day.Add(30*time.Second))
+	assert.Equal(t, TokenLimitDailyRequests, exceeded)
+
+	_, exceeded = counter.reserve(limits, day.Add(2*time.Minute))
+	assert.Equal(t, "", exceeded)
+}
+
+func TestChannelReservation(t *testing.T) {
+	channel := &Channel{Id: 990001, MaxConcurrency: 1}
+	defer delete(memoryLimiter.counters, channel.Id)
+
+	reservation, ok := reserveChannel(channel)
+	assert.True(t, ok)
+	assert.NotNil(t, reservation)
+
+	_, ok = reserveChannel(channel)
+	assert.False(t, ok)
+
+	// 重复退还只退还一次
+	reservation.Release()
+	reservation.Release()
+	assert.Equal(t, int64(0), memoryLimiter.counters[channel.Id].inflight)
+
+	reservation, ok = reserveChannel(channel)
+	assert.True(t, ok)
+	assert.False(t, reservation.start(channel.Id+1))
+	assert.True(t, reservation.start(channel.Id))
+	// 已发出请求后不再退还
+	reservation.Release()
+	assert.Equal(t, int64(1), memoryLimiter.counters[channel.Id].inflight)
+
+	channelLimitRelease(channel, 0)
+	assert.Equal(t, int64(0), memoryLimiter.counters[channel.Id].inflight)
+
+	// 没有限制的渠道不占用名额
+	reservation, ok = reserveChannel(&Channel{Id: 990002})
+	assert.True(t, ok)
+	assert.Nil(t, reservation)
+	reservation.Release()
+}
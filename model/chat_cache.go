@@ -9,6 +9,7 @@ import (
 type ChatCache struct {
 	Hash       string `json:"hash" gorm:"type:varchar(32);primaryKey"`
 	UserId     int    `json:"user_id" gorm:"type:int;not null;index"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(255);index"`
 	Data       string `json:"data" gorm:"type:json;not null"`
 	Expiration int64  `json:"expiration" gorm:"type:bigint;not null;index"`
 }
@@ -35,6 +36,51 @@ func GetChatCacheListByUserId(userId int) ([]*ChatCache, error) {
 	return chatCaches, err
 }
 
+var allowedChatCacheOrderFields = map[string]bool{
+	"user_id":    true,
+	"model_name": true,
+	"expiration": true,
+}
+
+type SearchChatCacheParams struct {
+	UserId    int    `form:"user_id"`
+	ModelName string `form:"model_name"`
+	PaginationParams
+}
+
+func GetChatCacheList(params *SearchChatCacheParams) (*DataResult[ChatCache], error) {
+	var chatCaches []*ChatCache
+	db := DB.Model(&ChatCache{}).Where("expiration > ?", time.Now().Unix())
+
+	if params.UserId != 0 {
+		db = db.Where("user_id = ?", params.UserId)
+	}
+	if params.ModelName != "" {
+		db = db.Where("model_name = ?", params.ModelName)
+	}
+	// 缓存表没有 id 字段，默认按过期时间排序
+	if params.Order == "" {
+		params.Order = "-expiration"
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &chatCaches, allowedChatCacheOrderFields)
+}
+
+func DeleteChatCacheByHash(hash string) (int64, error) {
+	result := DB.Where("hash = ?", hash).Delete(&ChatCache{})
+	return result.RowsAffected, result.Error
+}
+
+func DeleteChatCacheByUserId(userId int) (int64, error) {
+	result := DB.Where("user_id = ?", userId).Delete(&ChatCache{})
+	return result.RowsAffected, result.Error
+}
+
+func FlushChatCache() (int64, error) {
+	result := DB.Where("1 = 1").Delete(&ChatCache{})
+	return result.RowsAffected, result.Error
+}
+
 func RemoveChatCache() error {
 	now := time.Now().Unix()
 	return DB.Where("expiration < ?", now).Delete(ChatCache{}).Error
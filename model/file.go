@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// File 批处理的输入输出文件，内容直接保存在数据库中
+type File struct {
+	ID        int64  `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	FileID    string `json:"file_id" gorm:"type:varchar(50);uniqueIndex"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	Purpose   string `json:"purpose" gorm:"type:varchar(30);index"`
+	Filename  string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes     int    `json:"bytes"`
+	Content   string `json:"-"`
+	CreatedAt int64  `json:"created_at" gorm:"index"`
+}
+
+func GetFileByFileId(userId int, fileId string) (file *File, err error) {
+	file = &File{}
+	err = DB.Where("user_id = ? and file_id = ?", userId, fileId).Omit("content").First(file).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return
+}
+
+func GetFileContent(fileId string) (content string, err error) {
+	err = DB.Model(&File{}).Where("file_id = ?", fileId).Pluck("content", &content).Error
+	return
+}
+
+func GetUserFiles(userId int, purpose string, limit int) (files []*File, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+
+	err = tx.Omit("content").Order("id desc").Limit(limit).Find(&files).Error
+	return
+}
+
+func (file *File) Insert() error {
+	return DB.Create(file).Error
+}
+
+// AppendContent 在数据库中直接追加内容，用于批处理逐步写入结果
+func (file *File) AppendContent(content string) error {
+	contentExpr := gorm.Expr("content || ?", content)
+	if isMySQL() {
+		contentExpr = gorm.Expr("CONCAT(content, ?)", content)
+	}
+
+	return DB.Model(&File{}).Where("file_id = ?", file.FileID).Updates(map[string]any{
+		"content": contentExpr,
+		"bytes":   gorm.Expr("bytes + ?", len(content)),
+	}).Error
+}
+
+func (file *File) Delete() error {
+	return DB.Delete(file).Error
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAppendContent(t *testing.T) {
+	setupTestDB(t, &File{})
+
+	file := &File{FileID: "file-test", Content: "a\n", Bytes: 2}
+	assert.Nil(t, file.Insert())
+
+	for _, content := range []string{"b\n", "中文\n"} {
+		assert.Nil(t, file.AppendContent(content))
+	}
+
+	content, err := GetFileContent(file.FileID)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\n中文\n", content)
+
+	stored, err := GetFileByFileId(0, file.FileID)
+	assert.Nil(t, err)
+	assert.Equal(t, len(content), stored.Bytes)
+}
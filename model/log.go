@@ -24,16 +24,42 @@ type Log struct {
 	CompletionTokens int    `json:"completion_tokens" gorm:"default:0"`
 	ChannelId        int    `json:"channel_id" gorm:"index"`
 	RequestTime      int    `json:"request_time" gorm:"default:0"`
+	OrganizationId   int    `json:"organization_id" gorm:"index;default:0"`
+	// 错误日志记录上游返回的状态码
+	StatusCode int `json:"status_code" gorm:"default:0"`
+	LogTokensDetails
+	LogRequestDetails
 
 	Channel *Channel `json:"channel" gorm:"foreignKey:Id;references:ChannelId"`
 }
 
+// LogTokensDetails 缓存和推理的 tokens，已经包含在 PromptTokens 和 CompletionTokens 中
+type LogTokensDetails struct {
+	CachedTokens     int `json:"cached_tokens" gorm:"default:0"`
+	CacheWriteTokens int `json:"cache_write_tokens" gorm:"default:0"`
+	ReasoningTokens  int `json:"reasoning_tokens" gorm:"default:0"`
+}
+
+// LogRequestDetails 请求的来源和耗时等信息，用于按请求 ID 排查问题
+type LogRequestDetails struct {
+	RequestId string `json:"request_id" gorm:"type:varchar(64);index;default:''"`
+	ClientIp  string `json:"client_ip" gorm:"type:varchar(64);default:''"`
+	UserAgent string `json:"user_agent" gorm:"type:varchar(255);default:''"`
+	IsStream  bool   `json:"is_stream" gorm:"default:false"`
+	// 流式响应的首字延迟，毫秒
+	FirstResponseTime int `json:"first_response_time" gorm:"default:0"`
+	RetryTimes        int `json:"retry_times" gorm:"default:0"`
+	// 模型映射后实际请求上游的模型
+	UpstreamModel string `json:"upstream_model" gorm:"type:varchar(255);default:''"`
+}
+
 const (
 	LogTypeUnknown = iota
 	LogTypeTopup
 	LogTypeConsume
 	LogTypeManage
 	LogTypeSystem
+	LogTypeError
 )
 
 func RecordLog(userId int, logType int, content string) {
@@ -53,29 +79,51 @@ func RecordLog(userId int, logType int, content string) {
 	}
 }
 
-func RecordConsumeLog(ctx context.Context, userId int, channelId int, promptTokens int, completionTokens int, modelName string, tokenName string, quota int, content string, requestTime int) {
+func RecordConsumeLog(ctx context.Context, userId int, organizationId int, channelId int, promptTokens int, completionTokens int, tokensDetails LogTokensDetails, requestDetails LogRequestDetails, modelName string, tokenName string, quota int, content string, requestTime int) {
 	logger.LogInfo(ctx, fmt.Sprintf("record consume log: userId=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
 	if !config.LogConsumeEnabled {
 		return
 	}
 	log := &Log{
-		UserId:           userId,
-		Username:         GetUsernameById(userId),
-		CreatedAt:        utils.GetTimestamp(),
-		Type:             LogTypeConsume,
-		Content:          content,
-		PromptTokens:     promptTokens,
-		CompletionTokens: completionTokens,
-		TokenName:        tokenName,
-		ModelName:        modelName,
-		Quota:            quota,
-		ChannelId:        channelId,
-		RequestTime:      requestTime,
+		UserId:            userId,
+		Username:          GetUsernameById(userId),
+		CreatedAt:         utils.GetTimestamp(),
+		Type:              LogTypeConsume,
+		Content:           content,
+		PromptTokens:      promptTokens,
+		CompletionTokens:  completionTokens,
+		TokenName:         tokenName,
+		ModelName:         modelName,
+		Quota:             quota,
+		ChannelId:         channelId,
+		RequestTime:       requestTime,
+		OrganizationId:    organizationId,
+		LogTokensDetails:  tokensDetails,
+		LogRequestDetails: requestDetails,
 	}
-	err := DB.Create(log).Error
-	if err != nil {
-		logger.LogError(ctx, "failed to record log: "+err.Error())
+	createLog(ctx, log)
+}
+
+// RecordErrorLog 记录每一次失败的上游请求
+func RecordErrorLog(ctx context.Context, userId int, organizationId int, channelId int, requestDetails LogRequestDetails, modelName string, tokenName string, statusCode int, content string) {
+	if !config.LogErrorEnabled {
+		return
+	}
+	log := &Log{
+		UserId:         userId,
+		Username:       GetUsernameById(userId),
+		CreatedAt:      utils.GetTimestamp(),
+		Type:           LogTypeError,
+		Content:        content,
+		TokenName:      tokenName,
+		ModelName:      modelName,
+		ChannelId:      channelId,
+		OrganizationId: organizationId,
+		StatusCode:     statusCode,
+
+		LogRequestDetails: requestDetails,
 	}
+	createLog(ctx, log)
 }
 
 type LogsListParams struct {
@@ -87,6 +135,8 @@ type LogsListParams struct {
 	Username       string `form:"username"`
 	TokenName      string `form:"token_name"`
 	ChannelId      int    `form:"channel_id"`
+	StatusCode     int    `form:"status_code"`
+	RequestId      string `form:"request_id"`
 }
 
 var allowedLogsOrderFields = map[string]bool{
@@ -118,6 +168,9 @@ func GetLogsList(params *LogsListParams) (*DataResult[Log], error) {
 	if params.TokenName != "" {
 		tx = tx.Where("token_name = ?", params.TokenName)
 	}
+	if params.RequestId != "" {
+		tx = tx.Where("request_id = ?", params.RequestId)
+	}
 	if params.StartTimestamp != 0 {
 		tx = tx.Where("created_at >= ?", params.StartTimestamp)
 	}
@@ -127,6 +180,9 @@ func GetLogsList(params *LogsListParams) (*DataResult[Log], error) {
 	if params.ChannelId != 0 {
 		tx = tx.Where("channel_id = ?", params.ChannelId)
 	}
+	if params.StatusCode != 0 {
+		tx = tx.Where("status_code = ?", params.StatusCode)
+	}
 
 	return PaginateAndOrder[Log](tx, &params.PaginationParams, &logs, allowedLogsOrderFields)
 }
@@ -145,6 +201,9 @@ func GetUserLogsList(userId int, params *LogsListParams) (*DataResult[Log], erro
 	if params.TokenName != "" {
 		tx = tx.Where("token_name = ?", params.TokenName)
 	}
+	if params.RequestId != "" {
+		tx = tx.Where("request_id = ?", params.RequestId)
+	}
 	if params.StartTimestamp != 0 {
 		tx = tx.Where("created_at >= ?", params.StartTimestamp)
 	}
@@ -201,6 +260,7 @@ type LogStatistic struct {
 	PromptTokens     int64  `gorm:"column:prompt_tokens"`
 	CompletionTokens int64  `gorm:"column:completion_tokens"`
 	RequestTime      int64  `gorm:"column:request_time"`
+	ErrorCount       int64  `gorm:"column:error_count"`
 }
 
 type LogStatisticGroupModel struct {
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/metrics"
+	"sync"
+	"time"
+)
+
+// logWriter 开启批量更新后，消费日志和错误日志先写入队列，再按时间间隔或条数批量插入
+type logWriter struct {
+	sync.RWMutex
+	queue  chan *Log
+	done   chan struct{}
+	closed bool
+}
+
+var asyncLogWriter *logWriter
+
+func initLogWriter() {
+	asyncLogWriter = &logWriter{
+		queue: make(chan *Log, config.BatchUpdateQueueSize),
+		done:  make(chan struct{}),
+	}
+	go asyncLogWriter.run()
+}
+
+func stopLogWriter() {
+	if asyncLogWriter == nil {
+		return
+	}
+
+	asyncLogWriter.Lock()
+	if !asyncLogWriter.closed {
+		asyncLogWriter.closed = true
+		close(asyncLogWriter.queue)
+	}
+	asyncLogWriter.Unlock()
+
+	<-asyncLogWriter.done
+}
+
+// createLog 未开启批量更新、队列已关闭或已满时直接写入数据库
+func createLog(ctx context.Context, log *Log) {
+	if asyncLogWriter != nil && asyncLogWriter.push(log) {
+		return
+	}
+
+	err := DB.Create(log).Error
+	if err != nil {
+		logger.LogError(ctx, "failed to record log: "+err.Error())
+	}
+}
+
+func (w *logWriter) push(log *Log) bool {
+	w.RLock()
+	defer w.RUnlock()
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.queue <- log:
+		metrics.SetBatchQueueDepth(metrics.BatchQueueLogs, len(w.queue))
+		return true
+	default:
+		logger.SysError("log queue is full, writing to database directly")
+		return false
+	}
+}
+
+func (w *logWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(time.Duration(config.BatchUpdateLogInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	buffer := make([]*Log, 0, config.BatchUpdateLogSize)
+	for {
+		select {
+		case log, ok := <-w.queue:
+			if !ok {
+				w.flush(buffer)
+				return
+			}
+			buffer = append(buffer, log)
+			if len(buffer) >= config.BatchUpdateLogSize {
+				w.flush(buffer)
+				buffer = buffer[:0]
+			}
+		case <-ticker.C:
+			w.flush(buffer)
+			buffer = buffer[:0]
+		}
+	}
+}
+
+func (w *logWriter) flush(logs []*Log) {
+	metrics.SetBatchQueueDepth(metrics.BatchQueueLogs, len(w.queue))
+	if len(logs) == 0 {
+		return
+	}
+
+	err := BatchInsert(DB, logs)
+	if err != nil {
+		// 批量写入失败时逐条写入，避免一条异常数据导致整批丢失
+		logger.SysError("failed to batch insert logs: " + err.Error())
+		for _, log := range logs {
+			// 已经写入的日志会带有 id
+			if log.Id != 0 {
+				continue
+			}
+			if err := DB.Create(log).Error; err != nil {
+				logger.SysError("failed to record log: " + err.Error())
+			}
+		}
+	}
+	metrics.RecordBatchFlushed(metrics.BatchQueueLogs, len(logs))
+}
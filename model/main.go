@@ -30,11 +30,25 @@ func SetupDB() {
 	if viper.GetBool("batch_update_enabled") {
 		config.BatchUpdateEnabled = true
 		config.BatchUpdateInterval = utils.GetOrDefault("batch_update_interval", 5)
+		config.BatchUpdateMaxRecords = viper.GetInt("batch_update_max_records")
+		config.BatchUpdateLogInterval = positiveIntConfig("batch_update_log_interval", config.BatchUpdateLogInterval)
+		config.BatchUpdateLogSize = positiveIntConfig("batch_update_log_size", config.BatchUpdateLogSize)
+		config.BatchUpdateQueueSize = positiveIntConfig("batch_update_queue_size", config.BatchUpdateQueueSize)
 		logger.SysLog("batch update enabled with interval " + strconv.Itoa(config.BatchUpdateInterval) + "s")
 		InitBatchUpdater()
 	}
 }
 
+// positiveIntConfig 配置为 0 或负数时使用默认值，避免日志队列和定时器创建失败
+func positiveIntConfig(key string, defaultValue int) int {
+	value := viper.GetInt(key)
+	if value <= 0 {
+		logger.SysError(fmt.Sprintf("%s must be positive, got %d, using default %d", key, value, defaultValue))
+		return defaultValue
+	}
+	return value
+}
+
 func createRootAccountIfNeed() error {
 	var user User
 	//if user.Status != common.UserStatusEnabled {
@@ -170,6 +184,26 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&File{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&Organization{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&OrganizationMember{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&AuditLog{})
+		if err != nil {
+			return err
+		}
+		err = db.AutoMigrate(&WebhookDelivery{})
+		if err != nil {
+			return err
+		}
 
 		migrationAfter(DB)
 
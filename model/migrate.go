@@ -50,6 +50,7 @@ func migrationBefore(db *gorm.DB) error {
 
 	m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
 		removeKeyIndexMigration(),
+		dropStatisticsWithoutOrganization(),
 	})
 	return m.Migrate()
 }
@@ -125,6 +126,35 @@ func changeChannelApiVersion() *gormigrate.Migration {
 	}
 }
 
+// dropStatisticsWithoutOrganization 统计表的主键增加了 organization_id，删除旧表后重新生成
+func dropStatisticsWithoutOrganization() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202410160001",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasTable(&Statistics{}) || tx.Migrator().HasColumn(&Statistics{}, "organization_id") {
+				return nil
+			}
+			return tx.Migrator().DropTable(&Statistics{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return nil
+		},
+	}
+}
+
+func rebuildOrganizationStatistics() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202410160002",
+		Migrate: func(tx *gorm.DB) error {
+			go UpdateStatistics(StatisticsUpdateTypeALL)
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return nil
+		},
+	}
+}
+
 func migrationAfter(db *gorm.DB) error {
 	// 从库不执行
 	if !config.IsMasterNode {
@@ -134,6 +164,7 @@ func migrationAfter(db *gorm.DB) error {
 	m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
 		addStatistics(),
 		changeChannelApiVersion(),
+		rebuildOrganizationStatistics(),
 	})
 	return m.Migrate()
 }
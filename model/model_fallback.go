@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"one-api/common/logger"
+	"sync"
+)
+
+// ModelFallbacks 分组 -> 模型 -> 备用模型链，分组为 * 时对所有分组生效
+type ModelFallbacks map[string]map[string][]string
+
+var modelFallbacks = ModelFallbacks{}
+var modelFallbacksLock sync.RWMutex
+
+func ModelFallbacks2JSONString() string {
+	modelFallbacksLock.RLock()
+	defer modelFallbacksLock.RUnlock()
+
+	jsonBytes, err := json.Marshal(modelFallbacks)
+	if err != nil {
+		logger.SysError("error marshalling model fallbacks: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateModelFallbacksByJSONString(jsonStr string) error {
+	fallbacks := ModelFallbacks{}
+	if jsonStr != "" {
+		if err := json.Unmarshal([]byte(jsonStr), &fallbacks); err != nil {
+			return err
+		}
+	}
+
+	modelFallbacksLock.Lock()
+	modelFallbacks = fallbacks
+	modelFallbacksLock.Unlock()
+
+	return nil
+}
+
+// GetModelFallbacks 获取模型的备用模型链，已去掉重复和原模型
+func GetModelFallbacks(group, modelName string) []string {
+	modelFallbacksLock.RLock()
+	defer modelFallbacksLock.RUnlock()
+
+	chain, ok := modelFallbacks[group][modelName]
+	if !ok {
+		chain = modelFallbacks["*"][modelName]
+	}
+
+	fallbacks := make([]string, 0, len(chain))
+	seen := map[string]bool{modelName: true}
+	for _, fallback := range chain {
+		if fallback == "" || seen[fallback] {
+			continue
+		}
+		seen[fallback] = true
+		fallbacks = append(fallbacks, fallback)
+	}
+
+	return fallbacks
+}
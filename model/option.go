@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
@@ -40,6 +41,7 @@ func InitOptionMap() {
 	config.OptionMap["AutomaticEnableChannelEnabled"] = strconv.FormatBool(config.AutomaticEnableChannelEnabled)
 	config.OptionMap["ApproximateTokenEnabled"] = strconv.FormatBool(config.ApproximateTokenEnabled)
 	config.OptionMap["LogConsumeEnabled"] = strconv.FormatBool(config.LogConsumeEnabled)
+	config.OptionMap["LogErrorEnabled"] = strconv.FormatBool(config.LogErrorEnabled)
 	config.OptionMap["DisplayInCurrencyEnabled"] = strconv.FormatBool(config.DisplayInCurrencyEnabled)
 	config.OptionMap["ChannelDisableThreshold"] = strconv.FormatFloat(config.ChannelDisableThreshold, 'f', -1, 64)
 	config.OptionMap["EmailDomainRestrictionEnabled"] = strconv.FormatBool(config.EmailDomainRestrictionEnabled)
@@ -75,11 +77,34 @@ func InitOptionMap() {
 	config.OptionMap["QuotaPerUnit"] = strconv.FormatFloat(config.QuotaPerUnit, 'f', -1, 64)
 	config.OptionMap["RetryTimes"] = strconv.Itoa(config.RetryTimes)
 	config.OptionMap["RetryCooldownSeconds"] = strconv.Itoa(config.RetryCooldownSeconds)
+	config.OptionMap["ChannelBalanceStrategy"] = config.ChannelBalanceStrategy
+	config.OptionMap["ChannelBalanceRules"] = BalanceRules2JSONString()
+	config.OptionMap["ModelFallbacks"] = ModelFallbacks2JSONString()
+	config.OptionMap["ChannelAffinityEnabled"] = strconv.FormatBool(config.ChannelAffinityEnabled)
+	config.OptionMap["ChannelAffinityHeader"] = config.ChannelAffinityHeader
+	config.OptionMap["ChannelAffinityMessages"] = strconv.Itoa(config.ChannelAffinityMessages)
+	config.OptionMap["CircuitBreakerThreshold"] = strconv.Itoa(config.CircuitBreakerThreshold)
+	config.OptionMap["CircuitBreakerWindowSeconds"] = strconv.Itoa(config.CircuitBreakerWindowSeconds)
+	config.OptionMap["CircuitBreakerOpenSeconds"] = strconv.Itoa(config.CircuitBreakerOpenSeconds)
+	config.OptionMap["CircuitBreakerHalfOpenRequests"] = strconv.Itoa(config.CircuitBreakerHalfOpenRequests)
+	config.OptionMap["ChannelLimitQueueSize"] = strconv.Itoa(config.ChannelLimitQueueSize)
+	config.OptionMap["ChannelLimitWaitSeconds"] = strconv.Itoa(config.ChannelLimitWaitSeconds)
+	config.OptionMap["BatchDiscount"] = strconv.FormatFloat(config.BatchDiscount, 'f', -1, 64)
 
 	config.OptionMap["MjNotifyEnabled"] = strconv.FormatBool(config.MjNotifyEnabled)
 
 	config.OptionMap["ChatCacheEnabled"] = strconv.FormatBool(config.ChatCacheEnabled)
 	config.OptionMap["ChatCacheExpireMinute"] = strconv.Itoa(config.ChatCacheExpireMinute)
+	config.OptionMap["ChatCacheSemanticEnabled"] = strconv.FormatBool(config.ChatCacheSemanticEnabled)
+	config.OptionMap["ChatCacheEmbeddingChannelId"] = strconv.Itoa(config.ChatCacheEmbeddingChannelId)
+	config.OptionMap["ChatCacheEmbeddingModel"] = config.ChatCacheEmbeddingModel
+	config.OptionMap["ChatCacheSemanticMaxEntries"] = strconv.Itoa(config.ChatCacheSemanticMaxEntries)
+	config.OptionMap["AuditLogEnabled"] = strconv.FormatBool(config.AuditLogEnabled)
+	config.OptionMap["AuditLogGroups"] = config.AuditLogGroups
+	config.OptionMap["AuditLogMaxBodySize"] = strconv.Itoa(config.AuditLogMaxBodySize)
+	config.OptionMap["AuditLogRetentionDays"] = strconv.Itoa(config.AuditLogRetentionDays)
+	config.OptionMap["AuditLogRedactRules"] = config.AuditLogRedactRules
+	config.OptionMap["AuditLogRedactPatterns"] = config.AuditLogRedactPatterns
 
 	config.OptionMap["ChatImageRequestProxy"] = ""
 
@@ -139,6 +164,18 @@ var optionIntMap = map[string]*int{
 	"RetryCooldownSeconds":  &config.RetryCooldownSeconds,
 	"ChatCacheExpireMinute": &config.ChatCacheExpireMinute,
 	"PaymentMinAmount":      &config.PaymentMinAmount,
+
+	"ChannelAffinityMessages":        &config.ChannelAffinityMessages,
+	"CircuitBreakerThreshold":        &config.CircuitBreakerThreshold,
+	"CircuitBreakerWindowSeconds":    &config.CircuitBreakerWindowSeconds,
+	"CircuitBreakerOpenSeconds":      &config.CircuitBreakerOpenSeconds,
+	"CircuitBreakerHalfOpenRequests": &config.CircuitBreakerHalfOpenRequests,
+	"ChannelLimitQueueSize":          &config.ChannelLimitQueueSize,
+	"ChannelLimitWaitSeconds":        &config.ChannelLimitWaitSeconds,
+	"ChatCacheEmbeddingChannelId":    &config.ChatCacheEmbeddingChannelId,
+	"ChatCacheSemanticMaxEntries":    &config.ChatCacheSemanticMaxEntries,
+	"AuditLogMaxBodySize":            &config.AuditLogMaxBodySize,
+	"AuditLogRetentionDays":          &config.AuditLogRetentionDays,
 }
 
 var optionBoolMap = map[string]*bool{
@@ -155,9 +192,13 @@ var optionBoolMap = map[string]*bool{
 	"AutomaticEnableChannelEnabled":  &config.AutomaticEnableChannelEnabled,
 	"ApproximateTokenEnabled":        &config.ApproximateTokenEnabled,
 	"LogConsumeEnabled":              &config.LogConsumeEnabled,
+	"LogErrorEnabled":                &config.LogErrorEnabled,
 	"DisplayInCurrencyEnabled":       &config.DisplayInCurrencyEnabled,
 	"MjNotifyEnabled":                &config.MjNotifyEnabled,
 	"ChatCacheEnabled":               &config.ChatCacheEnabled,
+	"ChannelAffinityEnabled":         &config.ChannelAffinityEnabled,
+	"ChatCacheSemanticEnabled":       &config.ChatCacheSemanticEnabled,
+	"AuditLogEnabled":                &config.AuditLogEnabled,
 }
 
 var optionStringMap = map[string]*string{
@@ -184,6 +225,27 @@ var optionStringMap = map[string]*string{
 	"ChatImageRequestProxy":       &config.ChatImageRequestProxy,
 	"CFWorkerImageUrl":            &config.CFWorkerImageUrl,
 	"CFWorkerImageKey":            &config.CFWorkerImageKey,
+	"ChannelBalanceStrategy":      &config.ChannelBalanceStrategy,
+	"ChannelAffinityHeader":       &config.ChannelAffinityHeader,
+	"ChatCacheEmbeddingModel":     &config.ChatCacheEmbeddingModel,
+	"AuditLogGroups":              &config.AuditLogGroups,
+	"AuditLogRedactRules":         &config.AuditLogRedactRules,
+	"AuditLogRedactPatterns":      &config.AuditLogRedactPatterns,
+}
+
+// parseBatchDiscount 批处理折扣必须在 (0, 1] 之间，否则批处理请求会不计费
+func parseBatchDiscount(value string) (float64, error) {
+	discount, err := strconv.ParseFloat(value, 64)
+	if err != nil || discount <= 0 || discount > 1 {
+		return 0, errors.New("批处理折扣必须大于 0 且不超过 1")
+	}
+
+	return discount, nil
+}
+
+func ValidateBatchDiscount(value string) error {
+	_, err := parseBatchDiscount(value)
+	return err
 }
 
 func updateOptionMap(key string, value string) (err error) {
@@ -216,6 +278,15 @@ func updateOptionMap(key string, value string) (err error) {
 		config.QuotaPerUnit, _ = strconv.ParseFloat(value, 64)
 	case "PaymentUSDRate":
 		config.PaymentUSDRate, _ = strconv.ParseFloat(value, 64)
+	case "ChannelBalanceRules":
+		err = UpdateBalanceRulesByJSONString(value)
+	case "ModelFallbacks":
+		err = UpdateModelFallbacksByJSONString(value)
+	case "BatchDiscount":
+		var discount float64
+		if discount, err = parseBatchDiscount(value); err == nil {
+			config.BatchDiscount = discount
+		}
 	case "RechargeDiscount":
 		err = common.UpdateRechargeDiscountByJSONString(value)
 		config.RechargeDiscount = common.RechargeDiscount2JSONString()
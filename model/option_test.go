@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBatchDiscount(t *testing.T) {
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{"0.5", true},
+		{"1", true},
+		{"0.01", true},
+		{"0", false},
+		{"-0.5", false},
+		{"1.5", false},
+		{"", false},
+		{"half", false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.valid, ValidateBatchDiscount(tc.value) == nil, tc.value)
+	}
+}
@@ -0,0 +1,370 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// Organization 组织，成员的令牌可以使用组织的共享额度
+type Organization struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name" gorm:"type:varchar(64);index"`
+	OwnerId      int    `json:"owner_id" gorm:"index"`
+	Quota        int    `json:"quota" gorm:"type:int;default:0"`
+	UsedQuota    int    `json:"used_quota" gorm:"type:int;default:0"`
+	RequestCount int    `json:"request_count" gorm:"type:int;default:0"`
+	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
+
+	// 当前用户在组织中的角色，只在接口中返回
+	Role string `json:"role,omitempty" gorm:"-"`
+}
+
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"uniqueIndex:idx_organization_member"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_organization_member;index"`
+	Role           string `json:"role" gorm:"type:varchar(16);default:'member'"`
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+
+	Username    string `json:"username" gorm:"-"`
+	DisplayName string `json:"display_name" gorm:"-"`
+}
+
+func IsValidOrganizationRole(role string) bool {
+	return role == OrganizationRoleAdmin || role == OrganizationRoleMember
+}
+
+// CanManageOrganization 所有者和管理员可以管理成员和为组织充值
+func CanManageOrganization(role string) bool {
+	return role == OrganizationRoleOwner || role == OrganizationRoleAdmin
+}
+
+var allowedOrganizationOrderFields = map[string]bool{
+	"id":           true,
+	"name":         true,
+	"quota":        true,
+	"used_quota":   true,
+	"created_time": true,
+}
+
+type SearchOrganizationParams struct {
+	Name string `form:"name"`
+	PaginationParams
+}
+
+func GetOrganizationsList(params *SearchOrganizationParams) (*DataResult[Organization], error) {
+	var organizations []*Organization
+	db := DB.Model(&Organization{})
+
+	if params.Name != "" {
+		db = db.Where("name LIKE ?", params.Name+"%")
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &organizations, allowedOrganizationOrderFields)
+}
+
+// GetUserOrganizations 用户加入的所有组织
+func GetUserOrganizations(userId int) ([]*Organization, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("user_id = ?", userId).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	organizations := make([]*Organization, 0, len(members))
+	for _, member := range members {
+		organization, err := GetOrganizationById(member.OrganizationId)
+		if err != nil {
+			continue
+		}
+		organization.Role = member.Role
+		organizations = append(organizations, organization)
+	}
+
+	return organizations, nil
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	organization := &Organization{}
+	err := DB.First(organization, "id = ?", id).Error
+	return organization, err
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(name string, ownerId int) (*Organization, error) {
+	organization := &Organization{
+		Name:        name,
+		OwnerId:     ownerId,
+		CreatedTime: utils.GetTimestamp(),
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&OrganizationMember{
+			OrganizationId: organization.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleOwner,
+			CreatedTime:    utils.GetTimestamp(),
+		}).Error
+	})
+
+	return organization, err
+}
+
+func (organization *Organization) UpdateName() error {
+	return DB.Model(organization).Update("name", organization.Name).Error
+}
+
+// GetOrganizationMember 用户不是组织成员时返回错误
+func GetOrganizationMember(organizationId, userId int) (*OrganizationMember, error) {
+	member := &OrganizationMember{}
+	err := DB.Where("organization_id = ? AND user_id = ?", organizationId, userId).First(member).Error
+	if err != nil {
+		return nil, errors.New("不是该组织的成员")
+	}
+	return member, nil
+}
+
+func GetOrganizationMembers(organizationId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	err := DB.Where("organization_id = ?", organizationId).Order("id asc").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		user, err := GetUserById(member.UserId, false)
+		if err != nil {
+			continue
+		}
+		member.Username = user.Username
+		member.DisplayName = user.DisplayName
+	}
+
+	return members, nil
+}
+
+func AddOrganizationMember(organizationId int, username string, role string) (*OrganizationMember, error) {
+	user := &User{Username: username}
+	if err := user.FillUserByUsername(); err != nil || user.Id == 0 {
+		return nil, errors.New("用户不存在")
+	}
+
+	if _, err := GetOrganizationMember(organizationId, user.Id); err == nil {
+		return nil, errors.New("该用户已经是组织成员")
+	}
+
+	member := &OrganizationMember{
+		OrganizationId: organizationId,
+		UserId:         user.Id,
+		Role:           role,
+		CreatedTime:    utils.GetTimestamp(),
+	}
+	if err := DB.Create(member).Error; err != nil {
+		return nil, err
+	}
+	member.Username = user.Username
+	member.DisplayName = user.DisplayName
+
+	return member, nil
+}
+
+func (member *OrganizationMember) UpdateRole(role string) error {
+	member.Role = role
+	return DB.Model(member).Update("role", role).Error
+}
+
+// Delete 移除成员，同时禁用成员使用组织额度的令牌
+func (member *OrganizationMember) Delete() error {
+	var tokens []*Token
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(member).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ? AND organization_id = ?", member.UserId, member.OrganizationId).Find(&tokens).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&Token{}).Where("user_id = ? AND organization_id = ?", member.UserId, member.OrganizationId).Update("status", config.TokenStatusDisabled).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.RedisEnabled {
+		for _, token := range tokens {
+			redis.RedisDel(fmt.Sprintf("token:%s", token.Key))
+		}
+	}
+
+	return nil
+}
+
+func GetOrganizationQuota(id int) (quota int, err error) {
+	err = DB.Model(&Organization{}).Where("id = ?", id).Select("quota").Find(&quota).Error
+	return quota, err
+}
+
+func IncreaseOrganizationQuota(id int, quota int) (err error) {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, id, quota)
+		return nil
+	}
+	return increaseOrganizationQuota(id, quota)
+}
+
+func increaseOrganizationQuota(id int, quota int) (err error) {
+	return DB.Model(&Organization{}).Where("id = ?", id).Update("quota", gorm.Expr("quota + ?", quota)).Error
+}
+
+func DecreaseOrganizationQuota(id int, quota int) (err error) {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, id, -quota)
+		return nil
+	}
+	return increaseOrganizationQuota(id, -quota)
+}
+
+// UpdateOrganizationQuota 管理员直接设置组织额度
+func UpdateOrganizationQuota(id int, quota int) error {
+	err := DB.Model(&Organization{}).Where("id = ?", id).Update("quota", quota).Error
+	if err == nil {
+		CacheUpdateOrganizationQuota(id)
+	}
+	return err
+}
+
+func UpdateOrganizationUsedQuota(id int, quota int) {
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationUsedQuota, id, quota)
+		addNewRecord(BatchUpdateTypeOrganizationRequestCount, id, 1)
+		return
+	}
+	updateOrganizationUsedQuota(id, quota, 1)
+}
+
+func updateOrganizationUsedQuota(id int, quota int, count int) {
+	err := DB.Model(&Organization{}).Where("id = ?", id).Updates(
+		map[string]interface{}{
+			"used_quota":    gorm.Expr("used_quota + ?", quota),
+			"request_count": gorm.Expr("request_count + ?", count),
+		},
+	).Error
+	if err != nil {
+		logger.SysError("failed to update organization used quota: " + err.Error())
+	}
+}
+
+func CacheGetOrganizationQuota(id int) (quota int, err error) {
+	if !config.RedisEnabled {
+		return GetOrganizationQuota(id)
+	}
+	quotaString, err := redis.RedisGet(fmt.Sprintf("organization_quota:%d", id))
+	if err != nil {
+		quota, err = GetOrganizationQuota(id)
+		if err != nil {
+			return 0, err
+		}
+		err = redis.RedisSet(fmt.Sprintf("organization_quota:%d", id), fmt.Sprintf("%d", quota), time.Duration(TokenCacheSeconds)*time.Second)
+		if err != nil {
+			logger.SysError("Redis set organization quota error: " + err.Error())
+		}
+		return quota, err
+	}
+	return strconv.Atoi(quotaString)
+}
+
+func CacheUpdateOrganizationQuota(id int) error {
+	if !config.RedisEnabled {
+		return nil
+	}
+	quota, err := GetOrganizationQuota(id)
+	if err != nil {
+		return err
+	}
+	return redis.RedisSet(fmt.Sprintf("organization_quota:%d", id), fmt.Sprintf("%d", quota), time.Duration(TokenCacheSeconds)*time.Second)
+}
+
+func CacheDecreaseOrganizationQuota(id int, quota int) error {
+	if !config.RedisEnabled {
+		return nil
+	}
+	return redis.RedisDecrease(fmt.Sprintf("organization_quota:%d", id), int64(quota))
+}
+
+// TopUpOrganization 为组织充值并记录到操作者的日志中
+func TopUpOrganization(organizationId, userId, quota int, source string) error {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	// 充值不走批量更新，写入数据库后再刷新缓存，否则缓存会读到未写入的旧额度
+	err := increaseOrganizationQuota(organizationId, quota)
+	if err != nil {
+		return err
+	}
+	CacheUpdateOrganizationQuota(organizationId)
+	RecordLog(userId, LogTypeTopup, fmt.Sprintf("%s为组织 #%d 充值 %s", source, organizationId, common.LogQuota(quota)))
+	return nil
+}
+
+type OrganizationMemberStatistics struct {
+	LogStatistic
+	UserId   int    `gorm:"column:user_id" json:"user_id"`
+	Username string `gorm:"column:username" json:"username"`
+}
+
+// GetOrganizationMemberStatisticsByPeriod 按成员统计组织额度的使用情况
+func GetOrganizationMemberStatisticsByPeriod(organizationId int, startTime, endTime string) (statistics []*OrganizationMemberStatistics, err error) {
+	dateStr := "date"
+	if common.UsingPostgreSQL {
+		dateStr = "TO_CHAR(date, 'YYYY-MM-DD') as date"
+	} else if common.UsingSQLite {
+		dateStr = "strftime('%Y-%m-%d', date) as date"
+	}
+
+	err = DB.Raw(`
+		SELECT `+dateStr+`,
+		statistics.user_id,
+		MAX(users.username) as username,
+		sum(statistics.request_count) as request_count,
+		sum(statistics.quota) as quota,
+		sum(statistics.prompt_tokens) as prompt_tokens,
+		sum(statistics.completion_tokens) as completion_tokens,
+		sum(statistics.request_time) as request_time,
+		sum(statistics.error_count) as error_count
+		FROM statistics
+		LEFT JOIN users ON statistics.user_id = users.id
+		WHERE organization_id = ?
+		AND date BETWEEN ? AND ?
+		GROUP BY date, statistics.user_id
+		ORDER BY date, statistics.user_id
+	`, organizationId, startTime, endTime).Scan(&statistics).Error
+	return
+}
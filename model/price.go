@@ -21,6 +21,10 @@ type Price struct {
 	ChannelType int     `json:"channel_type" gorm:"default:0" binding:"gte=0"`
 	Input       float64 `json:"input" gorm:"default:0" binding:"gte=0"`
 	Output      float64 `json:"output" gorm:"default:0" binding:"gte=0"`
+	AudioInput  float64 `json:"audio_input" gorm:"default:0" binding:"gte=0"`
+	AudioOutput float64 `json:"audio_output" gorm:"default:0" binding:"gte=0"`
+	CachedInput float64 `json:"cached_input" gorm:"default:0" binding:"gte=0"`
+	CacheWrite  float64 `json:"cache_write" gorm:"default:0" binding:"gte=0"`
 }
 
 func GetAllPrices() ([]*Price, error) {
@@ -62,6 +66,42 @@ func (price *Price) GetOutput() float64 {
 	return price.Output
 }
 
+// GetAudioInput 音频输入价格，未设置时按文本价格计算
+func (price *Price) GetAudioInput() float64 {
+	if price.AudioInput <= 0 || price.Type == TimesPriceType {
+		return price.GetInput()
+	}
+
+	return price.AudioInput
+}
+
+// GetAudioOutput 音频输出价格，未设置时按文本价格计算
+func (price *Price) GetAudioOutput() float64 {
+	if price.AudioOutput <= 0 || price.Type == TimesPriceType {
+		return price.GetOutput()
+	}
+
+	return price.AudioOutput
+}
+
+// GetCachedInput 缓存命中的输入价格，未设置时按文本价格计算
+func (price *Price) GetCachedInput() float64 {
+	if price.CachedInput <= 0 || price.Type == TimesPriceType {
+		return price.GetInput()
+	}
+
+	return price.CachedInput
+}
+
+// GetCacheWrite 写入缓存的输入价格，未设置时按文本价格计算
+func (price *Price) GetCacheWrite() float64 {
+	if price.CacheWrite <= 0 || price.Type == TimesPriceType {
+		return price.GetInput()
+	}
+
+	return price.CacheWrite
+}
+
 func (price *Price) FetchInputCurrencyPrice(rate float64) string {
 	r := decimal.NewFromFloat(price.GetInput()).Mul(decimal.NewFromFloat(rate))
 	return r.String()
@@ -79,6 +119,10 @@ func UpdatePrices(tx *gorm.DB, models []string, prices *Price) error {
 			ChannelType: prices.ChannelType,
 			Input:       prices.Input,
 			Output:      prices.Output,
+			AudioInput:  prices.AudioInput,
+			AudioOutput: prices.AudioOutput,
+			CachedInput: prices.CachedInput,
+			CacheWrite:  prices.CacheWrite,
 		}).Error
 
 	return err
@@ -334,6 +378,41 @@ func GetDefaultPrice() []*Price {
 		})
 	}
 
+	// 文本 $5 / 1M tokens	$20 / 1M tokens
+	// 音频 $100 / 1M tokens	$200 / 1M tokens
+	var DefaultRealtimePrice = map[string][]float64{
+		"gpt-4o-realtime-preview":            {2.5, 10, 50, 100},
+		"gpt-4o-realtime-preview-2024-10-01": {2.5, 10, 50, 100},
+	}
+
+	for model, realtimePrice := range DefaultRealtimePrice {
+		prices = append(prices, &Price{
+			Model:       model,
+			Type:        TokensPriceType,
+			ChannelType: config.ChannelTypeOpenAI,
+			Input:       realtimePrice[0],
+			Output:      realtimePrice[1],
+			AudioInput:  realtimePrice[2],
+			AudioOutput: realtimePrice[3],
+		})
+	}
+
+	// 重排序按搜索次数计费 $2 / 1K searches
+	var DefaultRerankPrice = map[string]float64{
+		"rerank-english-v3.0":      1,
+		"rerank-multilingual-v3.0": 1,
+	}
+
+	for model, rerankPrice := range DefaultRerankPrice {
+		prices = append(prices, &Price{
+			Model:       model,
+			Type:        TimesPriceType,
+			ChannelType: config.ChannelTypeCohere,
+			Input:       rerankPrice,
+			Output:      rerankPrice,
+		})
+	}
+
 	var DefaultMJPrice = map[string]float64{
 		"mj_imagine":        50,
 		"mj_variation":      50,
@@ -52,6 +52,22 @@ func GetRedemptionById(id int) (*Redemption, error) {
 }
 
 func Redeem(key string, userId int) (quota int, err error) {
+	return redeem(key, userId, 0)
+}
+
+// RedeemToOrganization 兑换码的额度充值到组织
+func RedeemToOrganization(key string, userId int, organizationId int) (quota int, err error) {
+	if organizationId == 0 {
+		return 0, errors.New("无效的组织 id")
+	}
+	quota, err = redeem(key, userId, organizationId)
+	if err == nil {
+		CacheUpdateOrganizationQuota(organizationId)
+	}
+	return quota, err
+}
+
+func redeem(key string, userId int, organizationId int) (quota int, err error) {
 	if key == "" {
 		return 0, errors.New("未提供兑换码")
 	}
@@ -73,7 +89,11 @@ func Redeem(key string, userId int) (quota int, err error) {
 		if redemption.Status != config.RedemptionCodeStatusEnabled {
 			return errors.New("该兑换码已被使用")
 		}
-		err = tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
+		if organizationId > 0 {
+			err = tx.Model(&Organization{}).Where("id = ?", organizationId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
+		} else {
+			err = tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
+		}
 		if err != nil {
 			return err
 		}
@@ -85,7 +105,11 @@ func Redeem(key string, userId int) (quota int, err error) {
 	if err != nil {
 		return 0, errors.New("兑换失败，" + err.Error())
 	}
-	RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(redemption.Quota)))
+	if organizationId > 0 {
+		RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码为组织 #%d 充值 %s", organizationId, common.LogQuota(redemption.Quota)))
+	} else {
+		RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(redemption.Quota)))
+	}
 	return redemption.Quota, nil
 }
 
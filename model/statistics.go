@@ -11,11 +11,13 @@ type Statistics struct {
 	UserId           int       `json:"user_id" gorm:"primary_key"`
 	ChannelId        int       `json:"channel_id" gorm:"primary_key"`
 	ModelName        string    `json:"model_name" gorm:"primary_key;type:varchar(255)"`
+	OrganizationId   int       `json:"organization_id" gorm:"primary_key;default:0"`
 	RequestCount     int       `json:"request_count"`
 	Quota            int       `json:"quota"`
 	PromptTokens     int       `json:"prompt_tokens"`
 	CompletionTokens int       `json:"completion_tokens"`
 	RequestTime      int       `json:"request_time"`
+	ErrorCount       int       `json:"error_count"`
 }
 
 func GetUserModelStatisticsByPeriod(userId int, startTime, endTime string) (LogStatistic []*LogStatisticGroupModel, err error) {
@@ -33,7 +35,8 @@ func GetUserModelStatisticsByPeriod(userId int, startTime, endTime string) (LogS
 		sum(quota) as quota,
 		sum(prompt_tokens) as prompt_tokens,
 		sum(completion_tokens) as completion_tokens,
-		sum(request_time) as request_time
+		sum(request_time) as request_time,
+		sum(error_count) as error_count
 		FROM statistics
 		WHERE user_id= ?
 		AND date BETWEEN ? AND ?
@@ -57,6 +60,7 @@ func GetChannelExpensesStatisticsByPeriod(startTime, endTime string) (LogStatist
 		sum(prompt_tokens) as prompt_tokens,
 		sum(completion_tokens) as completion_tokens,
 		sum(request_time) as request_time,
+		sum(error_count) as error_count,
 		MAX(channels.name) AS channel
 		FROM statistics
 		JOIN channels ON statistics.channel_id = channels.id
@@ -78,22 +82,24 @@ const (
 
 func UpdateStatistics(updateType StatisticsUpdateType) error {
 	sql := `
-	%s statistics (date, user_id, channel_id, model_name, request_count, quota, prompt_tokens, completion_tokens, request_time)
+	%s statistics (date, user_id, channel_id, model_name, organization_id, request_count, quota, prompt_tokens, completion_tokens, request_time, error_count)
 	SELECT 
 		%s as date,
 		user_id,
 		channel_id,
 		model_name, 
-		count(1) as request_count,
+		organization_id,
+		sum(case when type = 2 then 1 else 0 end) as request_count,
 		sum(quota) as quota,
 		sum(prompt_tokens) as prompt_tokens,
 		sum(completion_tokens) as completion_tokens,
-		sum(request_time) as request_time
+		sum(request_time) as request_time,
+		sum(case when type = 5 then 1 else 0 end) as error_count
 	FROM logs
 	WHERE
-		type = 2
+		type IN (2, 5)
 		%s
-	GROUP BY date, channel_id, user_id, model_name
+	GROUP BY date, channel_id, user_id, model_name, organization_id
 	ORDER BY date, model_name
 	%s
 	`
@@ -109,12 +115,13 @@ func UpdateStatistics(updateType StatisticsUpdateType) error {
 	} else if common.UsingPostgreSQL {
 		sqlPrefix = "INSERT INTO"
 		sqlDate = "DATE_TRUNC('day', TO_TIMESTAMP(created_at))::DATE"
-		sqlSuffix = `ON CONFLICT (date, user_id, channel_id, model_name) DO UPDATE SET
+		sqlSuffix = `ON CONFLICT (date, user_id, channel_id, model_name, organization_id) DO UPDATE SET
 		request_count = EXCLUDED.request_count,
 		quota = EXCLUDED.quota,
 		prompt_tokens = EXCLUDED.prompt_tokens,
 		completion_tokens = EXCLUDED.completion_tokens,
-		request_time = EXCLUDED.request_time`
+		request_time = EXCLUDED.request_time,
+		error_count = EXCLUDED.error_count`
 	} else {
 		sqlPrefix = "INSERT INTO"
 		sqlDate = "DATE_FORMAT(FROM_UNIXTIME(created_at), '%Y-%m-%d')"
@@ -123,7 +130,8 @@ func UpdateStatistics(updateType StatisticsUpdateType) error {
 		quota = VALUES(quota),
 		prompt_tokens = VALUES(prompt_tokens),
 		completion_tokens = VALUES(completion_tokens),
-		request_time = VALUES(request_time)`
+		request_time = VALUES(request_time),
+		error_count = VALUES(error_count)`
 	}
 	now := time.Now()
 	todayTimestamp := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
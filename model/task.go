@@ -2,13 +2,15 @@ package model
 
 import (
 	"errors"
+	"one-api/common/notify"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 const (
-	TaskPlatformSuno = "suno"
+	TaskPlatformSuno  = "suno"
+	TaskPlatformBatch = "batch"
 )
 
 type TaskStatus string
@@ -21,6 +23,7 @@ const (
 	TaskStatusFailure               = "FAILURE"
 	TaskStatusSuccess               = "SUCCESS"
 	TaskStatusUnknown               = "UNKNOWN"
+	TaskStatusCancelling            = "CANCELLING"
 )
 
 type Task struct {
@@ -62,6 +65,12 @@ func GetTaskByTaskId(platform string, userId int, taskId string) (task *Task, er
 	return
 }
 
+func GetUserTasksByPlatform(platform string, userId int, limit int) (tasks []*Task, err error) {
+	err = DB.Where("platform = ? and user_id = ?", platform, userId).Order("id desc").Limit(limit).Find(&tasks).Error
+
+	return
+}
+
 func (Task *Task) Insert() error {
 	return DB.Create(Task).Error
 }
@@ -70,6 +79,19 @@ func (Task *Task) Update() error {
 	return DB.Save(Task).Error
 }
 
+// SendFailedEvent 推送任务失败事件，refundQuota 为退还给用户的额度
+func (task *Task) SendFailedEvent(refundQuota int) {
+	notify.SendEvent(notify.EventTaskFailed, &notify.TaskEventData{
+		Platform:    task.Platform,
+		TaskId:      task.TaskID,
+		Action:      task.Action,
+		UserId:      task.UserId,
+		ChannelId:   task.ChannelId,
+		FailReason:  task.FailReason,
+		RefundQuota: refundQuota,
+	})
+}
+
 func TaskBulkUpdate(TaskIds []string, params map[string]any) error {
 	if len(TaskIds) == 0 {
 		return nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/notify"
 	"one-api/common/redis"
 	"one-api/common/stmp"
 	"one-api/common/utils"
@@ -25,6 +26,18 @@ type Token struct {
 	UnlimitedQuota bool   `json:"unlimited_quota" gorm:"default:false"`
 	UsedQuota      int    `json:"used_quota" gorm:"default:0"` // used quota
 	ChatCache      bool   `json:"chat_cache" gorm:"default:false"`
+	// 语义缓存的相似度阈值，0 表示只使用精确缓存
+	ChatCacheThreshold float64 `json:"chat_cache_threshold" gorm:"default:0"`
+	// 记录请求和响应内容，用于账单和回答质量的排查
+	AuditLog bool `json:"audit_log" gorm:"default:false"`
+	// 允许使用的模型和访问的 IP 段，逗号分隔，为空表示不限制
+	Models   string `json:"models" gorm:"type:text"`
+	AllowIPs string `json:"allow_ips" gorm:"type:text"`
+	// 大于 0 时使用组织的共享额度
+	OrganizationId int `json:"organization_id" gorm:"index;default:0"`
+
+	TokenLimits
+	TokenBudget
 }
 
 var allowedTokenOrderFields = map[string]bool{
@@ -89,6 +102,9 @@ func ValidateUserToken(key string) (token *Token, err error) {
 		}
 		return nil, errors.New("该令牌额度已用尽")
 	}
+	if token.HasBudget() && token.GetBudgetRemain(utils.GetTimestamp()) <= 0 {
+		return nil, errors.New("该令牌本周期预算已用尽")
+	}
 	return token, nil
 }
 
@@ -137,7 +153,7 @@ func (token *Token) Update() error {
 		token.ChatCache = false
 	}
 
-	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache").Updates(token).Error
+	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache", "chat_cache_threshold", "audit_log", "rpm", "tpm", "max_concurrency", "daily_requests", "models", "allow_ips", "organization_id", "budget_period", "budget_quota", "budget_used", "budget_reset_time").Updates(token).Error
 	// 防止Redis缓存不生效，直接删除
 	if err == nil && config.RedisEnabled {
 		redis.RedisDel(fmt.Sprintf("token:%s", token.Key))
@@ -224,17 +240,36 @@ func PreConsumeTokenQuota(tokenId int, quota int) (err error) {
 	if !token.UnlimitedQuota && token.RemainQuota < quota {
 		return errors.New("令牌额度不足")
 	}
-	userQuota, err := GetUserQuota(token.UserId)
-	if err != nil {
-		return err
-	}
-	if userQuota < quota {
-		return errors.New("用户额度不足")
+	if token.HasBudget() {
+		if err = refreshTokenBudget(token); err != nil {
+			return err
+		}
+		if token.GetBudgetRemain(utils.GetTimestamp()) < quota {
+			return errors.New("令牌本周期预算不足")
+		}
 	}
-	quotaTooLow := userQuota >= config.QuotaRemindThreshold && userQuota-quota < config.QuotaRemindThreshold
-	noMoreQuota := userQuota-quota <= 0
-	if quotaTooLow || noMoreQuota {
-		go sendQuotaWarningEmail(token.UserId, userQuota, noMoreQuota)
+	if token.OrganizationId > 0 {
+		organizationQuota, err := GetOrganizationQuota(token.OrganizationId)
+		if err != nil {
+			return err
+		}
+		if organizationQuota < quota {
+			return errors.New("组织额度不足")
+		}
+	} else {
+		userQuota, err := GetUserQuota(token.UserId)
+		if err != nil {
+			return err
+		}
+		if userQuota < quota {
+			return errors.New("用户额度不足")
+		}
+		quotaTooLow := userQuota >= config.QuotaRemindThreshold && userQuota-quota < config.QuotaRemindThreshold
+		noMoreQuota := userQuota-quota <= 0
+		if quotaTooLow || noMoreQuota {
+			go sendQuotaWarningEmail(token.UserId, userQuota, noMoreQuota)
+			sendQuotaWarningEvent(token, userQuota-quota, noMoreQuota)
+		}
 	}
 	if !token.UnlimitedQuota {
 		err = DecreaseTokenQuota(tokenId, quota)
@@ -242,6 +277,15 @@ func PreConsumeTokenQuota(tokenId int, quota int) (err error) {
 			return err
 		}
 	}
+	if token.HasBudget() {
+		err = updateTokenBudgetUsed(tokenId, quota)
+		if err != nil {
+			return err
+		}
+	}
+	if token.OrganizationId > 0 {
+		return DecreaseOrganizationQuota(token.OrganizationId, quota)
+	}
 	err = DecreaseUserQuota(token.UserId, quota)
 	return err
 }
@@ -271,12 +315,33 @@ func sendQuotaWarningEmail(userId int, userQuota int, noMoreQuota bool) {
 	}
 }
 
+func sendQuotaWarningEvent(token *Token, remainQuota int, noMoreQuota bool) {
+	eventType := notify.EventBalanceLow
+	if noMoreQuota {
+		eventType = notify.EventQuotaExhausted
+	}
+
+	notify.SendEvent(eventType, &notify.QuotaEventData{
+		UserId:      token.UserId,
+		TokenId:     token.Id,
+		TokenName:   token.Name,
+		RemainQuota: remainQuota,
+		Threshold:   config.QuotaRemindThreshold,
+	})
+}
+
 func PostConsumeTokenQuota(tokenId int, quota int) (err error) {
 	token, err := GetTokenById(tokenId)
 	if err != nil {
 		return err
 	}
-	if quota > 0 {
+	if token.OrganizationId > 0 {
+		if quota > 0 {
+			err = DecreaseOrganizationQuota(token.OrganizationId, quota)
+		} else {
+			err = IncreaseOrganizationQuota(token.OrganizationId, -quota)
+		}
+	} else if quota > 0 {
 		err = DecreaseUserQuota(token.UserId, quota)
 	} else {
 		err = IncreaseUserQuota(token.UserId, -quota)
@@ -294,5 +359,17 @@ func PostConsumeTokenQuota(tokenId int, quota int) (err error) {
 			return err
 		}
 	}
+	if token.HasBudget() && quota != 0 {
+		if quota > 0 {
+			// 未预扣时由这里触发周期重置
+			if err = refreshTokenBudget(token); err != nil {
+				return err
+			}
+		}
+		err = updateTokenBudgetUsed(tokenId, quota)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
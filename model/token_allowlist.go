@@ -0,0 +1,104 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+func splitAllowlist(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' '
+	})
+
+	items := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			items = append(items, field)
+		}
+	}
+
+	return items
+}
+
+// GetAllowedModels 令牌允许使用的模型，为空表示不限制
+func (token *Token) GetAllowedModels() []string {
+	return splitAllowlist(token.Models)
+}
+
+// GetAllowedIPs 令牌允许访问的 IP 段，单个 IP 按 /32 或 /128 处理
+func (token *Token) GetAllowedIPs() ([]*net.IPNet, error) {
+	items := splitAllowlist(token.AllowIPs)
+	nets := make([]*net.IPNet, 0, len(items))
+
+	for _, item := range items {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址：%s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 IP 段：%s", item)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+func (token *Token) ValidateAllowlist() error {
+	_, err := token.GetAllowedIPs()
+	return err
+}
+
+// IsIPAllowed 未设置 IP 白名单时允许所有 IP
+func (token *Token) IsIPAllowed(ip string) bool {
+	nets, err := token.GetAllowedIPs()
+	if err != nil {
+		return false
+	}
+	if len(nets) == 0 {
+		return true
+	}
+
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+
+	for _, ipNet := range nets {
+		if ipNet.Contains(clientIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsModelAllowed 支持和渠道模型相同的 * 结尾通配，allowedModels 为空时允许所有模型
+func IsModelAllowed(allowedModels []string, modelName string) bool {
+	if len(allowedModels) == 0 {
+		return true
+	}
+
+	for _, allowed := range allowedModels {
+		if allowed == modelName || allowed == "*" {
+			return true
+		}
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
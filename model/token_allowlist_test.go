@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsModelAllowed(t *testing.T) {
+	cases := []struct {
+		name      string
+		models    string
+		modelName string
+		expected  bool
+	}{
+		{"empty allows all", "", "gpt-4o", true},
+		{"exact match", "gpt-4o,claude-3-opus", "claude-3-opus", true},
+		{"not in list", "gpt-4o", "gpt-4o-mini", false},
+		{"prefix wildcard", "gpt-4o*", "gpt-4o-mini", true},
+		{"prefix wildcard mismatch", "gpt-4o*", "gpt-3.5-turbo", false},
+		{"wildcard only", "*", "claude-3-opus", true},
+		{"newline and space separated", "gpt-4o\nclaude-* gemini-pro", "gemini-pro", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := &Token{Models: tc.models}
+			assert.Equal(t, tc.expected, IsModelAllowed(token.GetAllowedModels(), tc.modelName))
+		})
+	}
+}
+
+func TestIsIPAllowed(t *testing.T) {
+	cases := []struct {
+		name     string
+		allowIPs string
+		ip       string
+		expected bool
+	}{
+		{"empty allows all", "", "1.2.3.4", true},
+		{"single ipv4", "1.2.3.4", "1.2.3.4", true},
+		{"single ipv4 mismatch", "1.2.3.4", "1.2.3.5", false},
+		{"ipv4 cidr", "10.0.0.0/8", "10.1.2.3", true},
+		{"ipv4 cidr mismatch", "10.0.0.0/8", "11.0.0.1", false},
+		{"single ipv6", "2001:db8::1", "2001:db8::1", true},
+		{"ipv6 cidr", "2001:db8::/32", "2001:db8:1::1", true},
+		{"ipv6 cidr mismatch", "2001:db8::/32", "2001:db9::1", false},
+		{"multiple entries", "1.2.3.4, 192.168.0.0/16", "192.168.1.1", true},
+		{"invalid client ip", "1.2.3.4", "unknown", false},
+		{"invalid allowlist denies all", "1.2.3.4,not-an-ip", "1.2.3.4", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := &Token{AllowIPs: tc.allowIPs}
+			assert.Equal(t, tc.expected, token.IsIPAllowed(tc.ip))
+		})
+	}
+}
+
+func TestValidateAllowlist(t *testing.T) {
+	cases := []struct {
+		allowIPs string
+		err      string
+	}{
+		{"", ""},
+		{"1.2.3.4,10.0.0.0/8,2001:db8::/32", ""},
+		{"1.2.3", "无效的 IP 地址：1.2.3"},
+		{"10.0.0.0/33", "无效的 IP 段：10.0.0.0/33"},
+	}
+
+	for _, tc := range cases {
+		err := (&Token{AllowIPs: tc.allowIPs}).ValidateAllowlist()
+		if tc.err == "" {
+			assert.Nil(t, err, tc.allowIPs)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.allowIPs)
+		}
+	}
+}
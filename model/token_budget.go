@@ -0,0 +1,133 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TokenBudgetDaily   = "daily"
+	TokenBudgetWeekly  = "weekly"
+	TokenBudgetMonthly = "monthly"
+)
+
+var tokenBudgetPeriods = []string{TokenBudgetDaily, TokenBudgetWeekly, TokenBudgetMonthly}
+
+// TokenBudget 令牌的周期预算，每个周期开始时自动恢复
+type TokenBudget struct {
+	BudgetPeriod    string `json:"budget_period" gorm:"type:varchar(16);default:''"`
+	BudgetQuota     int    `json:"budget_quota" gorm:"default:0"`
+	BudgetUsed      int    `json:"budget_used" gorm:"default:0"`
+	BudgetResetTime int64  `json:"budget_reset_time" gorm:"bigint;default:0"`
+}
+
+func (b *TokenBudget) HasBudget() bool {
+	return b.BudgetPeriod != ""
+}
+
+func (b *TokenBudget) ValidateBudget() error {
+	if b.BudgetPeriod == "" {
+		return nil
+	}
+
+	valid := false
+	for _, period := range tokenBudgetPeriods {
+		if b.BudgetPeriod == period {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.New("无效的预算周期")
+	}
+
+	if b.BudgetQuota < 0 {
+		return errors.New("预算额度不能小于 0")
+	}
+
+	return nil
+}
+
+// GetBudgetRemain 当前周期剩余的预算，周期已结束但还未重置时按完整预算计算
+func (b *TokenBudget) GetBudgetRemain(now int64) int {
+	if b.BudgetResetTime <= now {
+		return b.BudgetQuota
+	}
+
+	return max(b.BudgetQuota-b.BudgetUsed, 0)
+}
+
+// GetNextBudgetResetTime 下一个周期的开始时间
+func GetNextBudgetResetTime(period string, now time.Time) int64 {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case TokenBudgetDaily:
+		return today.AddDate(0, 0, 1).Unix()
+	case TokenBudgetWeekly:
+		// 每周一开始
+		days := (7 - int(today.Weekday()) + int(time.Monday)) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days).Unix()
+	case TokenBudgetMonthly:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()).Unix()
+	}
+
+	return 0
+}
+
+// refreshTokenBudget 周期已结束时重置预算
+func refreshTokenBudget(token *Token) error {
+	now := time.Now()
+	if !token.HasBudget() || token.BudgetResetTime > now.Unix() {
+		return nil
+	}
+
+	nextResetTime := GetNextBudgetResetTime(token.BudgetPeriod, now)
+	// 带上原重置时间作为条件，避免并发请求重复重置
+	err := DB.Model(&Token{}).Where("id = ? AND budget_reset_time = ?", token.Id, token.BudgetResetTime).Updates(
+		map[string]interface{}{
+			"budget_used":       0,
+			"budget_reset_time": nextResetTime,
+		},
+	).Error
+	if err != nil {
+		return err
+	}
+
+	return DB.Select("budget_used", "budget_reset_time").First(token, "id = ?", token.Id).Error
+}
+
+// updateTokenBudgetUsed quota 为负数时返还预算，不会小于 0
+func updateTokenBudgetUsed(id int, quota int) error {
+	return DB.Model(&Token{}).Where("id = ?", id).Update(
+		"budget_used", gorm.Expr("CASE WHEN budget_used + ? < 0 THEN 0 ELSE budget_used + ? END", quota, quota),
+	).Error
+}
+
+// ResetTokenBudgets 重置所有周期已结束的令牌预算
+func ResetTokenBudgets() {
+	now := time.Now()
+
+	for _, period := range tokenBudgetPeriods {
+		result := DB.Model(&Token{}).Where("budget_period = ? AND budget_reset_time <= ?", period, now.Unix()).Updates(
+			map[string]interface{}{
+				"budget_used":       0,
+				"budget_reset_time": GetNextBudgetResetTime(period, now),
+			},
+		)
+		if result.Error != nil {
+			logger.SysError("failed to reset token budget: " + result.Error.Error())
+			continue
+		}
+		if result.RowsAffected > 0 {
+			logger.SysLog("reset " + period + " token budget")
+		}
+	}
+}
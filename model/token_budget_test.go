@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNextBudgetResetTime(t *testing.T) {
+	location := time.FixedZone("UTC+8", 8*3600)
+	date := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, location)
+	}
+
+	cases := []struct {
+		name     string
+		period   string
+		now      time.Time
+		expected time.Time
+	}{
+		{"daily", TokenBudgetDaily, date(2024, 3, 15, 10), date(2024, 3, 16, 0)},
+		{"daily at midnight", TokenBudgetDaily, date(2024, 3, 15, 0), date(2024, 3, 16, 0)},
+		{"daily end of year", TokenBudgetDaily, date(2024, 12, 31, 23), date(2025, 1, 1, 0)},
+		{"weekly on friday", TokenBudgetWeekly, date(2024, 3, 15, 10), date(2024, 3, 18, 0)},
+		{"weekly on sunday", TokenBudgetWeekly, date(2024, 3, 17, 23), date(2024, 3, 18, 0)},
+		{"weekly on monday", TokenBudgetWeekly, date(2024, 3, 18, 0), date(2024, 3, 25, 0)},
+		{"monthly", TokenBudgetMonthly, date(2024, 1, 31, 10), date(2024, 2, 1, 0)},
+		{"monthly on the first day", TokenBudgetMonthly, date(2024, 2, 1, 0), date(2024, 3, 1, 0)},
+		{"monthly end of year", TokenBudgetMonthly, date(2024, 12, 15, 10), date(2025, 1, 1, 0)},
+		{"unknown period", "yearly", date(2024, 3, 15, 10), time.Unix(0, 0)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected.Unix(), GetNextBudgetResetTime(tc.period, tc.now))
+		})
+	}
+}
+
+func TestGetBudgetRemain(t *testing.T) {
+	cases := []struct {
+		name     string
+		budget   TokenBudget
+		now      int64
+		expected int
+	}{
+		{"in period", TokenBudget{BudgetQuota: 100, BudgetUsed: 30, BudgetResetTime: 200}, 100, 70},
+		{"exhausted", TokenBudget{BudgetQuota: 100, BudgetUsed: 130, BudgetResetTime: 200}, 100, 0},
+		{"period ended", TokenBudget{BudgetQuota: 100, BudgetUsed: 100, BudgetResetTime: 200}, 200, 100},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.budget.GetBudgetRemain(tc.now))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"one-api/common/config"
+	"sync"
+	"time"
+)
+
+// TokenLimits 令牌的速率限制，0 表示不限制
+type TokenLimits struct {
+	RPM            int `json:"rpm" gorm:"default:0"`
+	TPM            int `json:"tpm" gorm:"default:0"`
+	MaxConcurrency int `json:"max_concurrency" gorm:"default:0"`
+	DailyRequests  int `json:"daily_requests" gorm:"default:0"`
+}
+
+func (l TokenLimits) IsEmpty() bool {
+	return l.RPM <= 0 && l.TPM <= 0 && l.MaxConcurrency <= 0 && l.DailyRequests <= 0
+}
+
+func (l TokenLimits) Validate() error {
+	if l.RPM < 0 || l.TPM < 0 || l.MaxConcurrency < 0 || l.DailyRequests < 0 {
+		return fmt.Errorf("令牌限制不能小于 0")
+	}
+	return nil
+}
+
+const (
+	TokenLimitRequests      = "requests"
+	TokenLimitTokens        = "tokens"
+	TokenLimitConcurrency   = "concurrency"
+	TokenLimitDailyRequests = "daily_requests"
+)
+
+// TokenLimitStatus 令牌当前的限制状态，用于返回 x-ratelimit-* 响应头
+type TokenLimitStatus struct {
+	RemainingRequests int
+	RemainingTokens   int
+	ResetRequests     time.Duration
+	ResetTokens       time.Duration
+	// 触发的限制，为空表示未超限
+	Exceeded   string
+	RetryAfter time.Duration
+}
+
+func (l TokenLimits) rateLimits() rateLimits {
+	return rateLimits{
+		concurrency: l.MaxConcurrency,
+		rpm:         l.RPM,
+		tpm:         l.TPM,
+		daily:       l.DailyRequests,
+	}
+}
+
+type tokenLimiter interface {
+	// reserve 检查限制并占用名额，检查和占用是原子的，超过限制时不占用
+	reserve(tokenId int, limits TokenLimits, now time.Time) (rateUsage, string)
+	release(tokenId int, limits TokenLimits, tokens int, now time.Time)
+}
+
+var tokenMemoryLimiter = &memoryTokenLimiter{
+	counters: make(map[int]*rateCounter),
+}
+
+var tokenRedisLimiter = &redisTokenLimiter{}
+
+func getTokenLimiter() tokenLimiter {
+	if config.RedisEnabled {
+		return tokenRedisLimiter
+	}
+
+	return tokenMemoryLimiter
+}
+
+// TokenRequestStart 检查令牌是否超过限制，未超过时占用名额，请求结束后需要调用 TokenRequestDone
+func TokenRequestStart(tokenId int, limits TokenLimits) *TokenLimitStatus {
+	now := time.Now()
+	usage, exceeded := getTokenLimiter().reserve(tokenId, limits, now)
+	status := &TokenLimitStatus{Exceeded: exceeded}
+
+	nextMinute := time.Duration(60-now.Unix()%60) * time.Second
+	if limits.RPM > 0 {
+		status.RemainingRequests = max(limits.RPM-int(math.Ceil(usage.requests)), 0)
+		status.ResetRequests = nextMinute
+		// 剩余请求数包含本次请求
+		if exceeded == "" && status.RemainingRequests > 0 {
+			status.RemainingRequests--
+		}
+	}
+	if limits.TPM > 0 {
+		status.RemainingTokens = max(limits.TPM-int(math.Ceil(usage.tokens)), 0)
+		status.ResetTokens = nextMinute
+	}
+
+	switch exceeded {
+	case TokenLimitConcurrency:
+		status.RetryAfter = time.Second
+	case TokenLimitDailyRequests:
+		status.RetryAfter = nextDay(now).Sub(now).Round(time.Second)
+	case TokenLimitRequests, TokenLimitTokens:
+		status.RetryAfter = nextMinute
+	}
+
+	return status
+}
+
+// TokenRequestDone 令牌的请求结束，tokens 为本次请求使用的 token 数
+func TokenRequestDone(tokenId int, limits TokenLimits, tokens int) {
+	getTokenLimiter().release(tokenId, limits, tokens, time.Now())
+}
+
+func nextDay(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+}
+
+type memoryTokenLimiter struct {
+	sync.Mutex
+	counters map[int]*rateCounter
+}
+
+func (l *memoryTokenLimiter) reserve(tokenId int, limits TokenLimits, now time.Time) (rateUsage, string) {
+	l.Lock()
+	defer l.Unlock()
+
+	return rateCounterOf(l.counters, tokenId).reserve(limits.rateLimits(), now)
+}
+
+func (l *memoryTokenLimiter) release(tokenId int, limits TokenLimits, tokens int, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	rateCounterOf(l.counters, tokenId).release(now, tokens)
+}
+
+type redisTokenLimiter struct{}
+
+func tokenLimitKey(tokenId int) string {
+	return fmt.Sprintf("token_limit:%d", tokenId)
+}
+
+func tokenDailyKey(tokenId int, now time.Time) string {
+	return tokenLimitKey(tokenId) + ":daily:" + now.Format("20060102")
+}
+
+func (l *redisTokenLimiter) reserve(tokenId int, limits TokenLimits, now time.Time) (rateUsage, string) {
+	return redisRateReserve(context.Background(), tokenLimitKey(tokenId), tokenDailyKey(tokenId, now), limits.rateLimits(), now)
+}
+
+func (l *redisTokenLimiter) release(tokenId int, limits TokenLimits, tokens int, now time.Time) {
+	ctx := context.Background()
+	key := tokenLimitKey(tokenId)
+
+	if limits.MaxConcurrency > 0 {
+		redisCounterRelease(ctx, key+":inflight", inflightExpiration)
+	}
+	if limits.TPM > 0 {
+		redisMinuteAdd(ctx, key+":tpm", now, int64(tokens))
+	}
+}
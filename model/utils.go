@@ -3,7 +3,9 @@ package model
 import (
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,12 +17,22 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeOrganizationQuota
+	BatchUpdateTypeOrganizationUsedQuota
+	BatchUpdateTypeOrganizationRequestCount
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
 var batchUpdateStores []map[int]int
 var batchUpdateLocks []sync.Mutex
 
+// 待更新的记录数，达到 BatchUpdateMaxRecords 时通知立即写入
+var batchUpdatePending atomic.Int64
+var batchUpdateSignal = make(chan struct{}, 1)
+
+// 批量更新停止后直接写入数据库
+var batchUpdateStopped atomic.Bool
+
 func init() {
 	for i := 0; i < BatchUpdateTypeCount; i++ {
 		batchUpdateStores = append(batchUpdateStores, make(map[int]int))
@@ -29,19 +41,47 @@ func init() {
 }
 
 func InitBatchUpdater() {
+	initLogWriter()
 	go func() {
 		for {
-			time.Sleep(time.Duration(config.BatchUpdateInterval) * time.Second)
+			select {
+			case <-time.After(time.Duration(config.BatchUpdateInterval) * time.Second):
+			case <-batchUpdateSignal:
+			}
 			batchUpdate()
 		}
 	}()
 }
 
+// StopBatchUpdater 退出前写入队列中的日志和未更新的额度
+func StopBatchUpdater() {
+	if !config.BatchUpdateEnabled {
+		return
+	}
+	stopLogWriter()
+	batchUpdateStopped.Store(true)
+	batchUpdate()
+}
+
 func addNewRecord(type_ int, id int, value int) {
 	batchUpdateLocks[type_].Lock()
+	// 最后一次写入之后才到达的记录不再进入队列，否则退出时会丢失
+	if batchUpdateStopped.Load() {
+		batchUpdateLocks[type_].Unlock()
+		updateRecord(type_, id, value)
+		return
+	}
 	defer batchUpdateLocks[type_].Unlock()
 	if _, ok := batchUpdateStores[type_][id]; !ok {
 		batchUpdateStores[type_][id] = value
+		pending := batchUpdatePending.Add(1)
+		metrics.SetBatchQueueDepth(metrics.BatchQueueQuota, int(pending))
+		if config.BatchUpdateMaxRecords > 0 && pending >= int64(config.BatchUpdateMaxRecords) {
+			select {
+			case batchUpdateSignal <- struct{}{}:
+			default:
+			}
+		}
 	} else {
 		batchUpdateStores[type_][id] += value
 	}
@@ -53,32 +93,48 @@ func batchUpdate() {
 		batchUpdateLocks[i].Lock()
 		store := batchUpdateStores[i]
 		batchUpdateStores[i] = make(map[int]int)
+		pending := batchUpdatePending.Add(-int64(len(store)))
 		batchUpdateLocks[i].Unlock()
+		metrics.SetBatchQueueDepth(metrics.BatchQueueQuota, int(pending))
+		metrics.RecordBatchFlushed(metrics.BatchQueueQuota, len(store))
 		// TODO: maybe we can combine updates with same key?
 		for key, value := range store {
-			switch i {
-			case BatchUpdateTypeUserQuota:
-				err := increaseUserQuota(key, value)
-				if err != nil {
-					logger.SysError("failed to batch update user quota: " + err.Error())
-				}
-			case BatchUpdateTypeTokenQuota:
-				err := increaseTokenQuota(key, value)
-				if err != nil {
-					logger.SysError("failed to batch update token quota: " + err.Error())
-				}
-			case BatchUpdateTypeUsedQuota:
-				updateUserUsedQuota(key, value)
-			case BatchUpdateTypeRequestCount:
-				updateUserRequestCount(key, value)
-			case BatchUpdateTypeChannelUsedQuota:
-				updateChannelUsedQuota(key, value)
-			}
+			updateRecord(i, key, value)
 		}
 	}
 	logger.SysLog("batch update finished")
 }
 
+func updateRecord(type_ int, id int, value int) {
+	switch type_ {
+	case BatchUpdateTypeUserQuota:
+		err := increaseUserQuota(id, value)
+		if err != nil {
+			logger.SysError("failed to batch update user quota: " + err.Error())
+		}
+	case BatchUpdateTypeTokenQuota:
+		err := increaseTokenQuota(id, value)
+		if err != nil {
+			logger.SysError("failed to batch update token quota: " + err.Error())
+		}
+	case BatchUpdateTypeUsedQuota:
+		updateUserUsedQuota(id, value)
+	case BatchUpdateTypeRequestCount:
+		updateUserRequestCount(id, value)
+	case BatchUpdateTypeChannelUsedQuota:
+		updateChannelUsedQuota(id, value)
+	case BatchUpdateTypeOrganizationQuota:
+		err := increaseOrganizationQuota(id, value)
+		if err != nil {
+			logger.SysError("failed to batch update organization quota: " + err.Error())
+		}
+	case BatchUpdateTypeOrganizationUsedQuota:
+		updateOrganizationUsedQuota(id, value, 0)
+	case BatchUpdateTypeOrganizationRequestCount:
+		updateOrganizationUsedQuota(id, 0, value)
+	}
+}
+
 func BatchInsert[T any](db *gorm.DB, data []T) error {
 	batchSize := 200
 	for i := 0; i < len(data); i += batchSize {
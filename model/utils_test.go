@@ -0,0 +1,58 @@
+package model
+
+import (
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 使用内存中的 SQLite 替换 DB，测试结束后恢复
+func setupTestDB(t *testing.T, models ...any) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Skip("sqlite is not available: " + err.Error())
+	}
+	assert.Nil(t, db.AutoMigrate(models...))
+
+	defaultDB, usingSQLite := DB, common.UsingSQLite
+	DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		DB, common.UsingSQLite = defaultDB, usingSQLite
+	})
+}
+
+func TestStopBatchUpdater(t *testing.T) {
+	setupTestDB(t, &Channel{})
+
+	defaultLogger, enabled := logger.Logger, config.BatchUpdateEnabled
+	logger.Logger, config.BatchUpdateEnabled = zap.NewNop(), true
+	t.Cleanup(func() {
+		logger.Logger, config.BatchUpdateEnabled = defaultLogger, enabled
+		batchUpdateStopped.Store(false)
+	})
+
+	channel := &Channel{Id: 1, Name: "test"}
+	assert.Nil(t, DB.Create(channel).Error)
+	usedQuota := func() int64 {
+		assert.Nil(t, DB.First(channel, channel.Id).Error)
+		return channel.UsedQuota
+	}
+
+	addNewRecord(BatchUpdateTypeChannelUsedQuota, channel.Id, 10)
+	assert.Equal(t, int64(0), usedQuota())
+
+	// 退出时写入队列中的记录
+	StopBatchUpdater()
+	assert.Equal(t, int64(10), usedQuota())
+
+	// 停止之后到达的记录直接写入
+	addNewRecord(BatchUpdateTypeChannelUsedQuota, channel.Id, 5)
+	assert.Equal(t, int64(15), usedQuota())
+	assert.Empty(t, batchUpdateStores[BatchUpdateTypeChannelUsedQuota])
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"one-api/common/logger"
+	"one-api/common/notify"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// WebhookDelivery webhook 事件的投递记录，重试多次只记录最终结果
+type WebhookDelivery struct {
+	Id         int    `json:"id"`
+	EventId    string `json:"event_id" gorm:"type:varchar(64);index"`
+	EventType  string `json:"event_type" gorm:"type:varchar(32);index"`
+	URL        string `json:"url" gorm:"type:varchar(255);default:''"`
+	StatusCode int    `json:"status_code"`
+	Attempts   int    `json:"attempts"`
+	Success    bool   `json:"success" gorm:"index"`
+	Error      string `json:"error" gorm:"type:text"`
+	Payload    string `json:"payload" gorm:"type:text"`
+	// 包含重试等待的总耗时，单位毫秒
+	Duration  int64 `json:"duration"`
+	CreatedAt int64 `json:"created_at" gorm:"bigint;index"`
+}
+
+func RecordWebhookDelivery(delivery *notify.Delivery) {
+	webhookDelivery := &WebhookDelivery{
+		EventId:    delivery.EventId,
+		EventType:  string(delivery.EventType),
+		URL:        delivery.URL,
+		StatusCode: delivery.StatusCode,
+		Attempts:   delivery.Attempts,
+		Success:    delivery.Success,
+		Error:      delivery.Error,
+		Payload:    delivery.Payload,
+		Duration:   delivery.Duration,
+		CreatedAt:  time.Now().Unix(),
+	}
+	err := DB.Create(webhookDelivery).Error
+	if err != nil {
+		logger.SysError("failed to record webhook delivery: " + err.Error())
+	}
+}
+
+type WebhookDeliveriesListParams struct {
+	PaginationParams
+	EventId        string `form:"event_id"`
+	EventType      string `form:"event_type"`
+	Success        *bool  `form:"success"`
+	StartTimestamp int64  `form:"start_timestamp"`
+	EndTimestamp   int64  `form:"end_timestamp"`
+}
+
+var allowedWebhookDeliveriesOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"event_type": true,
+}
+
+// GetWebhookDeliveriesList 列表中不返回请求体，查看详情时再单独获取
+func GetWebhookDeliveriesList(params *WebhookDeliveriesListParams) (*DataResult[WebhookDelivery], error) {
+	var deliveries []*WebhookDelivery
+
+	tx := DB.Omit("payload")
+	if params.EventId != "" {
+		tx = tx.Where("event_id = ?", params.EventId)
+	}
+	if params.EventType != "" {
+		tx = tx.Where("event_type = ?", params.EventType)
+	}
+	if params.Success != nil {
+		tx = tx.Where("success = ?", *params.Success)
+	}
+	if params.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", params.StartTimestamp)
+	}
+	if params.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", params.EndTimestamp)
+	}
+
+	return PaginateAndOrder(tx, &params.PaginationParams, &deliveries, allowedWebhookDeliveriesOrderFields)
+}
+
+func GetWebhookDeliveryById(id int) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := DB.First(&delivery, "id = ?", id).Error
+	return &delivery, err
+}
+
+// DeleteExpiredWebhookDeliveries 按保留天数清理投递记录，保留天数小于等于 0 时不清理
+func DeleteExpiredWebhookDeliveries() (int64, error) {
+	retentionDays := viper.GetInt("notify.webhook.delivery_retention_days")
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	targetTimestamp := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	result := DB.Where("created_at < ?", targetTimestamp).Delete(&WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	"one-api/common/telemetry"
 	"one-api/common/utils"
 	"one-api/model"
 	"one-api/types"
@@ -27,6 +28,7 @@ type ProviderConfig struct {
 	ImagesGenerations   string
 	ImagesEdit          string
 	ImagesVariations    string
+	Rerank              string
 	ModelList           string
 }
 
@@ -42,6 +44,7 @@ func (pc *ProviderConfig) SetAPIUri(customMapping map[string]interface{}) {
 		config.RelayModeImagesGenerations:  &pc.ImagesGenerations,
 		config.RelayModeImagesEdits:        &pc.ImagesEdit,
 		config.RelayModeImagesVariations:   &pc.ImagesVariations,
+		config.RelayModeRerank:             &pc.Rerank,
 	}
 
 	for key, value := range customMapping {
@@ -112,6 +115,10 @@ func (p *BaseProvider) SetUsage(usage *types.Usage) {
 
 func (p *BaseProvider) SetContext(c *gin.Context) {
 	p.Context = c
+	// 上游请求的 span 挂在当前请求的链路下
+	if c != nil && c.Request != nil && p.Requester != nil {
+		p.Requester.Context = telemetry.DetachContext(c.Request.Context())
+	}
 }
 
 func (p *BaseProvider) SetOriginalModel(ModelName string) {
@@ -170,6 +177,8 @@ func (p *BaseProvider) GetAPIUri(relayMode int) string {
 		return p.Config.ImagesEdit
 	case config.RelayModeImagesVariations:
 		return p.Config.ImagesVariations
+	case config.RelayModeRerank:
+		return p.Config.Rerank
 	default:
 		return ""
 	}
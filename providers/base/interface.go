@@ -7,6 +7,7 @@ import (
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 type Requestable interface {
@@ -58,6 +59,12 @@ type EmbeddingsInterface interface {
 	CreateEmbeddings(request *types.EmbeddingRequest) (*types.EmbeddingResponse, *types.OpenAIErrorWithStatusCode)
 }
 
+// 重排序接口
+type RerankInterface interface {
+	ProviderInterface
+	CreateRerank(request *types.RerankRequest) (*types.RerankResponse, *types.OpenAIErrorWithStatusCode)
+}
+
 // 审查接口
 type ModerationInterface interface {
 	ProviderInterface
@@ -99,6 +106,12 @@ type ImageVariationsInterface interface {
 	CreateImageVariations(request *types.ImageEditRequest) (*types.ImageResponse, *types.OpenAIErrorWithStatusCode)
 }
 
+// 实时语音接口
+type RealtimeInterface interface {
+	ProviderInterface
+	CreateRealtimeConn(modelName string) (*websocket.Conn, *types.OpenAIErrorWithStatusCode)
+}
+
 // type RelayInterface interface {
 // 	ProviderInterface
 // 	CreateRelay() (*http.Response, *types.OpenAIErrorWithStatusCode)
@@ -0,0 +1,489 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+// ChatAdapter 让任意实现了 base.ChatInterface 的渠道都可以处理 Claude 格式的请求
+type ChatAdapter struct {
+	base.ChatInterface
+}
+
+func NewChatAdapter(provider base.ChatInterface) *ChatAdapter {
+	return &ChatAdapter{ChatInterface: provider}
+}
+
+func (a *ChatAdapter) CreateClaudeChat(request *ClaudeRequest) (*ClaudeResponse, *ClaudeErrorWithStatusCode) {
+	chatRequest, err := ConvertToChatRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "conversion_error", http.StatusBadRequest, true)
+	}
+
+	response, errWithCode := a.CreateChatCompletion(chatRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToClaudeErr(errWithCode)
+	}
+
+	return ConvertFromChatResponse(response, a.GetUsage(), request.Model), nil
+}
+
+func (a *ChatAdapter) CreateClaudeChatStream(request *ClaudeRequest) (requester.StreamReaderInterface[string], *ClaudeErrorWithStatusCode) {
+	chatRequest, err := ConvertToChatRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "conversion_error", http.StatusBadRequest, true)
+	}
+
+	stream, errWithCode := a.CreateChatCompletionStream(chatRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToClaudeErr(errWithCode)
+	}
+
+	return &chatAdapterStream{
+		StreamReaderInterface: stream,
+		converter: &chatStreamConverter{
+			usage:     a.GetUsage(),
+			modelName: request.Model,
+		},
+	}, nil
+}
+
+// ConvertToChatRequest 将 Claude 请求转换为 OpenAI 聊天请求
+func ConvertToChatRequest(request *ClaudeRequest) (*types.ChatCompletionRequest, error) {
+	chatRequest := &types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(request.Messages)+1),
+		MaxTokens:   request.MaxTokens,
+		Stop:        request.StopSequences,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stream:      request.Stream,
+	}
+
+	if request.System != "" {
+		chatRequest.Messages = append(chatRequest.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: request.System,
+		})
+	}
+
+	for _, message := range request.Messages {
+		messages, err := convertToChatMessages(&message)
+		if err != nil {
+			return nil, err
+		}
+		chatRequest.Messages = append(chatRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		chatRequest.Tools = append(chatRequest.Tools, &types.ChatCompletionTool{
+			Type: "function",
+			Function: types.ChatCompletionFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if request.ToolChoice != nil {
+		switch request.ToolChoice.Type {
+		case "any":
+			chatRequest.ToolChoice = types.ToolChoiceTypeRequired
+		case "none":
+			chatRequest.ToolChoice = types.ToolChoiceTypeNone
+		case "tool":
+			chatRequest.ToolChoice = map[string]any{
+				"type": types.ToolChoiceTypeFunction,
+				"function": map[string]any{
+					"name": request.ToolChoice.Name,
+				},
+			}
+		default:
+			chatRequest.ToolChoice = types.ToolChoiceTypeAuto
+		}
+	}
+
+	return chatRequest, nil
+}
+
+func convertToChatMessages(message *Message) ([]types.ChatCompletionMessage, error) {
+	if content, ok := message.Content.(string); ok {
+		return []types.ChatCompletionMessage{{
+			Role:    message.Role,
+			Content: content,
+		}}, nil
+	}
+
+	var contents []MessageContent
+	body, err := json.Marshal(message.Content)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &contents); err != nil {
+		return nil, errors.New("invalid message content")
+	}
+
+	// tool_result 需要拆分为单独的 tool 消息，并放在同一条消息的其他内容之前
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]any, 0, len(contents))
+	var toolCalls []*types.ChatCompletionToolCalls
+
+	for _, content := range contents {
+		switch content.Type {
+		case ContentTypeText:
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeText,
+				"text": content.Text,
+			})
+		case ContentTypeImage:
+			if content.Source == nil {
+				continue
+			}
+			url := content.Source.Data
+			if content.Source.Type == "base64" {
+				url = fmt.Sprintf("data:%s;base64,%s", content.Source.MediaType, content.Source.Data)
+			}
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeImageURL,
+				"image_url": map[string]any{
+					"url": url,
+				},
+			})
+		case ContentTypeToolUes:
+			arguments, err := json.Marshal(content.Input)
+			if err != nil {
+				return nil, err
+			}
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:    content.Id,
+				Type:  "function",
+				Index: len(toolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      content.Name,
+					Arguments: string(arguments),
+				},
+			})
+		case ContentTypeToolResult:
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    toolResultText(content.Content),
+				ToolCallID: content.ToolUseId,
+			})
+		}
+	}
+
+	if len(parts) == 0 && toolCalls == nil {
+		return messages, nil
+	}
+
+	chatMessage := types.ChatCompletionMessage{
+		Role:      message.Role,
+		ToolCalls: toolCalls,
+	}
+	if len(parts) > 0 {
+		chatMessage.Content = parts
+	}
+
+	return append(messages, chatMessage), nil
+}
+
+func toolResultText(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		var text strings.Builder
+		for _, item := range v {
+			block, ok := item.(map[string]any)
+			if !ok || block["type"] != ContentTypeText {
+				continue
+			}
+			if s, ok := block["text"].(string); ok {
+				text.WriteString(s)
+			}
+		}
+		return text.String()
+	case nil:
+		return ""
+	default:
+		return utils.Marshal(v)
+	}
+}
+
+// ConvertFromChatResponse 将 OpenAI 聊天响应转换为 Claude 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, usage *types.Usage, modelName string) *ClaudeResponse {
+	claudeResponse := &ClaudeResponse{
+		Id:         fmt.Sprintf("msg_%s", utils.GetUUID()),
+		Type:       "message",
+		Role:       types.ChatMessageRoleAssistant,
+		Content:    make([]ResContent, 0),
+		Model:      modelName,
+		StopReason: FinishReasonEndTurn,
+	}
+
+	if usage != nil {
+		claudeResponse.Usage = OpenaiUsageToClaudeUsage(usage)
+	}
+
+	if len(response.Choices) == 0 {
+		return claudeResponse
+	}
+
+	choice := response.Choices[0]
+	if text := choice.Message.StringContent(); text != "" {
+		claudeResponse.Content = append(claudeResponse.Content, ResContent{
+			Type: ContentTypeText,
+			Text: text,
+		})
+	}
+
+	for _, toolCall := range choice.Message.ToolCalls {
+		if toolCall.Function == nil {
+			continue
+		}
+		claudeResponse.Content = append(claudeResponse.Content, ResContent{
+			Type:  ContentTypeToolUes,
+			Id:    toolCall.Id,
+			Name:  toolCall.Function.Name,
+			Input: parseToolInput(toolCall.Function.Arguments),
+		})
+	}
+
+	claudeResponse.StopReason = stopReasonOpenAI2Claude(choice.FinishReason)
+	if len(choice.Message.ToolCalls) > 0 {
+		claudeResponse.StopReason = FinishReasonToolUse
+	}
+
+	return claudeResponse
+}
+
+func parseToolInput(arguments string) any {
+	input := make(map[string]any)
+	if arguments != "" {
+		_ = json.Unmarshal([]byte(arguments), &input)
+	}
+	return input
+}
+
+type chatAdapterStream struct {
+	requester.StreamReaderInterface[string]
+	converter *chatStreamConverter
+}
+
+func (s *chatAdapterStream) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.StreamReaderInterface.Recv()
+	wrappedDataChan := make(chan string)
+	wrappedErrChan := make(chan error)
+
+	// 数据和错误在同一个协程中转发，保证结束事件在最后输出
+	go func() {
+		for {
+			select {
+			case data := <-dataChan:
+				if events := s.converter.convert(data); events != "" {
+					wrappedDataChan <- events
+				}
+			case err := <-errChan:
+				if errors.Is(err, io.EOF) {
+					wrappedDataChan <- s.converter.finish()
+				} else {
+					wrappedDataChan <- s.converter.fail(err)
+				}
+				wrappedErrChan <- io.EOF
+				return
+			}
+		}
+	}()
+
+	return wrappedDataChan, wrappedErrChan
+}
+
+// chatStreamConverter 将 OpenAI 流式响应转换为 Claude 的事件流
+type chatStreamConverter struct {
+	usage        *types.Usage
+	modelName    string
+	started      bool
+	blockIndex   int
+	blockType    string
+	toolCallId   string
+	finishReason any
+}
+
+func (h *chatStreamConverter) convert(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	var events strings.Builder
+	h.start(&events)
+
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			if h.blockType != ContentTypeText {
+				h.startBlock(&events, ContentTypeText, map[string]any{
+					"type": ContentTypeText,
+					"text": "",
+				})
+			}
+			events.WriteString(h.event("content_block_delta", map[string]any{
+				"index": h.blockIndex,
+				"delta": map[string]any{
+					"type": "text_delta",
+					"text": choice.Delta.Content,
+				},
+			}))
+		}
+
+		for _, toolCall := range choice.Delta.ToolCalls {
+			h.convertToolCall(&events, toolCall)
+		}
+
+		if choice.FinishReason != nil && choice.FinishReason != "" {
+			h.finishReason = choice.FinishReason
+		}
+	}
+
+	return events.String()
+}
+
+func (h *chatStreamConverter) convertToolCall(events *strings.Builder, toolCall *types.ChatCompletionToolCalls) {
+	if toolCall.Function == nil {
+		return
+	}
+
+	// 部分渠道的 index 始终为 0，所以以新的 id 或函数名作为新调用的开始
+	isNew := h.blockType != ContentTypeToolUes ||
+		(toolCall.Id != "" && toolCall.Id != h.toolCallId) ||
+		(toolCall.Id == "" && toolCall.Function.Name != "")
+	if isNew {
+		h.toolCallId = toolCall.Id
+		if h.toolCallId == "" {
+			h.toolCallId = fmt.Sprintf("toolu_%s", utils.GetUUID())
+		}
+		h.startBlock(events, ContentTypeToolUes, map[string]any{
+			"type":  ContentTypeToolUes,
+			"id":    h.toolCallId,
+			"name":  toolCall.Function.Name,
+			"input": map[string]any{},
+		})
+	}
+
+	if toolCall.Function.Arguments == "" {
+		return
+	}
+
+	events.WriteString(h.event("content_block_delta", map[string]any{
+		"index": h.blockIndex,
+		"delta": map[string]any{
+			"type":         "input_json_delta",
+			"partial_json": toolCall.Function.Arguments,
+		},
+	}))
+}
+
+func (h *chatStreamConverter) start(events *strings.Builder) {
+	if h.started {
+		return
+	}
+	h.started = true
+	h.blockIndex = -1
+
+	events.WriteString(h.event("message_start", map[string]any{
+		"message": map[string]any{
+			"id":            fmt.Sprintf("msg_%s", utils.GetUUID()),
+			"type":          "message",
+			"role":          types.ChatMessageRoleAssistant,
+			"content":       []any{},
+			"model":         h.modelName,
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage": map[string]any{
+				"input_tokens":  h.usage.PromptTokens,
+				"output_tokens": 0,
+			},
+		},
+	}))
+}
+
+func (h *chatStreamConverter) startBlock(events *strings.Builder, blockType string, contentBlock map[string]any) {
+	h.stopBlock(events)
+	h.blockIndex++
+	h.blockType = blockType
+
+	events.WriteString(h.event("content_block_start", map[string]any{
+		"index":         h.blockIndex,
+		"content_block": contentBlock,
+	}))
+}
+
+func (h *chatStreamConverter) stopBlock(events *strings.Builder) {
+	if h.blockType == "" {
+		return
+	}
+	h.blockType = ""
+
+	events.WriteString(h.event("content_block_stop", map[string]any{
+		"index": h.blockIndex,
+	}))
+}
+
+func (h *chatStreamConverter) finish() string {
+	var events strings.Builder
+	h.start(&events)
+	h.stopBlock(&events)
+
+	stopReason := stopReasonOpenAI2Claude(h.finishReason)
+	events.WriteString(h.event("message_delta", map[string]any{
+		"delta": map[string]any{
+			"stop_reason":   stopReason,
+			"stop_sequence": nil,
+		},
+		"usage": map[string]any{
+			"output_tokens": h.usage.CompletionTokens,
+		},
+	}))
+	events.WriteString(h.event("message_stop", map[string]any{}))
+
+	return events.String()
+}
+
+func (h *chatStreamConverter) fail(err error) string {
+	claudeError := &ClaudeError{
+		Type: "error",
+		ErrorInfo: ClaudeErrorInfo{
+			Type:    "api_error",
+			Message: err.Error(),
+		},
+	}
+
+	var aiError *types.OpenAIError
+	if errors.As(err, &aiError) {
+		claudeError.ErrorInfo.Message = aiError.Message
+		if aiError.Type != "" {
+			claudeError.ErrorInfo.Type = aiError.Type
+		}
+	}
+
+	body, _ := json.Marshal(claudeError)
+	return fmt.Sprintf("event: error\ndata: %s\n\n", body)
+}
+
+func (h *chatStreamConverter) event(eventType string, data map[string]any) string {
+	data["type"] = eventType
+	body, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, body)
+}
@@ -0,0 +1,318 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToChatRequest(t *testing.T) {
+	cases := []struct {
+		name       string
+		request    string
+		messages   []types.ChatCompletionMessage
+		toolChoice any
+		err        string
+	}{
+		{
+			name:    "system and text",
+			request: `{"model":"claude","system":"be brief","messages":[{"role":"user","content":"hi"}]}`,
+			messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleSystem, Content: "be brief"},
+				{Role: types.ChatMessageRoleUser, Content: "hi"},
+			},
+		},
+		{
+			name: "text and images",
+			request: `{"model":"claude","messages":[{"role":"user","content":[
+				{"type":"text","text":"what is this"},
+				{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},
+				{"type":"image","source":{"type":"url","data":"https://example.com/a.png"}},
+				{"type":"image"}
+			]}]}`,
+			messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleUser, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "what is this"},
+					map[string]any{"type": types.ContentTypeImageURL, "image_url": map[string]any{"url": "data:image/png;base64,AAAA"}},
+					map[string]any{"type": types.ContentTypeImageURL, "image_url": map[string]any{"url": "https://example.com/a.png"}},
+				}},
+			},
+		},
+		{
+			name: "tool use and tool result",
+			request: `{"model":"claude","messages":[
+				{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"a"}}]},
+				{"role":"user","content":[
+					{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"sun"},{"type":"image"},{"type":"text","text":"ny"}]},
+					{"type":"tool_result","tool_use_id":"toolu_2","content":{"temp":20}},
+					{"type":"text","text":"thanks"}
+				]}
+			],"tool_choice":{"type":"tool","name":"get_weather"}}`,
+			messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleAssistant, ToolCalls: []*types.ChatCompletionToolCalls{
+					{Id: "toolu_1", Type: "function", Index: 0, Function: &types.ChatCompletionToolCallsFunction{Name: "get_weather", Arguments: `{"city":"a"}`}},
+				}},
+				{Role: types.ChatMessageRoleTool, Content: "sunny", ToolCallID: "toolu_1"},
+				{Role: types.ChatMessageRoleTool, Content: `{"temp":20}`, ToolCallID: "toolu_2"},
+				{Role: types.ChatMessageRoleUser, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "thanks"},
+				}},
+			},
+			toolChoice: map[string]any{
+				"type":     types.ToolChoiceTypeFunction,
+				"function": map[string]any{"name": "get_weather"},
+			},
+		},
+		{
+			name:       "tool choice any",
+			request:    `{"model":"claude","messages":[{"role":"user","content":"hi"}],"tool_choice":{"type":"any"}}`,
+			messages:   []types.ChatCompletionMessage{{Role: types.ChatMessageRoleUser, Content: "hi"}},
+			toolChoice: types.ToolChoiceTypeRequired,
+		},
+		{
+			name:       "tool choice auto",
+			request:    `{"model":"claude","messages":[{"role":"user","content":"hi"}],"tool_choice":{"type":"auto"}}`,
+			messages:   []types.ChatCompletionMessage{{Role: types.ChatMessageRoleUser, Content: "hi"}},
+			toolChoice: types.ToolChoiceTypeAuto,
+		},
+		{
+			name:    "invalid content",
+			request: `{"model":"claude","messages":[{"role":"user","content":123}]}`,
+			err:     "invalid message content",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := &ClaudeRequest{}
+			assert.Nil(t, json.Unmarshal([]byte(tc.request), request))
+
+			chatRequest, err := ConvertToChatRequest(request)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "claude", chatRequest.Model)
+			assert.Equal(t, tc.messages, chatRequest.Messages)
+			assert.Equal(t, tc.toolChoice, chatRequest.ToolChoice)
+		})
+	}
+}
+
+func TestConvertFromChatResponse(t *testing.T) {
+	cases := []struct {
+		name       string
+		response   string
+		content    []ResContent
+		stopReason string
+	}{
+		{
+			name:       "text",
+			response:   `{"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`,
+			content:    []ResContent{{Type: ContentTypeText, Text: "hello"}},
+			stopReason: FinishReasonEndTurn,
+		},
+		{
+			name:       "max tokens",
+			response:   `{"choices":[{"index":0,"message":{"role":"assistant","content":"hel"},"finish_reason":"length"}]}`,
+			content:    []ResContent{{Type: ContentTypeText, Text: "hel"}},
+			stopReason: "max_tokens",
+		},
+		{
+			name: "tool calls",
+			response: `{"choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[
+				{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"a\"}"}},
+				{"id":"call_2","type":"function","function":{"name":"noop","arguments":""}}
+			]},"finish_reason":"stop"}]}`,
+			content: []ResContent{
+				{Type: ContentTypeToolUes, Id: "call_1", Name: "get_weather", Input: map[string]any{"city": "a"}},
+				{Type: ContentTypeToolUes, Id: "call_2", Name: "noop", Input: map[string]any{}},
+			},
+			stopReason: FinishReasonToolUse,
+		},
+		{
+			name:       "no choices",
+			response:   `{"choices":[]}`,
+			content:    []ResContent{},
+			stopReason: FinishReasonEndTurn,
+		},
+	}
+
+	usage := &types.Usage{
+		PromptTokens:     100,
+		CompletionTokens: 20,
+		PromptTokensDetails: &types.PromptTokensDetails{
+			CachedTokens: 30,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := &types.ChatCompletionResponse{}
+			assert.Nil(t, json.Unmarshal([]byte(tc.response), response))
+
+			claudeResponse := ConvertFromChatResponse(response, usage, "claude")
+			assert.Equal(t, "claude", claudeResponse.Model)
+			assert.Equal(t, tc.content, claudeResponse.Content)
+			assert.Equal(t, tc.stopReason, claudeResponse.StopReason)
+			assert.Equal(t, Usage{InputTokens: 70, OutputTokens: 20, CacheReadInputTokens: 30}, claudeResponse.Usage)
+		})
+	}
+}
+
+type claudeStreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	ContentBlock map[string]any `json:"content_block"`
+	Delta        map[string]any `json:"delta"`
+}
+
+func parseClaudeEvents(t *testing.T, raw string) []*claudeStreamEvent {
+	var events []*claudeStreamEvent
+	for _, block := range strings.Split(raw, "\n\n") {
+		if block == "" {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+		assert.Len(t, lines, 2)
+
+		event := &claudeStreamEvent{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), event))
+		assert.Equal(t, strings.TrimPrefix(lines[0], "event: "), event.Type)
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestChatStreamConverter(t *testing.T) {
+	cases := []struct {
+		name       string
+		chunks     []string
+		events     []string
+		stopReason string
+	}{
+		{
+			name: "text",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+				`{"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			},
+			events: []string{
+				"message_start",
+				"content_block_start:0:text",
+				"content_block_delta:0:text_delta",
+				"content_block_delta:0:text_delta",
+				"content_block_stop:0",
+				"message_delta",
+				"message_stop",
+			},
+			stopReason: FinishReasonEndTurn,
+		},
+		{
+			name: "text then tool calls with the same index",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"content":"ok"}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"a","arguments":""}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_2","function":{"name":"b","arguments":"{}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			},
+			events: []string{
+				"message_start",
+				"content_block_start:0:text",
+				"content_block_delta:0:text_delta",
+				"content_block_stop:0",
+				"content_block_start:1:tool_use",
+				"content_block_delta:1:input_json_delta",
+				"content_block_stop:1",
+				"content_block_start:2:tool_use",
+				"content_block_delta:2:input_json_delta",
+				"content_block_stop:2",
+				"message_delta",
+				"message_stop",
+			},
+			stopReason: FinishReasonToolUse,
+		},
+		{
+			name:   "empty stream",
+			chunks: []string{`not json`},
+			events: []string{
+				"message_start",
+				"message_delta",
+				"message_stop",
+			},
+			stopReason: FinishReasonEndTurn,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			converter := &chatStreamConverter{
+				usage:     &types.Usage{PromptTokens: 10, CompletionTokens: 5},
+				modelName: "claude",
+			}
+
+			var raw strings.Builder
+			for _, chunk := range tc.chunks {
+				raw.WriteString(converter.convert(chunk))
+			}
+			raw.WriteString(converter.finish())
+
+			events := parseClaudeEvents(t, raw.String())
+			names := make([]string, 0, len(events))
+			for _, event := range events {
+				name := event.Type
+				switch event.Type {
+				case "content_block_start":
+					name = strings.Join([]string{name, strconv.Itoa(event.Index), event.ContentBlock["type"].(string)}, ":")
+				case "content_block_delta":
+					name = strings.Join([]string{name, strconv.Itoa(event.Index), event.Delta["type"].(string)}, ":")
+				case "content_block_stop":
+					name = strings.Join([]string{name, strconv.Itoa(event.Index)}, ":")
+				}
+				names = append(names, name)
+			}
+			assert.Equal(t, tc.events, names)
+
+			messageDelta := events[len(events)-2]
+			assert.Equal(t, tc.stopReason, messageDelta.Delta["stop_reason"])
+		})
+	}
+}
+
+func TestChatStreamConverterFail(t *testing.T) {
+	converter := &chatStreamConverter{usage: &types.Usage{}}
+
+	cases := []struct {
+		err      error
+		expected ClaudeErrorInfo
+	}{
+		{
+			err:      errors.New("connection reset"),
+			expected: ClaudeErrorInfo{Type: "api_error", Message: "connection reset"},
+		},
+		{
+			err:      &types.OpenAIError{Message: "overloaded", Type: "overloaded_error"},
+			expected: ClaudeErrorInfo{Type: "overloaded_error", Message: "overloaded"},
+		},
+	}
+
+	for _, tc := range cases {
+		raw := converter.fail(tc.err)
+		assert.True(t, strings.HasPrefix(raw, "event: error\ndata: "))
+
+		claudeError := &ClaudeError{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(raw, "event: error\ndata: "))), claudeError))
+		assert.Equal(t, tc.expected, claudeError.ErrorInfo)
+	}
+}
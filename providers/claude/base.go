@@ -99,6 +99,17 @@ func stopReasonClaude2OpenAI(reason string) string {
 	}
 }
 
+func stopReasonOpenAI2Claude(reason any) string {
+	switch reason {
+	case types.FinishReasonLength:
+		return "max_tokens"
+	case types.FinishReasonToolCalls, types.FinishReasonFunctionCall:
+		return FinishReasonToolUse
+	default:
+		return FinishReasonEndTurn
+	}
+}
+
 func convertRole(role string) string {
 	switch role {
 	case types.ChatMessageRoleUser, types.ChatMessageRoleTool, types.ChatMessageRoleFunction:
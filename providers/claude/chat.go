@@ -305,13 +305,7 @@ func ConvertToChatOpenai(provider base.ProviderInterface, response *ClaudeRespon
 		},
 	}
 
-	completionTokens := response.Usage.OutputTokens
-
-	promptTokens := response.Usage.InputTokens
-
-	openaiResponse.Usage.PromptTokens = promptTokens
-	openaiResponse.Usage.CompletionTokens = completionTokens
-	openaiResponse.Usage.TotalTokens = promptTokens + completionTokens
+	ClaudeUsageToOpenaiUsage(&response.Usage, openaiResponse.Usage)
 
 	usage := provider.GetUsage()
 	*usage = *openaiResponse.Usage
@@ -354,7 +348,8 @@ func (h *ClaudeStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan strin
 	switch claudeResponse.Type {
 	case "message_start":
 		h.convertToOpenaiStream(&claudeResponse, dataChan)
-		h.Usage.PromptTokens = claudeResponse.Message.Usage.InputTokens
+		h.Usage.PromptTokens = claudeResponse.Message.Usage.GetPromptTokens()
+		h.Usage.SetPromptCacheTokens(claudeResponse.Message.Usage.CacheReadInputTokens, claudeResponse.Message.Usage.CacheCreationInputTokens)
 
 	case "message_delta":
 		h.convertToOpenaiStream(&claudeResponse, dataChan)
@@ -68,7 +68,20 @@ func ClaudeUsageToOpenaiUsage(cUsage *Usage, usage *types.Usage) {
 		return
 	}
 
-	usage.PromptTokens = cUsage.InputTokens
+	usage.PromptTokens = cUsage.GetPromptTokens()
 	usage.CompletionTokens = cUsage.OutputTokens
-	usage.TotalTokens = cUsage.InputTokens + cUsage.OutputTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	usage.SetPromptCacheTokens(cUsage.CacheReadInputTokens, cUsage.CacheCreationInputTokens)
+}
+
+func OpenaiUsageToClaudeUsage(usage *types.Usage) Usage {
+	cachedTokens := usage.GetPromptCachedTokens()
+	cacheWriteTokens := usage.GetPromptCacheWriteTokens()
+
+	return Usage{
+		InputTokens:              max(usage.PromptTokens-cachedTokens-cacheWriteTokens, 0),
+		OutputTokens:             usage.CompletionTokens,
+		CacheCreationInputTokens: cacheWriteTokens,
+		CacheReadInputTokens:     cachedTokens,
+	}
 }
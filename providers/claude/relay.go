@@ -89,7 +89,8 @@ func (h *ClaudeRelayStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan
 
 	switch claudeResponse.Type {
 	case "message_start":
-		h.Usage.PromptTokens = claudeResponse.Message.Usage.InputTokens
+		h.Usage.PromptTokens = claudeResponse.Message.Usage.GetPromptTokens()
+		h.Usage.SetPromptCacheTokens(claudeResponse.Message.Usage.CacheReadInputTokens, claudeResponse.Message.Usage.CacheCreationInputTokens)
 	case "message_delta":
 		h.Usage.CompletionTokens = claudeResponse.Usage.OutputTokens
 		h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
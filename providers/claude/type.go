@@ -125,9 +125,17 @@ type Tools struct {
 }
 
 type Usage struct {
-	InputTokens  int `json:"input_tokens,omitempty"`
-	OutputTokens int `json:"output_tokens,omitempty"`
+	InputTokens              int `json:"input_tokens,omitempty"`
+	OutputTokens             int `json:"output_tokens,omitempty"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
+
+// GetPromptTokens Claude 的 input_tokens 不包含缓存部分
+func (u *Usage) GetPromptTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
 type ClaudeResponse struct {
 	Id           string       `json:"id"`
 	Type         string       `json:"type"`
@@ -0,0 +1,63 @@
+package cohere
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/types"
+)
+
+func (p *CohereProvider) CreateRerank(request *types.RerankRequest) (*types.RerankResponse, *types.OpenAIErrorWithStatusCode) {
+	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeRerank)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(url)
+	if fullRequestURL == "" {
+		return nil, common.ErrorWrapper(nil, "invalid_cohere_config", http.StatusInternalServerError)
+	}
+
+	headers := p.GetRequestHeaders()
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	cohereResponse := &CohereRerankResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, cohereResponse, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return ConvertToRerankOpenai(p, cohereResponse, request)
+}
+
+func ConvertToRerankOpenai(p *CohereProvider, response *CohereRerankResponse, request *types.RerankRequest) (*types.RerankResponse, *types.OpenAIErrorWithStatusCode) {
+	aiError := errorHandle(&response.CohereError)
+	if aiError != nil {
+		return nil, &types.OpenAIErrorWithStatusCode{
+			OpenAIError: *aiError,
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	if response.Meta.BilledUnits.SearchUnits > 0 {
+		p.Usage.SearchUnits = response.Meta.BilledUnits.SearchUnits
+	}
+	p.Usage.TotalTokens = p.Usage.PromptTokens
+
+	rerankResponse := &types.RerankResponse{
+		ID:      response.ID,
+		Model:   request.Model,
+		Results: response.Results,
+		Usage:   p.Usage,
+	}
+
+	return rerankResponse, nil
+}
@@ -193,3 +193,10 @@ type ModelDetails struct {
 	Name      string   `json:"name"`
 	Endpoints []string `json:"endpoints"`
 }
+
+type CohereRerankResponse struct {
+	ID      string               `json:"id"`
+	Results []types.RerankResult `json:"results"`
+	Meta    Meta                 `json:"meta"`
+	CohereError
+}
@@ -0,0 +1,488 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+// ChatAdapter 让任意实现了 base.ChatInterface 的渠道都可以处理 Gemini 格式的请求
+type ChatAdapter struct {
+	base.ChatInterface
+}
+
+func NewChatAdapter(provider base.ChatInterface) *ChatAdapter {
+	return &ChatAdapter{ChatInterface: provider}
+}
+
+func (a *ChatAdapter) CreateGeminiChat(request *GeminiChatRequest) (*GeminiChatResponse, *GeminiErrorWithStatusCode) {
+	chatRequest, err := ConvertToChatRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "INVALID_ARGUMENT", http.StatusBadRequest, true)
+	}
+
+	response, errWithCode := a.CreateChatCompletion(chatRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToGeminiErr(errWithCode)
+	}
+
+	return ConvertFromChatResponse(response, a.GetUsage(), request.Model), nil
+}
+
+func (a *ChatAdapter) CreateGeminiChatStream(request *GeminiChatRequest) (requester.StreamReaderInterface[string], *GeminiErrorWithStatusCode) {
+	chatRequest, err := ConvertToChatRequest(request)
+	if err != nil {
+		return nil, StringErrorWrapper(err.Error(), "INVALID_ARGUMENT", http.StatusBadRequest, true)
+	}
+
+	stream, errWithCode := a.CreateChatCompletionStream(chatRequest)
+	if errWithCode != nil {
+		return nil, OpenaiErrToGeminiErr(errWithCode)
+	}
+
+	return &chatAdapterStream{
+		StreamReaderInterface: stream,
+		converter: &chatStreamConverter{
+			usage:     a.GetUsage(),
+			modelName: request.Model,
+		},
+	}, nil
+}
+
+// ConvertToChatRequest 将 Gemini 请求转换为 OpenAI 聊天请求
+func ConvertToChatRequest(request *GeminiChatRequest) (*types.ChatCompletionRequest, error) {
+	config := request.GenerationConfig
+	chatRequest := &types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(request.Contents)+1),
+		MaxTokens:   config.MaxOutputTokens,
+		Temperature: config.Temperature,
+		TopP:        config.TopP,
+		N:           config.CandidateCount,
+		Stop:        config.StopSequences,
+		Stream:      request.Stream,
+	}
+
+	if request.SystemInstruction != nil {
+		if system := partsText(request.SystemInstruction.Parts); system != "" {
+			chatRequest.Messages = append(chatRequest.Messages, types.ChatCompletionMessage{
+				Role:    types.ChatMessageRoleSystem,
+				Content: system,
+			})
+		}
+	}
+
+	// Gemini 的函数调用没有 id，按函数名依次匹配调用结果
+	callIds := make(map[string][]string)
+	for _, content := range request.Contents {
+		messages, err := convertToChatMessages(&content, callIds)
+		if err != nil {
+			return nil, err
+		}
+		chatRequest.Messages = append(chatRequest.Messages, messages...)
+	}
+
+	for _, tool := range request.Tools {
+		for _, function := range tool.FunctionDeclarations {
+			function := function
+			function.Parameters = normalizeSchema(function.Parameters)
+			if function.Parameters == nil {
+				function.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			chatRequest.Tools = append(chatRequest.Tools, &types.ChatCompletionTool{
+				Type:     "function",
+				Function: function,
+			})
+		}
+	}
+
+	if request.ToolConfig != nil && request.ToolConfig.FunctionCallingConfig != nil {
+		callingConfig := request.ToolConfig.FunctionCallingConfig
+		switch callingConfig.Mode {
+		case "NONE":
+			chatRequest.ToolChoice = types.ToolChoiceTypeNone
+		case "ANY":
+			chatRequest.ToolChoice = types.ToolChoiceTypeRequired
+			if len(callingConfig.AllowedFunctionNames) == 1 {
+				chatRequest.ToolChoice = map[string]any{
+					"type": types.ToolChoiceTypeFunction,
+					"function": map[string]any{
+						"name": callingConfig.AllowedFunctionNames[0],
+					},
+				}
+			}
+		case "AUTO":
+			chatRequest.ToolChoice = types.ToolChoiceTypeAuto
+		}
+	}
+
+	if config.ResponseMimeType == "application/json" {
+		chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{Type: "json_object"}
+		if config.ResponseSchema != nil {
+			chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{
+				Type: "json_schema",
+				JsonSchema: map[string]any{
+					"name":   "response",
+					"schema": config.ResponseSchema,
+				},
+			}
+		}
+	}
+
+	return chatRequest, nil
+}
+
+// Gemini 的 schema 类型为大写(如 OBJECT)，OpenAI 需要小写
+func normalizeSchema(schema any) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if typeName, ok := value.(string); ok && key == "type" {
+				v[key] = strings.ToLower(typeName)
+				continue
+			}
+			v[key] = normalizeSchema(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeSchema(value)
+		}
+	}
+	return schema
+}
+
+func convertToChatMessages(content *GeminiChatContent, callIds map[string][]string) ([]types.ChatCompletionMessage, error) {
+	role := types.ChatMessageRoleUser
+	if content.Role == "model" {
+		role = types.ChatMessageRoleAssistant
+	}
+
+	// functionResponse 需要拆分为单独的 tool 消息
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]any, 0, len(content.Parts))
+	var toolCalls []*types.ChatCompletionToolCalls
+
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			arguments, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, err
+			}
+			if part.FunctionCall.Args == nil {
+				arguments = []byte("{}")
+			}
+
+			callId := "call_" + utils.GetRandomString(24)
+			callIds[part.FunctionCall.Name] = append(callIds[part.FunctionCall.Name], callId)
+			toolCalls = append(toolCalls, &types.ChatCompletionToolCalls{
+				Id:    callId,
+				Type:  "function",
+				Index: len(toolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(arguments),
+				},
+			})
+		case part.FunctionResponse != nil:
+			name := part.FunctionResponse.Name
+			callId := "call_" + utils.GetRandomString(24)
+			if ids := callIds[name]; len(ids) > 0 {
+				callId = ids[0]
+				callIds[name] = ids[1:]
+			}
+
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    utils.Marshal(part.FunctionResponse.Response),
+				Name:       &name,
+				ToolCallID: callId,
+			})
+		case part.InlineData != nil:
+			if !strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				return nil, fmt.Errorf("unsupported inline data type: %s", part.InlineData.MimeType)
+			}
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeImageURL,
+				"image_url": map[string]any{
+					"url": fmt.Sprintf("data:%s;base64,%s", part.InlineData.MimeType, part.InlineData.Data),
+				},
+			})
+		case part.FileData != nil:
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeImageURL,
+				"image_url": map[string]any{
+					"url": part.FileData.FileUri,
+				},
+			})
+		case part.Text != "":
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeText,
+				"text": part.Text,
+			})
+		}
+	}
+
+	if len(parts) == 0 && toolCalls == nil {
+		return messages, nil
+	}
+
+	chatMessage := types.ChatCompletionMessage{
+		Role:      role,
+		ToolCalls: toolCalls,
+	}
+	if len(parts) > 0 {
+		chatMessage.Content = parts
+	}
+
+	return append(messages, chatMessage), nil
+}
+
+func partsText(parts []GeminiPart) string {
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// ConvertFromChatResponse 将 OpenAI 聊天响应转换为 Gemini 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, usage *types.Usage, modelName string) *GeminiChatResponse {
+	geminiResponse := &GeminiChatResponse{
+		Candidates:    make([]GeminiChatCandidate, 0, len(response.Choices)),
+		UsageMetadata: usageToGemini(usage),
+		Model:         modelName,
+	}
+
+	for _, choice := range response.Choices {
+		parts := make([]GeminiPart, 0)
+		if text := choice.Message.StringContent(); text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			parts = append(parts, GeminiPart{
+				FunctionCall: toGeminiFunctionCall(toolCall.Function.Name, toolCall.Function.Arguments),
+			})
+		}
+
+		geminiResponse.Candidates = append(geminiResponse.Candidates, GeminiChatCandidate{
+			Content: GeminiChatContent{
+				Role:  "model",
+				Parts: parts,
+			},
+			FinishReason:  finishReasonOpenAI2Gemini(choice.FinishReason),
+			Index:         int64(choice.Index),
+			SafetyRatings: defaultSafetyRatings(),
+		})
+	}
+
+	return geminiResponse
+}
+
+func toGeminiFunctionCall(name, arguments string) *GeminiFunctionCall {
+	args := make(map[string]interface{})
+	if arguments != "" {
+		_ = json.Unmarshal([]byte(arguments), &args)
+	}
+
+	return &GeminiFunctionCall{
+		Name: name,
+		Args: args,
+	}
+}
+
+func usageToGemini(usage *types.Usage) *GeminiUsageMetadata {
+	if usage == nil {
+		return nil
+	}
+
+	reasoningTokens := usage.GetCompletionReasoningTokens()
+
+	return &GeminiUsageMetadata{
+		PromptTokenCount:        usage.PromptTokens,
+		CandidatesTokenCount:    max(usage.CompletionTokens-reasoningTokens, 0),
+		TotalTokenCount:         usage.PromptTokens + usage.CompletionTokens,
+		CachedContentTokenCount: usage.GetPromptCachedTokens(),
+		ThoughtsTokenCount:      reasoningTokens,
+	}
+}
+
+func finishReasonOpenAI2Gemini(reason any) string {
+	switch reason {
+	case types.FinishReasonLength:
+		return "MAX_TOKENS"
+	case types.FinishReasonContentFilter:
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+// 其他渠道没有安全评级，统一返回最低等级
+func defaultSafetyRatings() []GeminiChatSafetyRating {
+	categories := []string{
+		"HARM_CATEGORY_HARASSMENT",
+		"HARM_CATEGORY_HATE_SPEECH",
+		"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+		"HARM_CATEGORY_DANGEROUS_CONTENT",
+	}
+
+	ratings := make([]GeminiChatSafetyRating, 0, len(categories))
+	for _, category := range categories {
+		ratings = append(ratings, GeminiChatSafetyRating{
+			Category:    category,
+			Probability: "NEGLIGIBLE",
+		})
+	}
+	return ratings
+}
+
+type chatAdapterStream struct {
+	requester.StreamReaderInterface[string]
+	converter *chatStreamConverter
+}
+
+func (s *chatAdapterStream) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.StreamReaderInterface.Recv()
+	wrappedDataChan := make(chan string)
+	wrappedErrChan := make(chan error)
+
+	// 数据和错误在同一个协程中转发，保证结束数据在最后输出
+	go func() {
+		for {
+			select {
+			case data := <-dataChan:
+				if chunk := s.converter.convert(data); chunk != "" {
+					wrappedDataChan <- chunk
+				}
+			case err := <-errChan:
+				if errors.Is(err, io.EOF) {
+					wrappedDataChan <- s.converter.finish()
+				} else {
+					wrappedDataChan <- s.converter.fail(err)
+				}
+				wrappedErrChan <- io.EOF
+				return
+			}
+		}
+	}()
+
+	return wrappedDataChan, wrappedErrChan
+}
+
+type chatStreamToolCall struct {
+	name      string
+	arguments strings.Builder
+}
+
+// chatStreamConverter 将 OpenAI 流式响应转换为 Gemini 的 SSE 数据
+type chatStreamConverter struct {
+	usage        *types.Usage
+	modelName    string
+	toolCalls    []*chatStreamToolCall
+	toolCallId   string
+	finishReason any
+}
+
+func (h *chatStreamConverter) convert(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	text := ""
+	for _, choice := range chunk.Choices {
+		text += choice.Delta.Content
+
+		// 函数调用的参数是分段返回的，需要等结束时一起输出
+		for _, toolCall := range choice.Delta.ToolCalls {
+			h.appendToolCall(toolCall)
+		}
+
+		if choice.FinishReason != nil && choice.FinishReason != "" {
+			h.finishReason = choice.FinishReason
+		}
+	}
+
+	if text == "" {
+		return ""
+	}
+
+	return h.chunk([]GeminiPart{{Text: text}}, "", nil)
+}
+
+func (h *chatStreamConverter) appendToolCall(toolCall *types.ChatCompletionToolCalls) {
+	if toolCall.Function == nil {
+		return
+	}
+
+	current := len(h.toolCalls) - 1
+	if current < 0 || (toolCall.Id != "" && toolCall.Id != h.toolCallId) || (toolCall.Id == "" && toolCall.Function.Name != "") {
+		h.toolCallId = toolCall.Id
+		h.toolCalls = append(h.toolCalls, &chatStreamToolCall{name: toolCall.Function.Name})
+		current = len(h.toolCalls) - 1
+	}
+
+	h.toolCalls[current].arguments.WriteString(toolCall.Function.Arguments)
+}
+
+func (h *chatStreamConverter) finish() string {
+	parts := make([]GeminiPart, 0, len(h.toolCalls))
+	for _, toolCall := range h.toolCalls {
+		parts = append(parts, GeminiPart{
+			FunctionCall: toGeminiFunctionCall(toolCall.name, toolCall.arguments.String()),
+		})
+	}
+
+	return h.chunk(parts, finishReasonOpenAI2Gemini(h.finishReason), usageToGemini(h.usage))
+}
+
+func (h *chatStreamConverter) fail(err error) string {
+	geminiError := &GeminiErrorResponse{
+		ErrorInfo: &GeminiError{
+			Code:    http.StatusInternalServerError,
+			Status:  "INTERNAL",
+			Message: err.Error(),
+		},
+	}
+
+	var aiError *types.OpenAIError
+	if errors.As(err, &aiError) {
+		geminiError.ErrorInfo.Message = aiError.Message
+	}
+
+	body, _ := json.Marshal(geminiError)
+	return fmt.Sprintf("data: %s\n\n", body)
+}
+
+func (h *chatStreamConverter) chunk(parts []GeminiPart, finishReason string, usage *GeminiUsageMetadata) string {
+	response := GeminiChatResponse{
+		Candidates: []GeminiChatCandidate{{
+			Content: GeminiChatContent{
+				Role:  "model",
+				Parts: parts,
+			},
+			FinishReason:  finishReason,
+			SafetyRatings: defaultSafetyRatings(),
+		}},
+		UsageMetadata: usage,
+		Model:         h.modelName,
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("data: %s\n\n", body)
+}
@@ -0,0 +1,355 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToChatRequest(t *testing.T) {
+	cases := []struct {
+		name           string
+		request        string
+		messages       []types.ChatCompletionMessage
+		tools          []*types.ChatCompletionTool
+		toolChoice     any
+		responseFormat *types.ChatCompletionResponseFormat
+		err            string
+	}{
+		{
+			name: "system and contents",
+			request: `{"systemInstruction":{"parts":[{"text":"be "},{"text":"brief"}]},"contents":[
+				{"role":"user","parts":[{"text":"what is this"},{"inlineData":{"mimeType":"image/png","data":"AAAA"}},{"fileData":{"fileUri":"https://example.com/a.png"}}]},
+				{"role":"model","parts":[{"text":"a cat"}]}
+			],"generationConfig":{"maxOutputTokens":100,"responseMimeType":"application/json"}}`,
+			messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleSystem, Content: "be brief"},
+				{Role: types.ChatMessageRoleUser, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "what is this"},
+					map[string]any{"type": types.ContentTypeImageURL, "image_url": map[string]any{"url": "data:image/png;base64,AAAA"}},
+					map[string]any{"type": types.ContentTypeImageURL, "image_url": map[string]any{"url": "https://example.com/a.png"}},
+				}},
+				{Role: types.ChatMessageRoleAssistant, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "a cat"},
+				}},
+			},
+			responseFormat: &types.ChatCompletionResponseFormat{Type: "json_object"},
+		},
+		{
+			name: "tools and schema",
+			request: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"tools":[{"functionDeclarations":[
+				{"name":"get_weather","description":"weather","parameters":{"type":"OBJECT","properties":{"city":{"type":"STRING"},"days":{"type":"ARRAY","items":[{"type":"INTEGER"}]}}}},
+				{"name":"noop"}
+			]}],"toolConfig":{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["get_weather"]}},
+			"generationConfig":{"responseMimeType":"application/json","responseSchema":{"type":"object"}}}`,
+			messages: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleUser, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "hi"},
+				}},
+			},
+			tools: []*types.ChatCompletionTool{
+				{Type: "function", Function: types.ChatCompletionFunction{
+					Name:        "get_weather",
+					Description: "weather",
+					Parameters: map[string]any{"type": "object", "properties": map[string]any{
+						"city": map[string]any{"type": "string"},
+						"days": map[string]any{"type": "array", "items": []any{map[string]any{"type": "integer"}}},
+					}},
+				}},
+				{Type: "function", Function: types.ChatCompletionFunction{
+					Name:       "noop",
+					Parameters: map[string]any{"type": "object", "properties": map[string]any{}},
+				}},
+			},
+			toolChoice: map[string]any{
+				"type":     types.ToolChoiceTypeFunction,
+				"function": map[string]any{"name": "get_weather"},
+			},
+			responseFormat: &types.ChatCompletionResponseFormat{
+				Type:       "json_schema",
+				JsonSchema: map[string]any{"name": "response", "schema": map[string]any{"type": "object"}},
+			},
+		},
+		{
+			name:       "tool config none",
+			request:    `{"contents":[],"toolConfig":{"functionCallingConfig":{"mode":"NONE"}}}`,
+			messages:   []types.ChatCompletionMessage{},
+			toolChoice: types.ToolChoiceTypeNone,
+		},
+		{
+			name:       "tool config any without names",
+			request:    `{"contents":[],"toolConfig":{"functionCallingConfig":{"mode":"ANY"}}}`,
+			messages:   []types.ChatCompletionMessage{},
+			toolChoice: types.ToolChoiceTypeRequired,
+		},
+		{
+			name:    "unsupported inline data",
+			request: `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"audio/wav","data":"AAAA"}}]}]}`,
+			err:     "unsupported inline data type: audio/wav",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := &GeminiChatRequest{Model: "gemini"}
+			assert.Nil(t, json.Unmarshal([]byte(tc.request), request))
+
+			chatRequest, err := ConvertToChatRequest(request)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "gemini", chatRequest.Model)
+			assert.Equal(t, tc.messages, chatRequest.Messages)
+			assert.Equal(t, tc.tools, chatRequest.Tools)
+			assert.Equal(t, tc.toolChoice, chatRequest.ToolChoice)
+			assert.Equal(t, tc.responseFormat, chatRequest.ResponseFormat)
+		})
+	}
+}
+
+func TestConvertToChatRequestFunctionCallIds(t *testing.T) {
+	request := &GeminiChatRequest{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"contents":[
+		{"role":"model","parts":[
+			{"functionCall":{"name":"get_weather","args":{"city":"a"}}},
+			{"functionCall":{"name":"get_weather","args":{"city":"b"}}},
+			{"functionCall":{"name":"noop"}}
+		]},
+		{"role":"user","parts":[
+			{"functionResponse":{"name":"get_weather","response":{"temp":1}}},
+			{"functionResponse":{"name":"get_weather","response":{"temp":2}}},
+			{"functionResponse":{"name":"noop","response":{}}}
+		]}
+	]}`), request))
+
+	chatRequest, err := ConvertToChatRequest(request)
+	assert.Nil(t, err)
+	assert.Len(t, chatRequest.Messages, 4)
+
+	toolCalls := chatRequest.Messages[0].ToolCalls
+	assert.Equal(t, types.ChatMessageRoleAssistant, chatRequest.Messages[0].Role)
+	assert.Len(t, toolCalls, 3)
+	assert.Equal(t, `{"city":"a"}`, toolCalls[0].Function.Arguments)
+	assert.Equal(t, `{}`, toolCalls[2].Function.Arguments)
+
+	// 同名函数的调用结果按顺序对应调用 id
+	expected := []struct {
+		callId  string
+		content string
+	}{
+		{toolCalls[0].Id, `{"temp":1}`},
+		{toolCalls[1].Id, `{"temp":2}`},
+		{toolCalls[2].Id, `{}`},
+	}
+	for i, want := range expected {
+		message := chatRequest.Messages[i+1]
+		assert.Equal(t, types.ChatMessageRoleTool, message.Role)
+		assert.Equal(t, want.callId, message.ToolCallID)
+		assert.Equal(t, want.content, message.Content)
+	}
+}
+
+func TestConvertFromChatResponse(t *testing.T) {
+	cases := []struct {
+		name         string
+		response     string
+		parts        []GeminiPart
+		finishReason string
+	}{
+		{
+			name:         "text",
+			response:     `{"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`,
+			parts:        []GeminiPart{{Text: "hello"}},
+			finishReason: "STOP",
+		},
+		{
+			name:         "length",
+			response:     `{"choices":[{"index":0,"message":{"role":"assistant","content":"hel"},"finish_reason":"length"}]}`,
+			parts:        []GeminiPart{{Text: "hel"}},
+			finishReason: "MAX_TOKENS",
+		},
+		{
+			name:         "content filter",
+			response:     `{"choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`,
+			parts:        []GeminiPart{},
+			finishReason: "SAFETY",
+		},
+		{
+			name: "tool calls",
+			response: `{"choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[
+				{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"a\"}"}},
+				{"id":"call_2","type":"function","function":{"name":"noop","arguments":""}}
+			]},"finish_reason":"tool_calls"}]}`,
+			parts: []GeminiPart{
+				{FunctionCall: &GeminiFunctionCall{Name: "get_weather", Args: map[string]any{"city": "a"}}},
+				{FunctionCall: &GeminiFunctionCall{Name: "noop", Args: map[string]any{}}},
+			},
+			finishReason: "STOP",
+		},
+	}
+
+	usage := &types.Usage{
+		PromptTokens:     100,
+		CompletionTokens: 30,
+		PromptTokensDetails: &types.PromptTokensDetails{
+			CachedTokens: 40,
+		},
+		CompletionTokensDetails: &types.CompletionTokensDetails{
+			ReasoningTokens: 10,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := &types.ChatCompletionResponse{}
+			assert.Nil(t, json.Unmarshal([]byte(tc.response), response))
+
+			geminiResponse := ConvertFromChatResponse(response, usage, "gemini")
+			assert.Equal(t, "gemini", geminiResponse.Model)
+			assert.Len(t, geminiResponse.Candidates, 1)
+
+			candidate := geminiResponse.Candidates[0]
+			assert.Equal(t, "model", candidate.Content.Role)
+			assert.Equal(t, tc.parts, candidate.Content.Parts)
+			assert.Equal(t, tc.finishReason, candidate.FinishReason)
+			assert.Len(t, candidate.SafetyRatings, 4)
+			assert.Equal(t, &GeminiUsageMetadata{
+				PromptTokenCount:        100,
+				CandidatesTokenCount:    20,
+				TotalTokenCount:         130,
+				CachedContentTokenCount: 40,
+				ThoughtsTokenCount:      10,
+			}, geminiResponse.UsageMetadata)
+		})
+	}
+
+	assert.Nil(t, ConvertFromChatResponse(&types.ChatCompletionResponse{}, nil, "gemini").UsageMetadata)
+}
+
+func parseGeminiChunks(t *testing.T, raw string) []*GeminiChatResponse {
+	var chunks []*GeminiChatResponse
+	for _, block := range strings.Split(raw, "\n\n") {
+		if block == "" {
+			continue
+		}
+
+		chunk := &GeminiChatResponse{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(block, "data: ")), chunk))
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+func TestChatStreamConverter(t *testing.T) {
+	cases := []struct {
+		name         string
+		chunks       []string
+		texts        []string
+		toolCalls    []*GeminiFunctionCall
+		finishReason string
+	}{
+		{
+			name: "text",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+				`{"choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+				`{"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			},
+			texts:        []string{"Hel", "lo"},
+			toolCalls:    []*GeminiFunctionCall{},
+			finishReason: "STOP",
+		},
+		{
+			name: "tool calls with the same index",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"a","arguments":""}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"x\":"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_2","function":{"name":"b","arguments":"{}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			},
+			toolCalls: []*GeminiFunctionCall{
+				{Name: "a", Args: map[string]any{"x": float64(1)}},
+				// 空参数在序列化时省略
+				{Name: "b"},
+			},
+			finishReason: "STOP",
+		},
+		{
+			name: "length",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"length"}]}`,
+				`not json`,
+			},
+			texts:        []string{"Hi"},
+			toolCalls:    []*GeminiFunctionCall{},
+			finishReason: "MAX_TOKENS",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			converter := &chatStreamConverter{
+				usage:     &types.Usage{PromptTokens: 10, CompletionTokens: 5},
+				modelName: "gemini",
+			}
+
+			var raw strings.Builder
+			for _, chunk := range tc.chunks {
+				raw.WriteString(converter.convert(chunk))
+			}
+			raw.WriteString(converter.finish())
+
+			chunks := parseGeminiChunks(t, raw.String())
+			assert.Len(t, chunks, len(tc.texts)+1)
+
+			var texts []string
+			for _, chunk := range chunks[:len(chunks)-1] {
+				assert.Equal(t, "gemini", chunk.Model)
+				assert.Equal(t, "", chunk.Candidates[0].FinishReason)
+				assert.Nil(t, chunk.UsageMetadata)
+				texts = append(texts, chunk.Candidates[0].Content.Parts[0].Text)
+			}
+			assert.Equal(t, tc.texts, texts)
+
+			// 最后一块包含函数调用、结束原因和用量
+			last := chunks[len(chunks)-1].Candidates[0]
+			toolCalls := make([]*GeminiFunctionCall, 0)
+			for _, part := range last.Content.Parts {
+				toolCalls = append(toolCalls, part.FunctionCall)
+			}
+			assert.Equal(t, tc.toolCalls, toolCalls)
+			assert.Equal(t, tc.finishReason, last.FinishReason)
+			assert.Equal(t, 15, chunks[len(chunks)-1].UsageMetadata.TotalTokenCount)
+		})
+	}
+}
+
+func TestChatStreamConverterFail(t *testing.T) {
+	converter := &chatStreamConverter{usage: &types.Usage{}}
+
+	cases := []struct {
+		err     error
+		message string
+	}{
+		{errors.New("connection reset"), "connection reset"},
+		{&types.OpenAIError{Message: "overloaded", Type: "server_error"}, "overloaded"},
+	}
+
+	for _, tc := range cases {
+		raw := converter.fail(tc.err)
+
+		geminiError := &GeminiErrorResponse{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(raw, "data: "))), geminiError))
+		assert.Equal(t, &GeminiError{Code: 500, Status: "INTERNAL", Message: tc.message}, geminiError.ErrorInfo)
+	}
+}
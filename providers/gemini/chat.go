@@ -19,6 +19,7 @@ const (
 type GeminiStreamHandler struct {
 	Usage          *types.Usage
 	LastCandidates int
+	LastThoughts   int
 	LastType       string
 	Request        *types.ChatCompletionRequest
 }
@@ -259,8 +260,9 @@ func (h *GeminiStreamHandler) convertToOpenaiStream(geminiResponse *GeminiChatRe
 
 	h.Usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
 	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates
-	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
 	h.LastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
+	h.LastThoughts = updateStreamThoughts(h.Usage, geminiResponse.UsageMetadata, h.LastThoughts)
+	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
 }
 
 const tokenThreshold = 1000000
@@ -302,17 +304,35 @@ func adjustTokenCounts(modelName string, usage *GeminiUsageMetadata) {
 		usage.CandidatesTokenCount = adjustTokenCount(usage.CandidatesTokenCount)
 	}
 
-	usage.TotalTokenCount = usage.PromptTokenCount + usage.CandidatesTokenCount
+	usage.TotalTokenCount = usage.PromptTokenCount + usage.CandidatesTokenCount + usage.ThoughtsTokenCount
 }
 
+// convertOpenAIUsage 思考的tokens按输出计费，所以计入CompletionTokens
 func convertOpenAIUsage(modelName string, geminiUsage *GeminiUsageMetadata) types.Usage {
 	adjustTokenCounts(modelName, geminiUsage)
 
-	return types.Usage{
+	usage := types.Usage{
 		PromptTokens:     geminiUsage.PromptTokenCount,
-		CompletionTokens: geminiUsage.CandidatesTokenCount,
+		CompletionTokens: geminiUsage.CandidatesTokenCount + geminiUsage.ThoughtsTokenCount,
 		TotalTokens:      geminiUsage.TotalTokenCount,
 	}
+	usage.SetPromptCacheTokens(geminiUsage.CachedContentTokenCount, 0)
+	usage.SetCompletionReasoningTokens(geminiUsage.ThoughtsTokenCount)
+
+	return usage
+}
+
+// updateStreamThoughts 流式响应中的思考tokens是累计值，只累加增量
+func updateStreamThoughts(usage *types.Usage, geminiUsage *GeminiUsageMetadata, lastThoughts int) int {
+	if geminiUsage.ThoughtsTokenCount > lastThoughts {
+		usage.CompletionTokens += geminiUsage.ThoughtsTokenCount - lastThoughts
+		lastThoughts = geminiUsage.ThoughtsTokenCount
+	}
+
+	usage.SetPromptCacheTokens(geminiUsage.CachedContentTokenCount, 0)
+	usage.SetCompletionReasoningTokens(lastThoughts)
+
+	return lastThoughts
 }
 
 func (p *GeminiProvider) pluginHandle(request *GeminiChatRequest) {
@@ -11,6 +11,7 @@ import (
 type GeminiRelayStreamHandler struct {
 	Usage          *types.Usage
 	LastCandidates int
+	LastThoughts   int
 	LastType       string
 	Prefix         string
 	ModelName      string
@@ -107,8 +108,9 @@ func (h *GeminiRelayStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan
 
 	h.Usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
 	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates
-	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
 	h.LastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
+	h.LastThoughts = updateStreamThoughts(h.Usage, geminiResponse.UsageMetadata, h.LastThoughts)
+	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
 
 	dataChan <- rawStr
 }
@@ -13,8 +13,8 @@ type GeminiChatRequest struct {
 	Model             string                     `json:"-"`
 	Stream            bool                       `json:"-"`
 	Contents          []GeminiChatContent        `json:"contents"`
-	SafetySettings    []GeminiChatSafetySettings `json:"safety_settings,omitempty"`
-	GenerationConfig  GeminiChatGenerationConfig `json:"generation_config,omitempty"`
+	SafetySettings    []GeminiChatSafetySettings `json:"safetySettings,omitempty"`
+	GenerationConfig  GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
 	Tools             []GeminiChatTools          `json:"tools,omitempty"`
 	ToolConfig        *GeminiToolConfig          `json:"toolConfig,omitempty"`
 	SystemInstruction *GeminiChatContent         `json:"systemInstruction,omitempty"`
@@ -25,7 +25,7 @@ type GeminiToolConfig struct {
 }
 
 type GeminiFunctionCallingConfig struct {
-	Model                string   `json:"model,omitempty"`
+	Mode                 string   `json:"mode,omitempty"`
 	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 type GeminiInlineData struct {
@@ -235,6 +235,7 @@ type GeminiUsageMetadata struct {
 	CandidatesTokenCount    int `json:"candidatesTokenCount"`
 	TotalTokenCount         int `json:"totalTokenCount"`
 	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
 }
 
 type GeminiChatCandidate struct {
@@ -65,6 +65,7 @@ func getOpenAIConfig(baseURL string, channel *model.Channel) base.ProviderConfig
 		ImagesGenerations:   "/v1/images/generations",
 		ImagesEdit:          "/v1/images/edits",
 		ImagesVariations:    "/v1/images/variations",
+		Rerank:              "/v1/rerank",
 		ModelList:           "/v1/models",
 	}
 
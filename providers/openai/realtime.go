@@ -0,0 +1,51 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/types"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+func (p *OpenAIProvider) CreateRealtimeConn(modelName string) (*websocket.Conn, *types.OpenAIErrorWithStatusCode) {
+	fullRequestURL := p.getRealtimeURL(modelName)
+
+	headers := p.GetRequestHeaders()
+	// 握手请求不需要这些头
+	delete(headers, "Content-Type")
+	delete(headers, "Accept")
+	if !p.IsAzure {
+		headers["OpenAI-Beta"] = "realtime=v1"
+	}
+
+	wsRequester := requester.NewWSRequester(*p.Channel.Proxy)
+	conn, err := wsRequester.NewRequest(fullRequestURL, wsRequester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "ws_request_failed", http.StatusBadGateway)
+	}
+
+	return conn, nil
+}
+
+func (p *OpenAIProvider) getRealtimeURL(modelName string) string {
+	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
+
+	var fullRequestURL string
+	if p.IsAzure {
+		modelName = strings.Replace(modelName, ".", "", -1)
+		fullRequestURL = fmt.Sprintf("%s/openai/realtime?api-version=%s&deployment=%s", baseURL, p.Channel.Other, url.QueryEscape(modelName))
+	} else {
+		fullRequestURL = fmt.Sprintf("%s/v1/realtime?model=%s", baseURL, url.QueryEscape(modelName))
+	}
+
+	if strings.HasPrefix(fullRequestURL, "https://") {
+		return "wss://" + strings.TrimPrefix(fullRequestURL, "https://")
+	}
+
+	return "ws://" + strings.TrimPrefix(fullRequestURL, "http://")
+}
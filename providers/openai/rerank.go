@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"net/http"
+	"one-api/common/config"
+	"one-api/types"
+)
+
+// CreateRerank 兼容 Jina 格式的重排序接口
+func (p *OpenAIProvider) CreateRerank(request *types.RerankRequest) (*types.RerankResponse, *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.GetRequestTextBody(config.RelayModeRerank, request.Model, request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	response := &OpenAIProviderRerankResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	openaiErr := ErrorHandle(&response.OpenAIErrorResponse)
+	if openaiErr != nil {
+		errWithCode = &types.OpenAIErrorWithStatusCode{
+			OpenAIError: *openaiErr,
+			StatusCode:  http.StatusBadRequest,
+		}
+		return nil, errWithCode
+	}
+
+	// Jina 只返回 total_tokens
+	if response.Usage != nil && response.Usage.TotalTokens > 0 {
+		p.Usage.PromptTokens = response.Usage.TotalTokens
+		p.Usage.TotalTokens = response.Usage.TotalTokens
+	}
+
+	return &response.RerankResponse, nil
+}
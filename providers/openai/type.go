@@ -30,6 +30,11 @@ type OpenAIProviderEmbeddingsResponse struct {
 	types.OpenAIErrorResponse
 }
 
+type OpenAIProviderRerankResponse struct {
+	types.RerankResponse
+	types.OpenAIErrorResponse
+}
+
 type OpenAIProviderModerationResponse struct {
 	types.ModerationResponse
 	types.OpenAIErrorResponse
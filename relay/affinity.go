@@ -0,0 +1,50 @@
+package relay
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"one-api/common/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setChannelAffinity 计算渠道亲和的 key，相同 key 的请求会尽量分配到同一个渠道，以命中上游的提示词缓存
+// 优先级：请求头 > user 字段 > 开头的几条消息
+func setChannelAffinity(c *gin.Context, user string, leadingMessages any) {
+	if !config.ChannelAffinityEnabled {
+		return
+	}
+
+	key := ""
+	if config.ChannelAffinityHeader != "" {
+		if session := c.GetHeader(config.ChannelAffinityHeader); session != "" {
+			key = "session:" + session
+		}
+	}
+
+	if key == "" && user != "" {
+		key = "user:" + user
+	}
+
+	if key == "" && leadingMessages != nil && config.ChannelAffinityMessages > 0 {
+		body, err := json.Marshal(leadingMessages)
+		if err == nil {
+			hash := md5.Sum(body)
+			key = "messages:" + hex.EncodeToString(hash[:])
+		}
+	}
+
+	if key != "" {
+		c.Set("channel_affinity_key", key)
+	}
+}
+
+// getLeadingMessages 获取开头的几条消息，多轮对话中这部分保持不变
+func getLeadingMessages[T any](messages []T) []T {
+	if len(messages) > config.ChannelAffinityMessages {
+		return messages[:config.ChannelAffinityMessages]
+	}
+
+	return messages
+}
@@ -0,0 +1,84 @@
+package relay
+
+import (
+	"net/http"
+	"one-api/common/metrics"
+	"one-api/model"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trackChannelRequest 记录渠道的进行中请求、首字延迟、用量和请求结果，供负载均衡策略、熔断器和渠道限制使用
+func trackChannelRequest(c *gin.Context, channelId int, modelName string, usage *types.Usage) func(apiErr *types.OpenAIErrorWithStatusCode) {
+	startTime := time.Now()
+	c.Set("first_response_time", time.Time{})
+	model.ChannelGroup.RequestStart(channelId, usage.PromptTokens, takeChannelReservation(c))
+
+	return func(apiErr *types.OpenAIErrorWithStatusCode) {
+		// 非流式请求没有首字时间，按整个请求的耗时计算
+		ttft := time.Since(startTime)
+		if firstResponseTime := c.GetTime("first_response_time"); !firstResponseTime.IsZero() {
+			ttft = firstResponseTime.Sub(startTime)
+		}
+
+		model.ChannelGroup.RequestDone(channelId, modelName, ttft, getRequestResult(apiErr), usage.CompletionTokens)
+
+		code := http.StatusOK
+		if apiErr != nil {
+			code = apiErr.StatusCode
+		}
+		metrics.RecordRelayRequest(channelId, modelName, c.GetString("group"), c.FullPath(), code, time.Since(startTime), ttft)
+	}
+}
+
+const channelReservationKey = "channel_reservation"
+
+// setChannelReservation 保存选择渠道时占用的名额，重试时之前没有使用的名额先退还
+func setChannelReservation(c *gin.Context, reservation *model.ChannelReservation) {
+	ReleaseChannelReservation(c)
+	c.Set(channelReservationKey, reservation)
+}
+
+func takeChannelReservation(c *gin.Context) *model.ChannelReservation {
+	value, ok := c.Get(channelReservationKey)
+	if !ok {
+		return nil
+	}
+	c.Set(channelReservationKey, (*model.ChannelReservation)(nil))
+
+	return value.(*model.ChannelReservation)
+}
+
+// ReleaseChannelReservation 请求结束时退还选中渠道后没有使用的名额
+func ReleaseChannelReservation(c *gin.Context) {
+	takeChannelReservation(c).Release()
+}
+
+// markFirstResponse 记录流式响应第一个数据块的时间
+func markFirstResponse(c *gin.Context) {
+	if c.GetTime("first_response_time").IsZero() {
+		c.Set("first_response_time", time.Now())
+	}
+}
+
+// getRequestResult 只有上游的问题才计入渠道的错误，鉴权失败影响整个渠道，其他错误只影响当前模型
+func getRequestResult(apiErr *types.OpenAIErrorWithStatusCode) model.RequestResult {
+	if apiErr == nil || apiErr.LocalError {
+		return model.RequestSuccess
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return model.RequestChannelFailure
+	case http.StatusTooManyRequests:
+		return model.RequestModelFailure
+	}
+
+	if apiErr.StatusCode/100 == 5 {
+		return model.RequestModelFailure
+	}
+
+	return model.RequestSuccess
+}
@@ -13,8 +13,10 @@ type relayBase struct {
 	c             *gin.Context
 	provider      providersBase.ProviderInterface
 	originalModel string
-	modelName     string
-	cache         *relay_util.ChatCacheProps
+	// 实际使用的模型，原模型没有可用渠道时为备用模型
+	servedModel string
+	modelName   string
+	cache       *relay_util.ChatCacheProps
 }
 
 type RelayBaseInterface interface {
@@ -25,6 +27,7 @@ type RelayBaseInterface interface {
 	setProvider(modelName string) error
 	getProvider() providersBase.ProviderInterface
 	getOriginalModel() string
+	getServedModel() string
 	getModelName() string
 	getContext() *gin.Context
 	SetChatCache(allow bool)
@@ -48,12 +51,13 @@ func (r *relayBase) IsStream() bool {
 	return false
 }
 
-func (r *relayBase) setProvider(modelName string) error {
-	provider, modelName, fail := GetProvider(r.c, modelName)
+func (r *relayBase) setProvider(servedModel string) error {
+	provider, modelName, fail := GetProvider(r.c, servedModel)
 	if fail != nil {
 		return fail
 	}
 	r.provider = provider
+	r.servedModel = servedModel
 	r.modelName = modelName
 	return nil
 }
@@ -70,6 +74,13 @@ func (r *relayBase) getOriginalModel() string {
 	return r.originalModel
 }
 
+func (r *relayBase) getServedModel() string {
+	if r.servedModel == "" {
+		return r.originalModel
+	}
+	return r.servedModel
+}
+
 func (r *relayBase) getModelName() string {
 	//return r.modelName
 	return r.originalModel
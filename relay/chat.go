@@ -114,6 +114,7 @@ func (r *relayChat) setRequest() error {
 	}
 
 	r.originalModel = r.chatRequest.Model
+	setChannelAffinity(r.c, r.chatRequest.User, getLeadingMessages(r.chatRequest.Messages))
 
 	// Add preprocessing step
 	if err := r.preprocessMessages(); err != nil {
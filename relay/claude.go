@@ -9,8 +9,10 @@ import (
 	"one-api/common/config"
 	"one-api/common/image"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"one-api/common/requester"
 	"one-api/model"
+	providersBase "one-api/providers/base"
 	"one-api/providers/claude"
 	"one-api/relay/relay_util"
 	"one-api/types"
@@ -25,6 +27,7 @@ func RelaycClaudeOnly(c *gin.Context) {
 		common.AbortWithErr(c, http.StatusBadRequest, claude.ErrorToClaudeErr(err))
 		return
 	}
+	setChannelAffinity(c, "", []any{request.System, getLeadingMessages(request.Messages)})
 
 	cacheProps := relay_util.NewChatCacheProps(c, true)
 	cacheProps.SetHash(request)
@@ -37,9 +40,11 @@ func RelaycClaudeOnly(c *gin.Context) {
 		return
 	}
 
-	chatProvider, modelName, fail := GetClaudeChatInterface(c, request.Model)
+	chatProvider, servedModel, modelName, fail := getClaudeChatInterfaceWithFallback(c, request.Model)
 	if fail != nil {
-		common.AbortWithErr(c, http.StatusServiceUnavailable, claude.ErrorToClaudeErr(fail))
+		statusCode, code := providerError(fail)
+		errWithCode := claude.StringErrorWrapper(fail.Error(), code, statusCode, true)
+		common.AbortWithErr(c, statusCode, &errWithCode.ClaudeError)
 		return
 	}
 
@@ -55,15 +60,17 @@ func RelaycClaudeOnly(c *gin.Context) {
 		return
 	}
 
-	errWithCode, done := RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
+	errWithCode, done := RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel, servedModel)
 
 	if errWithCode == nil {
 		return
 	}
 
+	attempt := 1
 	apiErr := errWithCode.ToOpenAiError()
 
 	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	recordRelayErrorLog(c, channel.Id, servedModel, attempt, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -73,12 +80,15 @@ func RelaycClaudeOnly(c *gin.Context) {
 
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
-		model.ChannelGroup.Cooldowns(channel.Id)
-		chatProvider, modelName, fail := GetClaudeChatInterface(c, originalModel)
+		model.ChannelGroup.Cooldowns(channel.Id, servedModel)
+		metrics.RecordRelayRetry(servedModel, c.GetString("group"), c.FullPath())
+		// 获取失败时保留上一次的通道和模型，避免冷却整个通道
+		nextProvider, nextServedModel, nextModelName, fail := getClaudeChatInterfaceWithFallback(c, originalModel)
 		if fail != nil {
 			continue
 		}
-		request.Model = modelName
+		chatProvider, servedModel = nextProvider, nextServedModel
+		request.Model = nextModelName
 		channel = chatProvider.GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
 
@@ -91,13 +101,16 @@ func RelaycClaudeOnly(c *gin.Context) {
 			}
 		}
 
-		errWithCode, done = RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
+		attempt++
+		c.Set("retry_times", attempt-1)
+		errWithCode, done = RelayClaudeHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel, servedModel)
 		if errWithCode == nil {
 			return
 		}
 
 		apiErr = errWithCode.ToOpenAiError()
 		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		recordRelayErrorLog(c, channel.Id, servedModel, attempt, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -111,7 +124,7 @@ func RelaycClaudeOnly(c *gin.Context) {
 	}
 }
 
-func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.ClaudeChatInterface, cache *relay_util.ChatCacheProps, request *claude.ClaudeRequest, originalModel string) (errWithCode *claude.ClaudeErrorWithStatusCode, done bool) {
+func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.ClaudeChatInterface, cache *relay_util.ChatCacheProps, request *claude.ClaudeRequest, originalModel, servedModel string) (errWithCode *claude.ClaudeErrorWithStatusCode, done bool) {
 
 	usage := &types.Usage{
 		PromptTokens: promptTokens,
@@ -119,17 +132,21 @@ func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.Cl
 	chatProvider.SetUsage(usage)
 
 	var quota *relay_util.Quota
-	quota, err := relay_util.NewQuota(c, request.Model, promptTokens)
+	// 按实际使用的模型计费
+	quota, err := relay_util.NewQuota(c, servedModel, promptTokens)
 	if err != nil {
 		return claude.OpenaiErrToClaudeErr(err), true
 	}
 
+	requestDone := trackChannelRequest(c, chatProvider.GetChannel().Id, servedModel, usage)
 	errWithCode, done = SendClaude(c, chatProvider, cache, request)
 
 	if errWithCode != nil {
+		requestDone(errWithCode.ToOpenAiError())
 		quota.Undo(c)
 		return
 	}
+	requestDone(nil)
 
 	quota.Consume(c, usage)
 	if usage.CompletionTokens > 0 {
@@ -230,15 +247,46 @@ func CountTokenMessages(request *claude.ClaudeRequest, preCostType int) (int, er
 }
 
 func GetClaudeChatInterface(c *gin.Context, modelName string) (claude.ClaudeChatInterface, string, *claude.ClaudeError) {
-	provider, modelName, fail := GetProvider(c, modelName)
+	chatProvider, modelName, fail := getClaudeChatInterface(c, modelName)
 	if fail != nil {
 		return nil, "", claude.ErrorToClaudeErr(fail)
 	}
 
-	chatProvider, ok := provider.(claude.ClaudeChatInterface)
+	return chatProvider, modelName, nil
+}
+
+func getClaudeChatInterface(c *gin.Context, modelName string) (claude.ClaudeChatInterface, string, error) {
+	provider, modelName, fail := GetProvider(c, modelName)
+	if fail != nil {
+		return nil, "", fail
+	}
+
+	if chatProvider, ok := provider.(claude.ClaudeChatInterface); ok {
+		return chatProvider, modelName, nil
+	}
+
+	// 非 Claude 渠道，转换为 OpenAI 格式后再发送
+	chatProvider, ok := provider.(providersBase.ChatInterface)
 	if !ok {
-		return nil, "", claude.ErrorToClaudeErr(errors.New("channel not implemented"))
+		return nil, "", errors.New("channel not implemented")
 	}
 
-	return chatProvider, modelName, nil
+	return claude.NewChatAdapter(chatProvider), modelName, nil
+}
+
+// getClaudeChatInterfaceWithFallback 原模型没有可用渠道时，按备用模型链依次尝试，返回实际使用的模型
+func getClaudeChatInterfaceWithFallback(c *gin.Context, modelName string) (claude.ClaudeChatInterface, string, string, error) {
+	chatProvider, newModelName, fail := getClaudeChatInterface(c, modelName)
+	if fail == nil || !canFallback(fail) {
+		return chatProvider, modelName, newModelName, fail
+	}
+
+	for _, fallbackModel := range getFallbackModels(c, modelName) {
+		if fallbackProvider, fallbackModelName, err := getClaudeChatInterface(c, fallbackModel); err == nil {
+			logModelFallback(c, modelName, fallbackModel)
+			return fallbackProvider, fallbackModel, fallbackModelName, nil
+		}
+	}
+
+	return nil, "", "", fail
 }
@@ -11,6 +11,7 @@ import (
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/requester"
+	"one-api/common/telemetry"
 	"one-api/common/utils"
 	"one-api/controller"
 	"one-api/model"
@@ -21,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
@@ -29,11 +31,15 @@ func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
 	if strings.HasPrefix(path, "/v1/chat/completions") {
 		allowCache = true
 		relay = NewRelayChat(c)
+	} else if strings.HasPrefix(path, "/v1/responses") {
+		relay = NewRelayResponses(c)
 	} else if strings.HasPrefix(path, "/v1/completions") {
 		allowCache = true
 		relay = NewRelayCompletions(c)
 	} else if strings.HasPrefix(path, "/v1/embeddings") {
 		relay = NewRelayEmbeddings(c)
+	} else if strings.HasPrefix(path, "/v1/rerank") {
+		relay = NewRelayRerank(c)
 	} else if strings.HasPrefix(path, "/v1/moderations") {
 		relay = NewRelayModerations(c)
 	} else if strings.HasPrefix(path, "/v1/images/generations") {
@@ -57,7 +63,29 @@ func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
 	return relay
 }
 
+var errModelNotAllowed = errors.New("该令牌无权使用模型")
+
+// noChannelError 分组下没有可用渠道，只有这种错误会尝试备用模型
+type noChannelError struct {
+	message string
+}
+
+func (e *noChannelError) Error() string {
+	return e.message
+}
+
 func GetProvider(c *gin.Context, modeName string) (provider providersBase.ProviderInterface, newModelName string, fail error) {
+	_, span := telemetry.StartSpan(c.Request.Context(), "relay.select_channel", attribute.String("model", modeName))
+	defer func() {
+		span.SetAttributes(attribute.Int("channel_id", c.GetInt("channel_id")))
+		telemetry.EndSpan(span, fail)
+	}()
+
+	if modeName != "" && !model.IsModelAllowed(c.GetStringSlice("token_models"), modeName) {
+		fail = fmt.Errorf("%w %s", errModelNotAllowed, modeName)
+		return
+	}
+
 	channel, fail := fetchChannel(c, modeName)
 	if fail != nil {
 		return
@@ -76,6 +104,7 @@ func GetProvider(c *gin.Context, modeName string) (provider providersBase.Provid
 	if fail != nil {
 		return
 	}
+	c.Set("upstream_model", newModelName)
 
 	return
 }
@@ -113,15 +142,22 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 	if skipChannelId > 0 {
 		filters = append(filters, model.FilterChannelId(skipChannelId))
 	}
+	if channelTypes, ok := c.Get("allow_channel_type"); ok {
+		filters = append(filters, model.FilterChannelTypes(channelTypes.([]int)))
+	}
 
-	channel, err := model.ChannelGroup.Next(group, modelName, filters...)
+	// 渠道都达到并发、RPM、TPM 限制时短暂排队
+	channel, reservation, err := model.ChannelGroup.WaitNext(c.Request.Context(), c.GetString("channel_affinity_key"), group, modelName, filters...)
+	setChannelReservation(c, reservation)
 	if err != nil {
-		message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)
+		if errors.Is(err, model.ErrChannelBusy) {
+			return nil, &noChannelError{"当前分组上游负载已饱和，请稍后再试"}
+		}
 		if channel != nil {
 			logger.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
-			message = "数据库一致性已被破坏，请联系管理员"
+			return nil, errors.New("数据库一致性已被破坏，请联系管理员")
 		}
-		return nil, errors.New(message)
+		return nil, &noChannelError{fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)}
 	}
 
 	return channel, nil
@@ -147,6 +183,12 @@ func responseJsonClient(c *gin.Context, data interface{}) *types.OpenAIErrorWith
 type StreamEndHandler func() string
 
 func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler) (errWithOP *types.OpenAIErrorWithStatusCode) {
+	_, span := telemetry.StartSpan(c.Request.Context(), "relay.stream")
+	var streamErr error
+	defer func() {
+		telemetry.EndSpan(span, streamErr)
+	}()
+
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
 
@@ -154,12 +196,14 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 	c.Stream(func(w io.Writer) bool {
 		select {
 		case data := <-dataChan:
+			markFirstResponse(c)
 			streamData := "data: " + data + "\n\n"
 			fmt.Fprint(w, streamData)
 			cache.SetResponse(streamData)
 			return true
 		case err := <-errChan:
 			if !errors.Is(err, io.EOF) {
+				streamErr = err
 				fmt.Fprint(w, "data: "+err.Error()+"\n\n")
 				errWithOP = common.ErrorWrapper(err, "stream_error", http.StatusInternalServerError)
 				// 报错不应该缓存
@@ -185,6 +229,12 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 }
 
 func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler) {
+	_, span := telemetry.StartSpan(c.Request.Context(), "relay.stream")
+	var streamErr error
+	defer func() {
+		telemetry.EndSpan(span, streamErr)
+	}()
+
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
 
@@ -192,11 +242,13 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 	c.Stream(func(w io.Writer) bool {
 		select {
 		case data := <-dataChan:
+			markFirstResponse(c)
 			fmt.Fprint(w, data)
 			cache.SetResponse(data)
 			return true
 		case err := <-errChan:
 			if !errors.Is(err, io.EOF) {
+				streamErr = err
 				fmt.Fprint(w, err.Error())
 				logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
 				// 报错不应该缓存
@@ -317,6 +369,12 @@ func processChannelRelayError(ctx context.Context, channelId int, channelName st
 	}
 }
 
+// recordRelayErrorLog 记录失败的请求，attempt 为第几次尝试
+func recordRelayErrorLog(c *gin.Context, channelId int, modelName string, attempt int, err *types.OpenAIErrorWithStatusCode) {
+	content := fmt.Sprintf("第 %d 次请求失败，状态码 %d，错误类型 %s：%s", attempt, err.StatusCode, err.Type, err.Message)
+	go model.RecordErrorLog(c.Request.Context(), c.GetInt("id"), c.GetInt("organization_id"), channelId, relay_util.GetLogRequestDetails(c), modelName, c.GetString("token_name"), err.StatusCode, content)
+}
+
 func relayResponseWithErr(c *gin.Context, err *types.OpenAIErrorWithStatusCode) {
 	requestId := c.GetString(logger.RequestIdKey)
 	err.OpenAIError.Message = utils.MessageWithRequestId(err.OpenAIError.Message, requestId)
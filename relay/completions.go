@@ -40,6 +40,7 @@ func (r *relayCompletions) setRequest() error {
 	}
 
 	r.originalModel = r.request.Model
+	setChannelAffinity(r.c, r.request.User, nil)
 
 	return nil
 }
@@ -0,0 +1,69 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getFallbackModels 分组配置的备用模型链
+func getFallbackModels(c *gin.Context, modelName string) []string {
+	return model.GetModelFallbacks(c.GetString("group"), modelName)
+}
+
+func logModelFallback(c *gin.Context, modelName, fallbackModel string) {
+	logger.LogWarn(c.Request.Context(), fmt.Sprintf("model %s has no available channel, fallback to %s", modelName, fallbackModel))
+}
+
+const modelNotAllowedCode = "model_not_allowed"
+
+// canFallback 只有没有可用渠道时才尝试备用模型，令牌无权使用模型等错误直接返回
+func canFallback(err error) bool {
+	var noChannel *noChannelError
+	return errors.As(err, &noChannel)
+}
+
+// providerError 令牌无权使用模型时返回 403，其他获取渠道失败的情况返回 503
+func providerError(err error) (statusCode int, code string) {
+	if errors.Is(err, errModelNotAllowed) {
+		return http.StatusForbidden, modelNotAllowedCode
+	}
+
+	return http.StatusServiceUnavailable, "internal_error"
+}
+
+func abortWithProviderError(c *gin.Context, err error) {
+	statusCode, code := providerError(err)
+	if statusCode == http.StatusForbidden {
+		relayResponseWithErr(c, common.StringErrorWrapperLocal(err.Error(), code, statusCode))
+		c.Abort()
+		return
+	}
+
+	common.AbortWithMessage(c, statusCode, err.Error())
+}
+
+// setProviderWithFallback 原模型没有可用渠道时，按备用模型链依次尝试
+func setProviderWithFallback(relay RelayBaseInterface) error {
+	c := relay.getContext()
+	originalModel := relay.getOriginalModel()
+
+	err := relay.setProvider(originalModel)
+	if err == nil || !canFallback(err) {
+		return err
+	}
+
+	for _, fallbackModel := range getFallbackModels(c, originalModel) {
+		if relay.setProvider(fallbackModel) == nil {
+			logModelFallback(c, originalModel, fallbackModel)
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanFallback(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+		status   int
+	}{
+		{"no channel", &noChannelError{"当前分组 default 下对于模型 gpt-4o 无可用渠道"}, true, http.StatusServiceUnavailable},
+		{"model not allowed", fmt.Errorf("%w %s", errModelNotAllowed, "gpt-4o"), false, http.StatusForbidden},
+		{"other error", errors.New("数据库一致性已被破坏，请联系管理员"), false, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, canFallback(tc.err))
+			statusCode, _ := providerError(tc.err)
+			assert.Equal(t, tc.status, statusCode)
+		})
+	}
+}
+
+func TestGetProviderModelNotAllowed(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set("token_models", []string{"gpt-4o-mini"})
+
+	_, _, err := GetProvider(c, "gpt-4o")
+	assert.ErrorIs(t, err, errModelNotAllowed)
+	assert.EqualError(t, err, "该令牌无权使用模型 gpt-4o")
+	assert.False(t, canFallback(err))
+
+	// 备用模型链不会绕过令牌的模型限制
+	_, _, _, err = getClaudeChatInterfaceWithFallback(c, "gpt-4o")
+	assert.ErrorIs(t, err, errModelNotAllowed)
+}
@@ -9,8 +9,10 @@ import (
 	"one-api/common/config"
 	"one-api/common/image"
 	"one-api/common/logger"
+	"one-api/common/metrics"
 	"one-api/common/requester"
 	"one-api/model"
+	providersBase "one-api/providers/base"
 	"one-api/providers/gemini"
 	"one-api/relay/relay_util"
 	"one-api/types"
@@ -19,8 +21,6 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-var AllowGeminiChannelType = []int{config.ChannelTypeGemini}
-
 func RelaycGeminiOnly(c *gin.Context) {
 	modelAction := c.Param("model")
 	if modelAction == "" {
@@ -48,8 +48,7 @@ func RelaycGeminiOnly(c *gin.Context) {
 
 	request.Model = modelList[0]
 	request.Stream = isStream
-
-	c.Set("allow_channel_type", AllowGeminiChannelType)
+	setChannelAffinity(c, "", []any{request.SystemInstruction, getLeadingMessages(request.Contents)})
 
 	cacheProps := relay_util.NewChatCacheProps(c, true)
 	cacheProps.SetHash(request)
@@ -62,9 +61,12 @@ func RelaycGeminiOnly(c *gin.Context) {
 		return
 	}
 
-	chatProvider, modelName, fail := GetGeminiChatInterface(c, request.Model)
+	chatProvider, servedModel, modelName, fail := getGeminiChatInterfaceWithFallback(c, request.Model)
 	if fail != nil {
-		common.AbortWithErr(c, http.StatusServiceUnavailable, fail)
+		statusCode, code := providerError(fail)
+		errWithCode := gemini.StringErrorWrapper(fail.Error(), code, statusCode, true)
+		errWithCode.ErrorInfo.Code = statusCode
+		common.AbortWithErr(c, statusCode, &errWithCode.GeminiErrorResponse)
 		return
 	}
 
@@ -80,15 +82,17 @@ func RelaycGeminiOnly(c *gin.Context) {
 		return
 	}
 
-	errWithCode, done := RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
+	errWithCode, done := RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel, servedModel)
 
 	if errWithCode == nil {
 		return
 	}
 
+	attempt := 1
 	apiErr := errWithCode.ToOpenAiError()
 
 	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	recordRelayErrorLog(c, channel.Id, servedModel, attempt, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -98,12 +102,15 @@ func RelaycGeminiOnly(c *gin.Context) {
 
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
-		model.ChannelGroup.Cooldowns(channel.Id)
-		chatProvider, modelName, fail := GetGeminiChatInterface(c, originalModel)
+		model.ChannelGroup.Cooldowns(channel.Id, servedModel)
+		metrics.RecordRelayRetry(servedModel, c.GetString("group"), c.FullPath())
+		// 获取失败时保留上一次的通道和模型，避免冷却整个通道
+		nextProvider, nextServedModel, nextModelName, fail := getGeminiChatInterfaceWithFallback(c, originalModel)
 		if fail != nil {
 			continue
 		}
-		request.Model = modelName
+		chatProvider, servedModel = nextProvider, nextServedModel
+		request.Model = nextModelName
 		channel = chatProvider.GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
 
@@ -116,13 +123,16 @@ func RelaycGeminiOnly(c *gin.Context) {
 			}
 		}
 
-		errWithCode, done = RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel)
+		attempt++
+		c.Set("retry_times", attempt-1)
+		errWithCode, done = RelayGeminiHandler(c, promptTokens, chatProvider, cacheProps, request, originalModel, servedModel)
 		if errWithCode == nil {
 			return
 		}
 
 		apiErr = errWithCode.ToOpenAiError()
 		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		recordRelayErrorLog(c, channel.Id, servedModel, attempt, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -136,7 +146,7 @@ func RelaycGeminiOnly(c *gin.Context) {
 	}
 }
 
-func RelayGeminiHandler(c *gin.Context, promptTokens int, chatProvider gemini.GeminiChatInterface, cache *relay_util.ChatCacheProps, request *gemini.GeminiChatRequest, originalModel string) (errWithCode *gemini.GeminiErrorWithStatusCode, done bool) {
+func RelayGeminiHandler(c *gin.Context, promptTokens int, chatProvider gemini.GeminiChatInterface, cache *relay_util.ChatCacheProps, request *gemini.GeminiChatRequest, originalModel, servedModel string) (errWithCode *gemini.GeminiErrorWithStatusCode, done bool) {
 
 	usage := &types.Usage{
 		PromptTokens: promptTokens,
@@ -144,17 +154,21 @@ func RelayGeminiHandler(c *gin.Context, promptTokens int, chatProvider gemini.Ge
 	chatProvider.SetUsage(usage)
 
 	var quota *relay_util.Quota
-	quota, err := relay_util.NewQuota(c, request.Model, promptTokens)
+	// 按实际使用的模型计费
+	quota, err := relay_util.NewQuota(c, servedModel, promptTokens)
 	if err != nil {
 		return gemini.OpenaiErrToGeminiErr(err), true
 	}
 
+	requestDone := trackChannelRequest(c, chatProvider.GetChannel().Id, servedModel, usage)
 	errWithCode, done = SendGemini(c, chatProvider, cache, request)
 
 	if errWithCode != nil {
+		requestDone(errWithCode.ToOpenAiError())
 		quota.Undo(c)
 		return
 	}
+	requestDone(nil)
 
 	quota.Consume(c, usage)
 	if usage.CompletionTokens > 0 {
@@ -235,16 +249,46 @@ func CountGeminiTokenMessages(request *gemini.GeminiChatRequest, preCostType int
 }
 
 func GetGeminiChatInterface(c *gin.Context, modelName string) (gemini.GeminiChatInterface, string, *gemini.GeminiErrorResponse) {
-	fmt.Println(modelName)
-	provider, modelName, fail := GetProvider(c, modelName)
+	chatProvider, modelName, fail := getGeminiChatInterface(c, modelName)
 	if fail != nil {
 		return nil, "", gemini.ErrorToGeminiErr(fail)
 	}
 
-	chatProvider, ok := provider.(gemini.GeminiChatInterface)
+	return chatProvider, modelName, nil
+}
+
+func getGeminiChatInterface(c *gin.Context, modelName string) (gemini.GeminiChatInterface, string, error) {
+	provider, modelName, fail := GetProvider(c, modelName)
+	if fail != nil {
+		return nil, "", fail
+	}
+
+	if chatProvider, ok := provider.(gemini.GeminiChatInterface); ok {
+		return chatProvider, modelName, nil
+	}
+
+	// 非 Gemini 渠道，转换为 OpenAI 格式后再发送
+	chatProvider, ok := provider.(providersBase.ChatInterface)
 	if !ok {
-		return nil, "", gemini.ErrorToGeminiErr(errors.New("channel not implemented"))
+		return nil, "", errors.New("channel not implemented")
 	}
 
-	return chatProvider, modelName, nil
+	return gemini.NewChatAdapter(chatProvider), modelName, nil
+}
+
+// getGeminiChatInterfaceWithFallback 原模型没有可用渠道时，按备用模型链依次尝试，返回实际使用的模型
+func getGeminiChatInterfaceWithFallback(c *gin.Context, modelName string) (gemini.GeminiChatInterface, string, string, error) {
+	chatProvider, newModelName, fail := getGeminiChatInterface(c, modelName)
+	if fail == nil || !canFallback(fail) {
+		return chatProvider, modelName, newModelName, fail
+	}
+
+	for _, fallbackModel := range getFallbackModels(c, modelName) {
+		if fallbackProvider, fallbackModelName, err := getGeminiChatInterface(c, fallbackModel); err == nil {
+			logModelFallback(c, modelName, fallbackModel)
+			return fallbackProvider, fallbackModel, fallbackModelName, nil
+		}
+	}
+
+	return nil, "", "", fail
 }
@@ -6,12 +6,15 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/telemetry"
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Relay(c *gin.Context) {
@@ -38,8 +41,8 @@ func Relay(c *gin.Context) {
 		return
 	}
 
-	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
-		common.AbortWithMessage(c, http.StatusServiceUnavailable, err.Error())
+	if err := setProviderWithFallback(relay); err != nil {
+		abortWithProviderError(c, err)
 		return
 	}
 
@@ -48,8 +51,10 @@ func Relay(c *gin.Context) {
 		return
 	}
 
+	attempt := 1
 	channel := relay.getProvider().GetChannel()
 	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	recordRelayErrorLog(c, channel.Id, relay.getServedModel(), attempt, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -59,18 +64,22 @@ func Relay(c *gin.Context) {
 
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
-		model.ChannelGroup.Cooldowns(channel.Id)
-		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		model.ChannelGroup.Cooldowns(channel.Id, relay.getServedModel())
+		metrics.RecordRelayRetry(relay.getServedModel(), c.GetString("group"), c.FullPath())
+		if err := setProviderWithFallback(relay); err != nil {
 			continue
 		}
 
 		channel = relay.getProvider().GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
+		attempt++
+		c.Set("retry_times", attempt-1)
 		apiErr, done = RelayHandler(relay)
 		if apiErr == nil {
 			return
 		}
 		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		recordRelayErrorLog(c, channel.Id, relay.getServedModel(), attempt, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -85,7 +94,10 @@ func Relay(c *gin.Context) {
 }
 
 func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	_, span := telemetry.StartSpan(relay.getContext().Request.Context(), "relay.count_tokens")
 	promptTokens, tonkeErr := relay.getPromptTokens()
+	span.SetAttributes(attribute.Int("prompt_tokens", promptTokens))
+	telemetry.EndSpan(span, tonkeErr)
 	if tonkeErr != nil {
 		err = common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest)
 		done = true
@@ -99,13 +111,16 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 	relay.getProvider().SetUsage(usage)
 
 	var quota *relay_util.Quota
-	quota, err = relay_util.NewQuota(relay.getContext(), relay.getOriginalModel(), promptTokens)
+	// 按实际使用的模型计费
+	quota, err = relay_util.NewQuota(relay.getContext(), relay.getServedModel(), promptTokens)
 	if err != nil {
 		done = true
 		return
 	}
 
+	requestDone := trackChannelRequest(relay.getContext(), relay.getProvider().GetChannel().Id, relay.getServedModel(), usage)
 	err, done = relay.send()
+	requestDone(err)
 
 	if err != nil {
 		quota.Undo(relay.getContext())
@@ -136,5 +151,7 @@ func cacheProcessing(c *gin.Context, cacheProps *relay_util.ChatCacheProps, isSt
 		}
 	}
 
-	model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, "缓存", requestTime)
+	requestDetails := relay_util.GetLogRequestDetails(c)
+	requestDetails.IsStream = isStream
+	model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, c.GetInt("organization_id"), cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, model.LogTokensDetails{}, requestDetails, cacheProps.ModelName, tokenName, 0, "缓存", requestTime)
 }
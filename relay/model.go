@@ -67,8 +67,13 @@ func ListModels(c *gin.Context) {
 	}
 	sort.Strings(models)
 
+	tokenModels := c.GetStringSlice("token_models")
 	var groupOpenAIModels []*OpenAIModels
 	for _, modelName := range models {
+		// 只列出令牌允许使用的模型
+		if !model.IsModelAllowed(tokenModels, modelName) {
+			continue
+		}
 		groupOpenAIModels = append(groupOpenAIModels, getOpenAIModelWithName(modelName))
 	}
 
@@ -123,7 +128,8 @@ func ListModelsForAdmin(c *gin.Context) {
 func RetrieveModel(c *gin.Context) {
 	modelName := c.Param("model")
 	openaiModel := getOpenAIModelWithName(modelName)
-	if *openaiModel.OwnedBy != relay_util.UnknownOwnedBy {
+	allowed := model.IsModelAllowed(c.GetStringSlice("token_models"), modelName)
+	if allowed && *openaiModel.OwnedBy != relay_util.UnknownOwnedBy {
 		c.JSON(200, openaiModel)
 	} else {
 		openAIError := types.OpenAIError{
@@ -0,0 +1,217 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var AllowRealtimeChannelType = []int{config.ChannelTypeOpenAI, config.ChannelTypeAzure}
+
+var realtimeUpgrader = websocket.Upgrader{
+	Subprotocols: []string{"realtime"},
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+func RelayRealtime(c *gin.Context) {
+	modelName := c.Query("model")
+	if modelName == "" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	c.Set("allow_channel_type", AllowRealtimeChannelType)
+
+	serverConn, errWithCode := getRealtimeConn(c, modelName)
+	if errWithCode != nil {
+		common.AbortWithMessage(c, errWithCode.StatusCode, errWithCode.Message)
+		return
+	}
+
+	// 预扣费，余额不足时直接拒绝
+	quota, errWithCode := relay_util.NewQuota(c, modelName, 0)
+	if errWithCode != nil {
+		serverConn.Close()
+		common.AbortWithMessage(c, errWithCode.StatusCode, errWithCode.Message)
+		return
+	}
+
+	clientConn, err := realtimeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		serverConn.Close()
+		quota.Undo(c)
+		logger.LogError(c.Request.Context(), "realtime upgrade failed: "+err.Error())
+		return
+	}
+
+	session := &realtimeSession{
+		c:          c,
+		modelName:  modelName,
+		clientConn: clientConn,
+		serverConn: serverConn,
+		quota:      quota,
+	}
+	session.run()
+}
+
+func getRealtimeConn(c *gin.Context, modelName string) (*websocket.Conn, *types.OpenAIErrorWithStatusCode) {
+	var errWithCode *types.OpenAIErrorWithStatusCode
+
+	for i := config.RetryTimes; i >= 0; i-- {
+		provider, newModelName, fail := GetProvider(c, modelName)
+		if fail != nil {
+			return nil, common.ErrorWrapperLocal(fail, "get_channel_failed", http.StatusServiceUnavailable)
+		}
+
+		realtimeProvider, ok := provider.(providersBase.RealtimeInterface)
+		if !ok {
+			return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		}
+
+		var conn *websocket.Conn
+		conn, errWithCode = realtimeProvider.CreateRealtimeConn(newModelName)
+		if errWithCode == nil {
+			return conn, nil
+		}
+
+		channel := provider.GetChannel()
+		logger.LogError(c.Request.Context(), fmt.Sprintf("realtime channel #%d(%s) connect failed: %s (remain times %d)", channel.Id, channel.Name, errWithCode.Message, i))
+		// 冻结通道
+		model.ChannelGroup.Cooldowns(channel.Id, modelName)
+	}
+
+	return nil, errWithCode
+}
+
+// realtimeSession 一个客户端连接对应一个会话，按每次 response.done 的用量计费
+type realtimeSession struct {
+	c          *gin.Context
+	modelName  string
+	clientConn *websocket.Conn
+	serverConn *websocket.Conn
+
+	quota     *relay_util.Quota
+	usage     types.Usage
+	closeOnce sync.Once
+}
+
+func (s *realtimeSession) run() {
+	done := make(chan struct{}, 2)
+
+	go func() {
+		s.forwardClientMessages()
+		done <- struct{}{}
+	}()
+	go func() {
+		s.forwardServerMessages()
+		done <- struct{}{}
+	}()
+
+	// 任意一端断开后关闭两端连接，并等待另一端退出
+	<-done
+	s.close()
+	<-done
+
+	// 退回最后一次未使用的预扣费
+	if s.quota != nil {
+		s.quota.Undo(s.c)
+	}
+
+	logger.LogInfo(s.c.Request.Context(), fmt.Sprintf("realtime session closed, model: %s, prompt tokens: %d, completion tokens: %d", s.modelName, s.usage.PromptTokens, s.usage.CompletionTokens))
+}
+
+func (s *realtimeSession) close() {
+	s.closeOnce.Do(func() {
+		s.clientConn.Close()
+		s.serverConn.Close()
+	})
+}
+
+func (s *realtimeSession) forwardClientMessages() {
+	for {
+		messageType, message, err := s.clientConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := s.serverConn.WriteMessage(messageType, message); err != nil {
+			return
+		}
+	}
+}
+
+func (s *realtimeSession) forwardServerMessages() {
+	for {
+		messageType, message, err := s.serverConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := s.clientConn.WriteMessage(messageType, message); err != nil {
+			return
+		}
+
+		// 音频数据量大，只解析 response.done 事件
+		if messageType != websocket.TextMessage || !bytes.Contains(message, []byte(types.RealtimeEventTypeResponseDone)) {
+			continue
+		}
+
+		var event types.RealtimeEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			continue
+		}
+
+		if event.Type != types.RealtimeEventTypeResponseDone || event.Response == nil || event.Response.Usage == nil {
+			continue
+		}
+
+		if errWithCode := s.consume(event.Response.Usage); errWithCode != nil {
+			s.sendError(errWithCode)
+			return
+		}
+	}
+}
+
+// consume 结算本次响应，并为下一次响应预扣费
+func (s *realtimeSession) consume(realtimeUsage *types.RealtimeUsage) *types.OpenAIErrorWithStatusCode {
+	usage := realtimeUsage.ToOpenAIUsage()
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += usage.TotalTokens
+
+	if s.quota == nil {
+		return common.ErrorWrapperLocal(errors.New("quota not found"), "quota_error", http.StatusInternalServerError)
+	}
+	s.quota.Consume(s.c, usage)
+
+	quota, errWithCode := relay_util.NewQuota(s.c, s.modelName, 0)
+	s.quota = quota
+
+	return errWithCode
+}
+
+func (s *realtimeSession) sendError(errWithCode *types.OpenAIErrorWithStatusCode) {
+	event := types.RealtimeEvent{
+		Type:  types.RealtimeEventTypeError,
+		Error: &errWithCode.OpenAIError,
+	}
+
+	if err := s.clientConn.WriteJSON(event); err != nil {
+		logger.LogError(s.c.Request.Context(), "realtime send error failed: "+err.Error())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"one-api/model"
 	"one-api/providers/azure"
 	"one-api/providers/openai"
+	"one-api/relay/relay_util"
 	"strings"
 	"time"
 
@@ -81,6 +82,6 @@ func RelayOnly(c *gin.Context) {
 			requestTime = int(time.Since(requestStartTime).Milliseconds())
 		}
 	}
-	model.RecordConsumeLog(c.Request.Context(), c.GetInt("id"), c.GetInt("channel_id"), 0, 0, "", c.GetString("token_name"), 0, "中继:"+path, requestTime)
+	model.RecordConsumeLog(c.Request.Context(), c.GetInt("id"), c.GetInt("organization_id"), c.GetInt("channel_id"), 0, 0, model.LogTokensDetails{}, relay_util.GetLogRequestDetails(c), "", c.GetString("token_name"), 0, "中继:"+path, requestTime)
 
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/utils"
 
@@ -22,6 +23,8 @@ type ChatCacheProps struct {
 	Hash   string      `json:"-"`
 	Cache  bool        `json:"-"`
 	Driver CacheDriver `json:"-"`
+
+	groupRatio float64
 }
 
 type CacheDriver interface {
@@ -50,6 +53,12 @@ func NewChatCacheProps(c *gin.Context, allow bool) *ChatCacheProps {
 
 	props.UserId = c.GetInt("id")
 	props.TokenId = c.GetInt("token_id")
+	props.groupRatio = common.GetGroupRatio(c.GetString("group"))
+
+	// 令牌设置了相似度阈值时，在精确缓存未命中后再按语义查找
+	if threshold := c.GetFloat64("chat_cache_threshold"); config.ChatCacheSemanticEnabled && threshold > 0 {
+		props.Driver = NewChatCacheSemantic(c, props.Driver, threshold)
+	}
 
 	return props
 }
@@ -60,6 +69,10 @@ func (p *ChatCacheProps) SetHash(request any) {
 	}
 
 	p.hash(utils.Marshal(request))
+
+	if semantic, ok := p.Driver.(*ChatCacheSemantic); ok {
+		semantic.SetRequest(p, request)
+	}
 }
 
 func (p *ChatCacheProps) SetResponse(response any) {
@@ -102,7 +115,16 @@ func (p *ChatCacheProps) GetCache() *ChatCacheProps {
 		return nil
 	}
 
-	return p.Driver.Get(p.getHash(), p.UserId)
+	cache := p.Driver.Get(p.getHash(), p.UserId)
+	if cache == nil {
+		recordChatCacheStats(false, 0)
+		return nil
+	}
+
+	cache.groupRatio = p.groupRatio
+	recordChatCacheStats(true, cache.getSavedQuota())
+
+	return cache
 }
 
 func (p *ChatCacheProps) needCache() bool {
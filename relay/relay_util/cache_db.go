@@ -39,6 +39,7 @@ func SetCacheDB(hash string, props *ChatCacheProps, expire int64) error {
 	cache := &model.ChatCache{
 		Hash:       hash,
 		UserId:     props.UserId,
+		ModelName:  props.ModelName,
 		Data:       data,
 		Expiration: expire,
 	}
@@ -0,0 +1,263 @@
+package relay_util
+
+import (
+	"context"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/metrics"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"one-api/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ChatCacheItem 管理接口中展示的缓存内容
+type ChatCacheItem struct {
+	Hash             string `json:"hash"`
+	UserId           int    `json:"user_id"`
+	TokenId          int    `json:"token_id"`
+	ChannelID        int    `json:"channel_id"`
+	ModelName        string `json:"model_name"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Response         string `json:"response"`
+	Expiration       int64  `json:"expiration"`
+}
+
+// ChatCacheManager 管理员查看和清理缓存，DB 和 Redis 驱动各自实现
+type ChatCacheManager interface {
+	List(params *model.SearchChatCacheParams) (*model.DataResult[ChatCacheItem], error)
+	Delete(hash string) (int64, error)
+	DeleteByUser(userId int) (int64, error)
+	Flush() (int64, error)
+}
+
+func GetChatCacheManager() ChatCacheManager {
+	if config.RedisEnabled {
+		return &ChatCacheRedis{}
+	}
+	return &ChatCacheDB{}
+}
+
+func (db *ChatCacheDB) List(params *model.SearchChatCacheParams) (*model.DataResult[ChatCacheItem], error) {
+	caches, err := model.GetChatCacheList(params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*ChatCacheItem, 0, len(*caches.Data))
+	for _, cache := range *caches.Data {
+		props, err := utils.UnmarshalString[ChatCacheProps](cache.Data)
+		if err != nil {
+			continue
+		}
+		items = append(items, newChatCacheItem(cache.Hash, &props, cache.Expiration))
+	}
+
+	return &model.DataResult[ChatCacheItem]{
+		Data:       &items,
+		Page:       caches.Page,
+		Size:       caches.Size,
+		TotalCount: caches.TotalCount,
+	}, nil
+}
+
+func (db *ChatCacheDB) Delete(hash string) (int64, error) {
+	return model.DeleteChatCacheByHash(hash)
+}
+
+func (db *ChatCacheDB) DeleteByUser(userId int) (int64, error) {
+	return model.DeleteChatCacheByUserId(userId)
+}
+
+func (db *ChatCacheDB) Flush() (int64, error) {
+	return model.FlushChatCache()
+}
+
+// List Redis 中没有索引，遍历 key 之后在内存中过滤和分页
+func (r *ChatCacheRedis) List(params *model.SearchChatCacheParams) (*model.DataResult[ChatCacheItem], error) {
+	pattern := fmt.Sprintf("%s:*", chatCacheKey)
+	if params.UserId != 0 {
+		pattern = fmt.Sprintf("%s:%d:*", chatCacheKey, params.UserId)
+	}
+
+	keys, err := redis.RedisScanKeys(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	ctx := context.Background()
+	items := make([]*ChatCacheItem, 0)
+	for start := 0; start < len(keys); start += 100 {
+		end := min(start+100, len(keys))
+		values, err := redis.RDB.MGet(ctx, keys[start:end]...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+			props, err := utils.UnmarshalString[ChatCacheProps](data)
+			if err != nil {
+				continue
+			}
+			if params.ModelName != "" && props.ModelName != params.ModelName {
+				continue
+			}
+			items = append(items, newChatCacheItem(r.getHash(keys[start+i]), &props, 0))
+		}
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Size < 1 {
+		params.Size = config.ItemsPerPage
+	}
+	if params.Size > config.MaxRecentItems {
+		return nil, fmt.Errorf("size 参数不能超过 %d", config.MaxRecentItems)
+	}
+
+	totalCount := int64(len(items))
+	offset := min((params.Page-1)*params.Size, len(items))
+	items = items[offset:min(offset+params.Size, len(items))]
+
+	for _, item := range items {
+		ttl, err := redis.RDB.TTL(ctx, r.getKey(item.Hash, item.UserId)).Result()
+		if err == nil && ttl > 0 {
+			item.Expiration = time.Now().Add(ttl).Unix()
+		}
+	}
+
+	return &model.DataResult[ChatCacheItem]{
+		Data:       &items,
+		Page:       params.Page,
+		Size:       params.Size,
+		TotalCount: totalCount,
+	}, nil
+}
+
+func (r *ChatCacheRedis) Delete(hash string) (int64, error) {
+	return r.deleteByPattern(fmt.Sprintf("%s:*:%s", chatCacheKey, hash))
+}
+
+func (r *ChatCacheRedis) DeleteByUser(userId int) (int64, error) {
+	return r.deleteByPattern(fmt.Sprintf("%s:%d:*", chatCacheKey, userId))
+}
+
+func (r *ChatCacheRedis) Flush() (int64, error) {
+	return r.deleteByPattern(fmt.Sprintf("%s:*", chatCacheKey))
+}
+
+func (r *ChatCacheRedis) deleteByPattern(pattern string) (int64, error) {
+	keys, err := redis.RedisScanKeys(pattern)
+	if err != nil {
+		return 0, err
+	}
+	return redis.RedisDelKeys(keys...)
+}
+
+func (r *ChatCacheRedis) getHash(key string) string {
+	return key[strings.LastIndex(key, ":")+1:]
+}
+
+func newChatCacheItem(hash string, props *ChatCacheProps, expiration int64) *ChatCacheItem {
+	return &ChatCacheItem{
+		Hash:             hash,
+		UserId:           props.UserId,
+		TokenId:          props.TokenId,
+		ChannelID:        props.ChannelID,
+		ModelName:        props.ModelName,
+		PromptTokens:     props.PromptTokens,
+		CompletionTokens: props.CompletionTokens,
+		Response:         props.Response,
+		Expiration:       expiration,
+	}
+}
+
+// ChatCacheStats 缓存命中统计，开启 Redis 时多个节点共享
+type ChatCacheStats struct {
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	SavedQuota int64   `json:"saved_quota"`
+}
+
+const chatCacheStatsKey = "chat_cache_stats"
+
+var chatCacheHits, chatCacheMisses, chatCacheSavedQuota atomic.Int64
+
+func recordChatCacheStats(hit bool, savedQuota int64) {
+	metrics.RecordChatCache(hit)
+
+	if config.RedisEnabled {
+		ctx := context.Background()
+		pipe := redis.RDB.Pipeline()
+		if hit {
+			pipe.HIncrBy(ctx, chatCacheStatsKey, "hits", 1)
+			pipe.HIncrBy(ctx, chatCacheStatsKey, "saved_quota", savedQuota)
+		} else {
+			pipe.HIncrBy(ctx, chatCacheStatsKey, "misses", 1)
+		}
+		pipe.Exec(ctx)
+		return
+	}
+
+	if hit {
+		chatCacheHits.Add(1)
+		chatCacheSavedQuota.Add(savedQuota)
+	} else {
+		chatCacheMisses.Add(1)
+	}
+}
+
+func GetChatCacheStats() *ChatCacheStats {
+	stats := &ChatCacheStats{}
+	if config.RedisEnabled {
+		values, err := redis.RDB.HGetAll(context.Background(), chatCacheStatsKey).Result()
+		if err == nil {
+			stats.Hits, _ = strconv.ParseInt(values["hits"], 10, 64)
+			stats.Misses, _ = strconv.ParseInt(values["misses"], 10, 64)
+			stats.SavedQuota, _ = strconv.ParseInt(values["saved_quota"], 10, 64)
+		}
+	} else {
+		stats.Hits = chatCacheHits.Load()
+		stats.Misses = chatCacheMisses.Load()
+		stats.SavedQuota = chatCacheSavedQuota.Load()
+	}
+
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+
+	return stats
+}
+
+func ResetChatCacheStats() {
+	if config.RedisEnabled {
+		redis.RedisDel(chatCacheStatsKey)
+		return
+	}
+
+	chatCacheHits.Store(0)
+	chatCacheMisses.Store(0)
+	chatCacheSavedQuota.Store(0)
+}
+
+// getSavedQuota 命中缓存时按原请求的价格计算节省的额度
+func (p *ChatCacheProps) getSavedQuota() int64 {
+	price := PricingInstance.GetPrice(p.ModelName)
+	if price.Type == model.TimesPriceType {
+		return int64(1000 * price.GetInput() * p.groupRatio)
+	}
+
+	return int64(float64(p.PromptTokens)*price.GetInput()*p.groupRatio + float64(p.CompletionTokens)*price.GetOutput()*p.groupRatio)
+}
@@ -0,0 +1,308 @@
+package relay_util
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/telemetry"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/providers"
+	providers_base "one-api/providers/base"
+	"one-api/types"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询向量会阻塞请求，超时后跳过语义查找
+const semanticEmbeddingTimeout = 5 * time.Second
+
+// ChatCacheSemantic 语义缓存
+// 响应内容仍然保存在精确缓存的驱动中，这里只维护最后一条用户消息的向量到缓存 hash 的索引
+// 索引保存在进程内，即使开启了 Redis，多节点部署时每个节点也只能匹配自己写入的缓存
+type ChatCacheSemantic struct {
+	c         *gin.Context
+	driver    CacheDriver
+	threshold float64
+
+	// scope 是除最后一条用户消息外的请求内容，只有模型和参数都相同时才会匹配
+	scope  string
+	text   string
+	vector []float64
+}
+
+func NewChatCacheSemantic(c *gin.Context, driver CacheDriver, threshold float64) *ChatCacheSemantic {
+	return &ChatCacheSemantic{
+		c:         c,
+		driver:    driver,
+		threshold: threshold,
+	}
+}
+
+// SetRequest 目前只支持 OpenAI 格式的对话请求，其他请求只使用精确缓存
+func (s *ChatCacheSemantic) SetRequest(props *ChatCacheProps, request any) {
+	chatRequest, ok := request.(*types.ChatCompletionRequest)
+	if !ok {
+		return
+	}
+
+	index := -1
+	for i := len(chatRequest.Messages) - 1; i >= 0; i-- {
+		if chatRequest.Messages[i].Role == types.ChatMessageRoleUser {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return
+	}
+
+	// 包含图片等非文本内容时无法按语义匹配
+	for _, part := range chatRequest.Messages[index].ParseContent() {
+		if part.Type != types.ContentTypeText {
+			return
+		}
+	}
+
+	text := chatRequest.Messages[index].StringContent()
+	if text == "" {
+		return
+	}
+
+	scopeRequest := *chatRequest
+	scopeRequest.Messages = make([]types.ChatCompletionMessage, len(chatRequest.Messages))
+	copy(scopeRequest.Messages, chatRequest.Messages)
+	scopeRequest.Messages[index].Content = ""
+
+	hash := md5.Sum([]byte(fmt.Sprintf("%d-%d-%s", props.UserId, props.TokenId, utils.Marshal(&scopeRequest))))
+	s.scope = hex.EncodeToString(hash[:])
+	s.text = text
+}
+
+func (s *ChatCacheSemantic) Get(hash string, userId int) *ChatCacheProps {
+	if cache := s.driver.Get(hash, userId); cache != nil {
+		return cache
+	}
+
+	if s.text == "" {
+		return nil
+	}
+
+	vector, err := s.embedding()
+	if err != nil {
+		logger.SysError("semantic cache embedding error: " + err.Error())
+		return nil
+	}
+	s.vector = vector
+
+	cacheHash := semanticIndex.search(s.scope, vector, s.threshold)
+	if cacheHash == "" {
+		return nil
+	}
+
+	return s.driver.Get(cacheHash, userId)
+}
+
+func (s *ChatCacheSemantic) Set(hash string, props *ChatCacheProps, expire int64) error {
+	err := s.driver.Set(hash, props, expire)
+	if err != nil || s.vector == nil {
+		return err
+	}
+
+	semanticIndex.add(s.scope, &semanticCacheEntry{
+		hash:       hash,
+		vector:     s.vector,
+		expiration: time.Now().Unix() + expire*60,
+	})
+
+	return nil
+}
+
+// embedding 查询向量的用量按 embedding 模型计入当前令牌，并记录消费日志
+func (s *ChatCacheSemantic) embedding() ([]float64, error) {
+	channel := model.ChannelGroup.GetChannel(config.ChatCacheEmbeddingChannelId)
+	if channel == nil {
+		return nil, errors.New("embedding channel not found")
+	}
+
+	usage := &types.Usage{
+		PromptTokens: common.CountTokenText(s.text, config.ChatCacheEmbeddingModel),
+	}
+	quota, errWithCode := NewQuota(s.c, config.ChatCacheEmbeddingModel, usage.PromptTokens)
+	if errWithCode != nil {
+		return nil, errors.New(errWithCode.Message)
+	}
+	quota.channelId = channel.Id
+
+	vector, err := getSemanticEmbedding(s.c.Request.Context(), channel, s.text, usage)
+	if err != nil {
+		quota.Undo(s.c)
+		return nil, err
+	}
+	quota.Consume(s.c, usage)
+
+	return vector, nil
+}
+
+func getSemanticEmbedding(ctx context.Context, channel *model.Channel, text string, usage *types.Usage) ([]float64, error) {
+	req, err := http.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+	if err != nil {
+		return nil, err
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+
+	provider := providers.GetProvider(channel, c)
+	if provider == nil {
+		return nil, errors.New("channel not implemented")
+	}
+	embeddingsProvider, ok := provider.(providers_base.EmbeddingsInterface)
+	if !ok {
+		return nil, errors.New("channel not implemented")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(telemetry.DetachContext(ctx), semanticEmbeddingTimeout)
+	defer cancel()
+	provider.GetRequester().Context = timeoutCtx
+
+	modelName, err := provider.ModelMappingHandler(config.ChatCacheEmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingsProvider.SetUsage(usage)
+	response, errWithCode := embeddingsProvider.CreateEmbeddings(&types.EmbeddingRequest{
+		Model: modelName,
+		Input: text,
+	})
+	if errWithCode != nil {
+		return nil, errors.New(errWithCode.Message)
+	}
+	if len(response.Data) == 0 {
+		return nil, errors.New("empty embedding response")
+	}
+
+	return normalizeVector(response.Data[0].Embedding)
+}
+
+// normalizeVector 转换为单位向量，之后的余弦相似度只需要计算点积
+func normalizeVector(embedding any) ([]float64, error) {
+	var vector []float64
+	switch values := embedding.(type) {
+	case []float64:
+		vector = append(vector, values...)
+	case []any:
+		vector = make([]float64, 0, len(values))
+		for _, value := range values {
+			number, ok := value.(float64)
+			if !ok {
+				return nil, errors.New("invalid embedding value")
+			}
+			vector = append(vector, number)
+		}
+	default:
+		return nil, errors.New("unsupported embedding format")
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm == 0 {
+		return nil, errors.New("empty embedding vector")
+	}
+
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+
+	return vector, nil
+}
+
+type semanticCacheEntry struct {
+	hash       string
+	vector     []float64
+	expiration int64
+}
+
+// semanticCacheIndex 进程内的向量索引，按 scope 分组后线性查找，不在节点之间共享
+type semanticCacheIndex struct {
+	sync.RWMutex
+	entries map[string][]*semanticCacheEntry
+	size    int
+}
+
+var semanticIndex = &semanticCacheIndex{
+	entries: make(map[string][]*semanticCacheEntry),
+}
+
+func (i *semanticCacheIndex) search(scope string, vector []float64, threshold float64) string {
+	i.RLock()
+	defer i.RUnlock()
+
+	now := time.Now().Unix()
+	bestHash := ""
+	bestScore := threshold
+	for _, entry := range i.entries[scope] {
+		if entry.expiration <= now || len(entry.vector) != len(vector) {
+			continue
+		}
+
+		var score float64
+		for j := range vector {
+			score += vector[j] * entry.vector[j]
+		}
+		if score >= bestScore {
+			bestScore = score
+			bestHash = entry.hash
+		}
+	}
+
+	return bestHash
+}
+
+func (i *semanticCacheIndex) add(scope string, entry *semanticCacheEntry) {
+	i.Lock()
+	defer i.Unlock()
+
+	if i.size >= config.ChatCacheSemanticMaxEntries {
+		i.removeExpired()
+		// 清理过期数据后仍然已满，不再写入
+		if i.size >= config.ChatCacheSemanticMaxEntries {
+			return
+		}
+	}
+
+	i.entries[scope] = append(i.entries[scope], entry)
+	i.size++
+}
+
+func (i *semanticCacheIndex) removeExpired() {
+	now := time.Now().Unix()
+	for scope, entries := range i.entries {
+		valid := entries[:0]
+		for _, entry := range entries {
+			if entry.expiration > now {
+				valid = append(valid, entry)
+			}
+		}
+		i.size -= len(entries) - len(valid)
+
+		if len(valid) == 0 {
+			delete(i.entries, scope)
+		} else {
+			i.entries[scope] = valid
+		}
+	}
+}
@@ -9,39 +9,75 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/metrics"
+	"one-api/common/telemetry"
 	"one-api/model"
 	"one-api/types"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// postConsumeWG 进行中的扣费和退还，退出时需要等待完成后再停止批量更新
+var postConsumeWG sync.WaitGroup
+
+// WaitPostConsume 等待进行中的扣费完成，超时返回 false
+func WaitPostConsume(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		postConsumeWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 type Quota struct {
 	modelName        string
+	group            string
 	promptTokens     int
 	price            model.Price
 	groupRatio       float64
+	batchDiscount    float64
 	inputRatio       float64
 	preConsumedQuota int
 	userId           int
 	channelId        int
 	tokenId          int
+	organizationId   int
+	tokenBudget      bool
 	HandelStatus     bool
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) (*Quota, *types.OpenAIErrorWithStatusCode) {
 	quota := &Quota{
-		modelName:    modelName,
-		promptTokens: promptTokens,
-		userId:       c.GetInt("id"),
-		channelId:    c.GetInt("channel_id"),
-		tokenId:      c.GetInt("token_id"),
-		HandelStatus: false,
+		modelName:      modelName,
+		group:          c.GetString("group"),
+		promptTokens:   promptTokens,
+		userId:         c.GetInt("id"),
+		channelId:      c.GetInt("channel_id"),
+		tokenId:        c.GetInt("token_id"),
+		organizationId: c.GetInt("organization_id"),
+		tokenBudget:    c.GetBool("token_budget"),
+		HandelStatus:   false,
 	}
 
 	quota.price = *PricingInstance.GetPrice(quota.modelName)
-	quota.groupRatio = common.GetGroupRatio(c.GetString("group"))
-	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
+	quota.groupRatio = common.GetGroupRatio(quota.group)
+	quota.batchDiscount = 1
+	// 批处理请求按折扣计费
+	if c.GetBool("batch_request") {
+		quota.batchDiscount = config.BatchDiscount
+	}
+	quota.inputRatio = quota.price.GetInput() * quota.groupRatio * quota.batchDiscount
 
 	if quota.price.Type == model.TimesPriceType {
 		quota.preConsumedQuota = int(1000 * quota.inputRatio)
@@ -49,10 +85,16 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) (*Quota, *type
 		quota.preConsumedQuota = int(float64(quota.promptTokens)*quota.inputRatio) + config.PreConsumedQuota
 	}
 
+	_, span := telemetry.StartSpan(c.Request.Context(), "relay.pre_consume",
+		attribute.String("model", modelName),
+		attribute.Int("pre_consumed_quota", quota.preConsumedQuota),
+	)
 	errWithCode := quota.preQuotaConsumption()
 	if errWithCode != nil {
+		telemetry.EndSpan(span, errors.New(errWithCode.Message))
 		return nil, errWithCode
 	}
+	span.End()
 
 	return quota, nil
 }
@@ -62,21 +104,41 @@ func (q *Quota) preQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 		return nil
 	}
 
-	userQuota, err := model.CacheGetUserQuota(q.userId)
-	if err != nil {
-		return common.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
-	}
+	var userQuota int
+	var err error
+	// 使用组织额度的令牌从组织额度中扣除
+	if q.organizationId > 0 {
+		userQuota, err = model.CacheGetOrganizationQuota(q.organizationId)
+		if err != nil {
+			return common.ErrorWrapper(err, "get_organization_quota_failed", http.StatusInternalServerError)
+		}
 
-	if userQuota < q.preConsumedQuota {
-		return common.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
-	}
+		if userQuota < q.preConsumedQuota {
+			return common.ErrorWrapper(errors.New("organization quota is not enough"), "insufficient_organization_quota", http.StatusForbidden)
+		}
 
-	err = model.CacheDecreaseUserQuota(q.userId, q.preConsumedQuota)
-	if err != nil {
-		return common.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
+		err = model.CacheDecreaseOrganizationQuota(q.organizationId, q.preConsumedQuota)
+		if err != nil {
+			return common.ErrorWrapper(err, "decrease_organization_quota_failed", http.StatusInternalServerError)
+		}
+	} else {
+		userQuota, err = model.CacheGetUserQuota(q.userId)
+		if err != nil {
+			return common.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+		}
+
+		if userQuota < q.preConsumedQuota {
+			return common.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+		}
+
+		err = model.CacheDecreaseUserQuota(q.userId, q.preConsumedQuota)
+		if err != nil {
+			return common.ErrorWrapper(err, "decrease_user_quota_failed", http.StatusInternalServerError)
+		}
 	}
 
-	if userQuota > 100*q.preConsumedQuota {
+	// 有周期预算的令牌需要每次检查预算，不跳过预扣
+	if !q.tokenBudget && userQuota > 100*q.preConsumedQuota {
 		// in this case, we do not pre-consume quota
 		// because the user has enough quota
 		q.preConsumedQuota = 0
@@ -94,16 +156,41 @@ func (q *Quota) preQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 	return nil
 }
 
-func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string, ctx context.Context) error {
+func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string, requestDetails model.LogRequestDetails, ctx context.Context) error {
 	quota := 0
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
+	tokensDetails := model.LogTokensDetails{
+		CachedTokens:     usage.GetPromptCachedTokens(),
+		CacheWriteTokens: usage.GetPromptCacheWriteTokens(),
+		ReasoningTokens:  usage.GetCompletionReasoningTokens(),
+	}
 
 	if q.price.Type == model.TimesPriceType {
-		quota = int(1000 * q.inputRatio)
+		times := 1
+		// 重排序按搜索次数计费
+		if usage.SearchUnits > 1 {
+			times = usage.SearchUnits
+		}
+		quota = int(1000 * q.inputRatio * float64(times))
 	} else {
-		completionRatio := q.price.GetOutput() * q.groupRatio
-		quota = int(math.Ceil((float64(promptTokens) * q.inputRatio) + (float64(completionTokens) * completionRatio)))
+		completionRatio := q.price.GetOutput() * q.groupRatio * q.batchDiscount
+		// 音频 tokens 单独计价
+		promptAudioTokens := usage.GetPromptAudioTokens()
+		completionAudioTokens := usage.GetCompletionAudioTokens()
+		audioInputRatio := q.price.GetAudioInput() * q.groupRatio * q.batchDiscount
+		audioOutputRatio := q.price.GetAudioOutput() * q.groupRatio * q.batchDiscount
+		// 缓存命中和写入缓存的 tokens 单独计价，推理 tokens 按输出计价
+		cachedInputRatio := q.price.GetCachedInput() * q.groupRatio * q.batchDiscount
+		cacheWriteRatio := q.price.GetCacheWrite() * q.groupRatio * q.batchDiscount
+		textPromptTokens := max(promptTokens-promptAudioTokens-tokensDetails.CachedTokens-tokensDetails.CacheWriteTokens, 0)
+
+		quota = int(math.Ceil((float64(textPromptTokens) * q.inputRatio) +
+			(float64(promptAudioTokens) * audioInputRatio) +
+			(float64(tokensDetails.CachedTokens) * cachedInputRatio) +
+			(float64(tokensDetails.CacheWriteTokens) * cacheWriteRatio) +
+			(float64(completionTokens-completionAudioTokens) * completionRatio) +
+			(float64(completionAudioTokens) * audioOutputRatio)))
 	}
 
 	if q.inputRatio != 0 && quota <= 0 {
@@ -120,7 +207,11 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	if err != nil {
 		return errors.New("error consuming token remain quota: " + err.Error())
 	}
-	err = model.CacheUpdateUserQuota(q.userId)
+	if q.organizationId > 0 {
+		err = model.CacheUpdateOrganizationQuota(q.organizationId)
+	} else {
+		err = model.CacheUpdateUserQuota(q.userId)
+	}
 	if err != nil {
 		return errors.New("error consuming token remain quota: " + err.Error())
 	}
@@ -146,9 +237,16 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	}
 
 	logContent := fmt.Sprintf("模型费率 %s，分组倍率 %.2f", modelRatioStr, q.groupRatio)
-	model.RecordConsumeLog(ctx, q.userId, q.channelId, promptTokens, completionTokens, q.modelName, tokenName, quota, logContent, requestTime)
+	if q.batchDiscount != 1 {
+		logContent += fmt.Sprintf("，批处理折扣 %.2f", q.batchDiscount)
+	}
+	model.RecordConsumeLog(ctx, q.userId, q.organizationId, q.channelId, promptTokens, completionTokens, tokensDetails, requestDetails, q.modelName, tokenName, quota, logContent, requestTime)
 	model.UpdateUserUsedQuotaAndRequestCount(q.userId, quota)
+	if q.organizationId > 0 {
+		model.UpdateOrganizationUsedQuota(q.organizationId, quota)
+	}
 	model.UpdateChannelUsedQuota(q.channelId, quota)
+	metrics.RecordQuotaConsumed(q.channelId, q.modelName, q.group, quota)
 
 	return nil
 }
@@ -156,7 +254,9 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 func (q *Quota) Undo(c *gin.Context) {
 	tokenId := c.GetInt("token_id")
 	if q.HandelStatus {
+		postConsumeWG.Add(1)
 		go func(ctx context.Context) {
+			defer postConsumeWG.Done()
 			// return pre-consumed quota
 			err := model.PostConsumeTokenQuota(tokenId, -q.preConsumedQuota)
 			if err != nil {
@@ -168,15 +268,48 @@ func (q *Quota) Undo(c *gin.Context) {
 
 func (q *Quota) Consume(c *gin.Context, usage *types.Usage) {
 	tokenName := c.GetString("token_name")
+	requestDetails := GetLogRequestDetails(c)
+	// 计入令牌的 TPM 限制
+	c.Set("consumed_tokens", usage.PromptTokens+usage.CompletionTokens)
 	// 如果没有报错，则消费配额
+	postConsumeWG.Add(1)
 	go func(ctx context.Context) {
-		err := q.completedQuotaConsumption(usage, tokenName, ctx)
+		defer postConsumeWG.Done()
+		ctx, span := telemetry.StartSpan(ctx, "relay.post_consume",
+			attribute.Int("prompt_tokens", usage.PromptTokens),
+			attribute.Int("completion_tokens", usage.CompletionTokens),
+		)
+		err := q.completedQuotaConsumption(usage, tokenName, requestDetails, ctx)
 		if err != nil {
 			logger.LogError(ctx, err.Error())
 		}
+		telemetry.EndSpan(span, err)
 	}(c.Request.Context())
 }
 
+// GetLogRequestDetails 获取写入日志的请求信息，需要在响应写入后调用
+func GetLogRequestDetails(c *gin.Context) model.LogRequestDetails {
+	details := model.LogRequestDetails{
+		RequestId:     c.GetString(logger.RequestIdKey),
+		ClientIp:      c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		IsStream:      strings.Contains(c.Writer.Header().Get("Content-Type"), "text/event-stream"),
+		RetryTimes:    c.GetInt("retry_times"),
+		UpstreamModel: c.GetString("upstream_model"),
+	}
+	if len(details.UserAgent) > 255 {
+		details.UserAgent = details.UserAgent[:255]
+	}
+
+	firstResponseTime := c.GetTime("first_response_time")
+	requestStartTime, ok := c.Request.Context().Value("requestStartTime").(time.Time)
+	if !firstResponseTime.IsZero() && ok {
+		details.FirstResponseTime = int(firstResponseTime.Sub(requestStartTime).Milliseconds())
+	}
+
+	return details
+}
+
 func (q *Quota) GetInputRatio() float64 {
 	return q.inputRatio
 }
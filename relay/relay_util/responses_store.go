@@ -0,0 +1,173 @@
+package relay_util
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"one-api/types"
+	"sync"
+	"time"
+)
+
+// Responses API 的会话记录，用于 previous_response_id 续接对话
+type ResponsesHistory struct {
+	UserId   int                           `json:"user_id"`
+	Messages []types.ChatCompletionMessage `json:"messages"`
+}
+
+var responsesHistoryKey = "responses_history"
+
+const responsesHistoryExpire = 24 * time.Hour
+
+const (
+	// 内存中保存的会话记录总大小上限
+	responsesHistoryMaxSize = 64 * 1024 * 1024
+	// 定期清理内存中过期的会话记录
+	responsesHistorySweepInterval = 10 * time.Minute
+)
+
+// 未启用 Redis 时使用内存保存，内存缓存单条容量有限，不适合存放会话记录
+var responsesHistoryMemory = newResponsesHistoryStore(responsesHistoryMaxSize)
+
+func SetResponsesHistory(responseId string, history *ResponsesHistory) error {
+	data := utils.Marshal(history)
+	if data == "" {
+		return errors.New("marshal error")
+	}
+
+	key := getResponsesHistoryKey(responseId, history.UserId)
+	if config.RedisEnabled {
+		return redis.RedisSet(key, data, responsesHistoryExpire)
+	}
+
+	return responsesHistoryMemory.set(key, data, time.Now())
+}
+
+func GetResponsesHistory(responseId string, userId int) (*ResponsesHistory, error) {
+	key := getResponsesHistoryKey(responseId, userId)
+
+	var data string
+	if config.RedisEnabled {
+		cache, err := redis.RedisGet(key)
+		if err != nil {
+			return nil, errors.New("previous response not found")
+		}
+		data = cache
+	} else {
+		cache, ok := responsesHistoryMemory.get(key, time.Now())
+		if !ok {
+			return nil, errors.New("previous response not found")
+		}
+		data = cache
+	}
+
+	history, err := utils.UnmarshalString[ResponsesHistory](data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+func getResponsesHistoryKey(responseId string, userId int) string {
+	return fmt.Sprintf("%s:%d:%s", responsesHistoryKey, userId, responseId)
+}
+
+type responsesHistoryItem struct {
+	key        string
+	data       string
+	expiration time.Time
+}
+
+// responsesHistoryStore 按写入顺序保存会话记录，所有记录有效期相同，最早写入的最先过期
+type responsesHistoryStore struct {
+	sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List
+	size    int
+	maxSize int
+	sweeper sync.Once
+}
+
+func newResponsesHistoryStore(maxSize int) *responsesHistoryStore {
+	return &responsesHistoryStore{
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+	}
+}
+
+// set 总大小超过上限时丢弃最早的记录，过期的记录由定期清理移除
+func (s *responsesHistoryStore) set(key, data string, now time.Time) error {
+	if len(data) > s.maxSize {
+		return errors.New("response history is too large")
+	}
+	s.sweeper.Do(func() {
+		go s.sweep(responsesHistorySweepInterval)
+	})
+
+	s.Lock()
+	defer s.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+	s.items[key] = s.order.PushBack(&responsesHistoryItem{
+		key:        key,
+		data:       data,
+		expiration: now.Add(responsesHistoryExpire),
+	})
+	s.size += len(data)
+
+	for s.size > s.maxSize {
+		s.remove(s.order.Front())
+	}
+
+	return nil
+}
+
+func (s *responsesHistoryStore) sweep(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.removeExpired(time.Now())
+	}
+}
+
+// removeExpired 从队首开始移除，遇到未过期的记录即可停止
+func (s *responsesHistoryStore) removeExpired(now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		if now.Before(elem.Value.(*responsesHistoryItem).expiration) {
+			break
+		}
+		s.remove(elem)
+	}
+}
+
+func (s *responsesHistoryStore) get(key string, now time.Time) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return "", false
+	}
+
+	item := elem.Value.(*responsesHistoryItem)
+	if !now.Before(item.expiration) {
+		s.remove(elem)
+		return "", false
+	}
+
+	return item.data, true
+}
+
+func (s *responsesHistoryStore) remove(elem *list.Element) {
+	item := s.order.Remove(elem).(*responsesHistoryItem)
+	delete(s.items, item.key)
+	s.size -= len(item.data)
+}
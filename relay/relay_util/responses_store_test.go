@@ -0,0 +1,75 @@
+package relay_util
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponsesHistoryStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	cases := []struct {
+		name    string
+		writes  []string
+		at      time.Duration
+		present []string
+		size    int
+	}{
+		{
+			name:    "within the limit",
+			writes:  []string{"a", "b", "c"},
+			present: []string{"a", "b", "c"},
+			size:    12,
+		},
+		{
+			name:    "oldest is dropped over the limit",
+			writes:  []string{"a", "b", "c", "d", "e", "f"},
+			present: []string{"c", "d", "e", "f"},
+			size:    16,
+		},
+		{
+			name:    "rewritten key moves to the end",
+			writes:  []string{"a", "b", "c", "d", "a", "e"},
+			present: []string{"c", "d", "a", "e"},
+			size:    16,
+		},
+		{
+			name:    "expired",
+			writes:  []string{"a", "b"},
+			at:      responsesHistoryExpire,
+			present: []string{},
+			size:    0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 每条记录 4 字节，最多保存 4 条
+			store := newResponsesHistoryStore(16)
+			for _, key := range tc.writes {
+				assert.Nil(t, store.set(key, strings.Repeat(key, 4), now))
+			}
+			store.removeExpired(now.Add(tc.at))
+
+			for _, key := range tc.writes {
+				data, ok := store.get(key, now.Add(tc.at))
+				assert.Equal(t, slices.Contains(tc.present, key), ok, key)
+				if ok {
+					assert.Equal(t, strings.Repeat(key, 4), data)
+				}
+			}
+			assert.Equal(t, tc.size, store.size)
+			assert.Equal(t, len(store.items), store.order.Len())
+		})
+	}
+}
+
+func TestResponsesHistoryStoreTooLarge(t *testing.T) {
+	store := newResponsesHistoryStore(16)
+	assert.NotNil(t, store.set("a", strings.Repeat("a", 17), time.Now()))
+	assert.Equal(t, 0, store.size)
+}
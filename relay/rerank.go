@@ -0,0 +1,74 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type relayRerank struct {
+	relayBase
+	request types.RerankRequest
+}
+
+func NewRelayRerank(c *gin.Context) *relayRerank {
+	relay := &relayRerank{}
+	relay.c = c
+	return relay
+}
+
+func (r *relayRerank) setRequest() error {
+	if err := common.UnmarshalBodyReusable(r.c, &r.request); err != nil {
+		return err
+	}
+
+	if len(r.request.Documents) == 0 {
+		return errors.New("documents is required")
+	}
+
+	r.originalModel = r.request.Model
+
+	return nil
+}
+
+func (r *relayRerank) getPromptTokens() (int, error) {
+	input := append([]string{r.request.Query}, r.request.ParseDocuments()...)
+	return common.CountTokenInput(input, r.modelName), nil
+}
+
+func (r *relayRerank) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
+	provider, ok := r.provider.(providersBase.RerankInterface)
+	if !ok {
+		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		done = true
+		return
+	}
+
+	r.request.Model = r.modelName
+
+	response, err := provider.CreateRerank(&r.request)
+	if err != nil {
+		return
+	}
+
+	// 上游没有返回搜索次数时，按文档数量计算
+	usage := r.provider.GetUsage()
+	if usage.SearchUnits == 0 {
+		usage.SearchUnits = r.request.GetSearchUnits()
+	}
+
+	// 修改响应中的模型名称
+	response.Model = r.originalModel
+
+	err = responseJsonClient(r.c, response)
+
+	if err != nil {
+		done = true
+	}
+
+	return
+}
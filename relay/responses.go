@@ -0,0 +1,475 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type relayResponses struct {
+	relayBase
+	responsesRequest types.OpenAIResponsesRequest
+	chatRequest      types.ChatCompletionRequest
+	// 本轮之前的对话记录(不含 instructions)
+	historyMessages []types.ChatCompletionMessage
+	// 本轮新增的输入
+	inputMessages []types.ChatCompletionMessage
+	responseId    string
+}
+
+func NewRelayResponses(c *gin.Context) *relayResponses {
+	relay := &relayResponses{}
+	relay.c = c
+	return relay
+}
+
+func (r *relayResponses) setRequest() error {
+	if err := common.UnmarshalBodyReusable(r.c, &r.responsesRequest); err != nil {
+		return err
+	}
+
+	if r.responsesRequest.MaxOutputTokens < 0 || r.responsesRequest.MaxOutputTokens > math.MaxInt32/2 {
+		return errors.New("max_output_tokens is invalid")
+	}
+
+	if r.responsesRequest.Tools != nil {
+		r.c.Set("skip_only_chat", true)
+	}
+
+	if r.responsesRequest.PreviousResponseID != "" {
+		history, err := relay_util.GetResponsesHistory(r.responsesRequest.PreviousResponseID, r.c.GetInt("id"))
+		if err != nil {
+			return err
+		}
+		r.historyMessages = history.Messages
+	}
+
+	if err := r.convertRequest(); err != nil {
+		return err
+	}
+
+	r.responseId = fmt.Sprintf("resp_%s", utils.GetUUID())
+	r.originalModel = r.responsesRequest.Model
+	setChannelAffinity(r.c, r.responsesRequest.User, getLeadingMessages(r.chatRequest.Messages))
+
+	return nil
+}
+
+func (r *relayResponses) getRequest() interface{} {
+	return &r.responsesRequest
+}
+
+func (r *relayResponses) IsStream() bool {
+	return r.responsesRequest.Stream
+}
+
+func (r *relayResponses) getPromptTokens() (int, error) {
+	channel := r.provider.GetChannel()
+	return common.CountTokenMessages(r.chatRequest.Messages, r.modelName, channel.PreCost), nil
+}
+
+func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
+	chatProvider, ok := r.provider.(providersBase.ChatInterface)
+	if !ok {
+		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		done = true
+		return
+	}
+
+	r.chatRequest.Model = r.modelName
+
+	if r.responsesRequest.Stream {
+		var response requester.StreamReaderInterface[string]
+		response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
+		if err != nil {
+			return
+		}
+
+		converter := newResponsesStreamConverter(r)
+		wrappedResponse := &responsesStreamWrapper{
+			StreamReaderInterface: response,
+			converter:             converter,
+		}
+
+		responseGeneralStreamClient(r.c, wrappedResponse, r.cache, converter.finish)
+	} else {
+		var response *types.ChatCompletionResponse
+		response, err = chatProvider.CreateChatCompletion(&r.chatRequest)
+		if err != nil {
+			return
+		}
+
+		responses := r.convertResponse(response)
+		err = responseJsonClient(r.c, responses)
+
+		if err == nil && len(response.Choices) > 0 {
+			r.saveHistory(response.Choices[0].Message)
+		}
+	}
+
+	if err != nil {
+		done = true
+	}
+
+	return
+}
+
+// convertRequest 将 Responses 请求转换为 Chat Completions 请求
+func (r *relayResponses) convertRequest() error {
+	request := &r.responsesRequest
+
+	inputMessages, err := convertResponsesInput(request.Input)
+	if err != nil {
+		return err
+	}
+	r.inputMessages = inputMessages
+
+	messages := make([]types.ChatCompletionMessage, 0, len(r.historyMessages)+len(inputMessages)+1)
+	if request.Instructions != "" {
+		messages = append(messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: request.Instructions,
+		})
+	}
+	messages = append(messages, r.historyMessages...)
+	messages = append(messages, inputMessages...)
+
+	if len(messages) == 0 {
+		return errors.New("input is required")
+	}
+
+	r.chatRequest = types.ChatCompletionRequest{
+		Model:       request.Model,
+		Messages:    messages,
+		MaxTokens:   request.MaxOutputTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stream:      request.Stream,
+		User:        request.User,
+	}
+
+	if request.ParallelToolCalls != nil {
+		r.chatRequest.ParallelToolCalls = *request.ParallelToolCalls
+	}
+
+	for _, tool := range request.Tools {
+		if tool.Type != "function" {
+			return fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+
+		r.chatRequest.Tools = append(r.chatRequest.Tools, &types.ChatCompletionTool{
+			Type: "function",
+			Function: types.ChatCompletionFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	switch toolChoice := request.ToolChoice.(type) {
+	case string:
+		r.chatRequest.ToolChoice = toolChoice
+	case map[string]any:
+		if name, ok := toolChoice["name"].(string); ok && toolChoice["type"] == "function" {
+			r.chatRequest.ToolChoice = map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name": name,
+				},
+			}
+		}
+	}
+
+	if request.Text != nil && request.Text.Format != nil {
+		format := request.Text.Format
+		switch format.Type {
+		case "json_object":
+			r.chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{Type: format.Type}
+		case "json_schema":
+			r.chatRequest.ResponseFormat = &types.ChatCompletionResponseFormat{
+				Type: format.Type,
+				JsonSchema: map[string]any{
+					"name":        format.Name,
+					"description": format.Description,
+					"schema":      format.Schema,
+					"strict":      format.Strict,
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+func convertResponsesInput(input any) ([]types.ChatCompletionMessage, error) {
+	switch input := input.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []types.ChatCompletionMessage{{
+			Role:    types.ChatMessageRoleUser,
+			Content: input,
+		}}, nil
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []types.ResponsesInputItem
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, errors.New("input is invalid")
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0, len(items))
+	// 记录 call_id 对应的函数名, 部分渠道的工具结果需要携带函数名
+	callNames := make(map[string]string)
+
+	for _, item := range items {
+		switch item.Type {
+		case "", types.ResponsesItemTypeMessage:
+			role := item.Role
+			if role == "developer" {
+				role = types.ChatMessageRoleSystem
+			}
+
+			content, err := convertResponsesInputContent(item.Content)
+			if err != nil {
+				return nil, err
+			}
+
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:    role,
+				Content: content,
+			})
+		case types.ResponsesItemTypeFunctionCall:
+			callNames[item.CallID] = item.Name
+			toolCall := &types.ChatCompletionToolCalls{
+				Id:   item.CallID,
+				Type: "function",
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+
+			// 连续的函数调用合并到同一条 assistant 消息中
+			last := len(messages) - 1
+			if last >= 0 && messages[last].Role == types.ChatMessageRoleAssistant {
+				toolCall.Index = len(messages[last].ToolCalls)
+				messages[last].ToolCalls = append(messages[last].ToolCalls, toolCall)
+				continue
+			}
+
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:      types.ChatMessageRoleAssistant,
+				ToolCalls: []*types.ChatCompletionToolCalls{toolCall},
+			})
+		case types.ResponsesItemTypeFunctionCallOutput:
+			output, ok := item.Output.(string)
+			if !ok {
+				output = utils.Marshal(item.Output)
+			}
+
+			message := types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    output,
+				ToolCallID: item.CallID,
+			}
+			if name, ok := callNames[item.CallID]; ok {
+				message.Name = &name
+			}
+
+			messages = append(messages, message)
+		default:
+			return nil, fmt.Errorf("unsupported input item type: %s", item.Type)
+		}
+	}
+
+	return messages, nil
+}
+
+func convertResponsesInputContent(content any) (any, error) {
+	contents, ok := content.([]any)
+	if !ok {
+		return content, nil
+	}
+
+	parts := make([]any, 0, len(contents))
+	for _, item := range contents {
+		part, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		switch part["type"] {
+		case types.ResponsesContentTypeInputText, types.ResponsesContentTypeOutputText:
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeText,
+				"text": part["text"],
+			})
+		case types.ResponsesContentTypeRefusal:
+			parts = append(parts, map[string]any{
+				"type": types.ContentTypeText,
+				"text": part["refusal"],
+			})
+		case types.ResponsesContentTypeInputImage:
+			url, _ := part["image_url"].(string)
+			if url == "" {
+				return nil, errors.New("input_image only supports image_url")
+			}
+
+			imageURL := map[string]any{"url": url}
+			if detail, ok := part["detail"].(string); ok && detail != "" {
+				imageURL["detail"] = detail
+			}
+
+			parts = append(parts, map[string]any{
+				"type":      types.ContentTypeImageURL,
+				"image_url": imageURL,
+			})
+		default:
+			return nil, fmt.Errorf("unsupported content type: %v", part["type"])
+		}
+	}
+
+	return parts, nil
+}
+
+// newResponse 根据请求参数生成 Responses 对象
+func (r *relayResponses) newResponse(status string) *types.OpenAIResponsesResponse {
+	request := &r.responsesRequest
+
+	tools := request.Tools
+	if tools == nil {
+		tools = []*types.ResponsesTool{}
+	}
+
+	toolChoice := request.ToolChoice
+	if toolChoice == nil {
+		toolChoice = types.ToolChoiceTypeAuto
+	}
+
+	return &types.OpenAIResponsesResponse{
+		ID:                 r.responseId,
+		Object:             "response",
+		CreatedAt:          utils.GetTimestamp(),
+		Status:             status,
+		Instructions:       request.Instructions,
+		MaxOutputTokens:    request.MaxOutputTokens,
+		Model:              r.originalModel,
+		Output:             []*types.ResponsesOutputItem{},
+		ParallelToolCalls:  request.ParallelToolCalls == nil || *request.ParallelToolCalls,
+		PreviousResponseID: request.PreviousResponseID,
+		Store:              request.IsStore(),
+		Temperature:        request.Temperature,
+		TopP:               request.TopP,
+		ToolChoice:         toolChoice,
+		Tools:              tools,
+		Text:               request.Text,
+		User:               request.User,
+		Metadata:           request.Metadata,
+	}
+}
+
+func (r *relayResponses) convertResponse(response *types.ChatCompletionResponse) *types.OpenAIResponsesResponse {
+	responses := r.newResponse(types.ResponsesStatusCompleted)
+	responses.Usage = response.Usage.ToResponsesUsage()
+
+	if len(response.Choices) == 0 {
+		return responses
+	}
+
+	choice := response.Choices[0]
+	if content := choice.Message.StringContent(); content != "" {
+		responses.Output = append(responses.Output, &types.ResponsesOutputItem{
+			Type:   types.ResponsesItemTypeMessage,
+			ID:     fmt.Sprintf("msg_%s", utils.GetUUID()),
+			Status: types.ResponsesStatusCompleted,
+			Role:   types.ChatMessageRoleAssistant,
+			Content: []*types.ResponsesOutputContent{{
+				Type:        types.ResponsesContentTypeOutputText,
+				Text:        content,
+				Annotations: []any{},
+			}},
+		})
+	}
+
+	for _, toolCall := range choice.Message.ToolCalls {
+		if toolCall.Function == nil {
+			continue
+		}
+
+		if toolCall.Id == "" {
+			toolCall.Id = fmt.Sprintf("call_%s", utils.GetUUID())
+		}
+
+		arguments := toolCall.Function.Arguments
+		responses.Output = append(responses.Output, &types.ResponsesOutputItem{
+			Type:      types.ResponsesItemTypeFunctionCall,
+			ID:        fmt.Sprintf("fc_%s", utils.GetUUID()),
+			Status:    types.ResponsesStatusCompleted,
+			CallID:    toolCall.Id,
+			Name:      toolCall.Function.Name,
+			Arguments: &arguments,
+		})
+	}
+
+	if reason := getResponsesIncompleteReason(choice.FinishReason); reason != "" {
+		responses.Status = types.ResponsesStatusIncomplete
+		responses.IncompleteDetails = &types.ResponsesIncompleteDetails{Reason: reason}
+	}
+
+	return responses
+}
+
+func getResponsesIncompleteReason(finishReason any) string {
+	switch finishReason {
+	case types.FinishReasonLength:
+		return types.ResponsesIncompleteReasonMaxTokens
+	case types.FinishReasonContentFilter:
+		return types.ResponsesIncompleteReasonContentFilter
+	}
+
+	return ""
+}
+
+// saveHistory 保存本轮对话，供下一次请求通过 previous_response_id 续接
+func (r *relayResponses) saveHistory(message types.ChatCompletionMessage) {
+	if !r.responsesRequest.IsStore() {
+		return
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0, len(r.historyMessages)+len(r.inputMessages)+1)
+	messages = append(messages, r.historyMessages...)
+	messages = append(messages, r.inputMessages...)
+	assistantMessage := types.ChatCompletionMessage{
+		Role:      types.ChatMessageRoleAssistant,
+		ToolCalls: message.ToolCalls,
+	}
+	if content := message.StringContent(); content != "" {
+		assistantMessage.Content = content
+	}
+	messages = append(messages, assistantMessage)
+
+	err := relay_util.SetResponsesHistory(r.responseId, &relay_util.ResponsesHistory{
+		UserId:   r.c.GetInt("id"),
+		Messages: messages,
+	})
+	if err != nil {
+		logger.LogError(r.c.Request.Context(), "save responses history failed: "+err.Error())
+	}
+}
@@ -0,0 +1,353 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// responsesStreamWrapper 将 Chat Completions 的流式响应转换为 Responses 的 SSE 事件
+type responsesStreamWrapper struct {
+	requester.StreamReaderInterface[string]
+	converter *responsesStreamConverter
+}
+
+func (w *responsesStreamWrapper) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := w.StreamReaderInterface.Recv()
+	wrappedDataChan := make(chan string)
+	wrappedErrChan := make(chan error)
+
+	// 数据和错误在同一个协程中转发，保证事件顺序
+	go func() {
+		for {
+			select {
+			case data := <-dataChan:
+				if events := w.converter.convert(data); events != "" {
+					wrappedDataChan <- events
+				}
+			case err := <-errChan:
+				if !errors.Is(err, io.EOF) {
+					wrappedDataChan <- w.converter.fail(err)
+					err = io.EOF
+				}
+				wrappedErrChan <- err
+				return
+			}
+		}
+	}()
+
+	return wrappedDataChan, wrappedErrChan
+}
+
+type responsesStreamConverter struct {
+	relay        *relayResponses
+	response     *types.OpenAIResponsesResponse
+	sequence     int
+	started      bool
+	failed       bool
+	finishReason any
+
+	message     *types.ResponsesOutputItem
+	messageText strings.Builder
+
+	toolCalls    []*types.ChatCompletionToolCalls
+	toolItems    []*types.ResponsesOutputItem
+	toolCallArgs []*strings.Builder
+}
+
+func newResponsesStreamConverter(relay *relayResponses) *responsesStreamConverter {
+	return &responsesStreamConverter{
+		relay:    relay,
+		response: relay.newResponse(types.ResponsesStatusInProgress),
+	}
+}
+
+func (s *responsesStreamConverter) convert(data string) string {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return ""
+	}
+
+	var events strings.Builder
+	s.start(&events)
+
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			s.appendText(&events, choice.Delta.Content)
+		}
+
+		for _, toolCall := range choice.Delta.ToolCalls {
+			s.appendToolCall(&events, toolCall)
+		}
+
+		if choice.FinishReason != nil && choice.FinishReason != "" {
+			s.finishReason = choice.FinishReason
+		}
+	}
+
+	return events.String()
+}
+
+func (s *responsesStreamConverter) start(events *strings.Builder) {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:     types.ResponsesEventCreated,
+		Response: s.response,
+	}))
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:     types.ResponsesEventInProgress,
+		Response: s.response,
+	}))
+}
+
+func (s *responsesStreamConverter) appendText(events *strings.Builder, text string) {
+	if s.message == nil {
+		s.message = &types.ResponsesOutputItem{
+			Type:    types.ResponsesItemTypeMessage,
+			ID:      fmt.Sprintf("msg_%s", utils.GetUUID()),
+			Status:  types.ResponsesStatusInProgress,
+			Role:    types.ChatMessageRoleAssistant,
+			Content: []*types.ResponsesOutputContent{},
+		}
+		outputIndex := s.addOutput(s.message)
+
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:        types.ResponsesEventOutputItemAdded,
+			OutputIndex: &outputIndex,
+			Item:        s.message,
+		}))
+
+		contentIndex := 0
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:         types.ResponsesEventContentPartAdded,
+			OutputIndex:  &outputIndex,
+			ContentIndex: &contentIndex,
+			ItemID:       s.message.ID,
+			Part:         newResponsesOutputText(""),
+		}))
+	}
+
+	s.messageText.WriteString(text)
+
+	outputIndex := s.outputIndex(s.message)
+	contentIndex := 0
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:         types.ResponsesEventOutputTextDelta,
+		OutputIndex:  &outputIndex,
+		ContentIndex: &contentIndex,
+		ItemID:       s.message.ID,
+		Delta:        &text,
+	}))
+}
+
+func (s *responsesStreamConverter) appendToolCall(events *strings.Builder, toolCall *types.ChatCompletionToolCalls) {
+	if toolCall.Function == nil {
+		return
+	}
+
+	// 部分渠道的 index 始终为 0，所以以出现新的 id 或函数名作为新调用的开始
+	current := len(s.toolCalls) - 1
+	if current < 0 || (toolCall.Id != "" && toolCall.Id != s.toolCalls[current].Id) || (toolCall.Id == "" && toolCall.Function.Name != "") {
+		callId := toolCall.Id
+		if callId == "" {
+			callId = fmt.Sprintf("call_%s", utils.GetUUID())
+		}
+
+		arguments := ""
+		item := &types.ResponsesOutputItem{
+			Type:      types.ResponsesItemTypeFunctionCall,
+			ID:        fmt.Sprintf("fc_%s", utils.GetUUID()),
+			Status:    types.ResponsesStatusInProgress,
+			CallID:    callId,
+			Name:      toolCall.Function.Name,
+			Arguments: &arguments,
+		}
+
+		s.toolCalls = append(s.toolCalls, &types.ChatCompletionToolCalls{
+			Id:    callId,
+			Type:  "function",
+			Index: len(s.toolCalls),
+			Function: &types.ChatCompletionToolCallsFunction{
+				Name: toolCall.Function.Name,
+			},
+		})
+		s.toolItems = append(s.toolItems, item)
+		s.toolCallArgs = append(s.toolCallArgs, &strings.Builder{})
+		current = len(s.toolCalls) - 1
+
+		outputIndex := s.addOutput(item)
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:        types.ResponsesEventOutputItemAdded,
+			OutputIndex: &outputIndex,
+			Item:        item,
+		}))
+	}
+
+	if toolCall.Function.Arguments == "" {
+		return
+	}
+
+	s.toolCallArgs[current].WriteString(toolCall.Function.Arguments)
+
+	item := s.toolItems[current]
+	outputIndex := s.outputIndex(item)
+	delta := toolCall.Function.Arguments
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:        types.ResponsesEventFunctionCallArgsDelta,
+		OutputIndex: &outputIndex,
+		ItemID:      item.ID,
+		Delta:       &delta,
+	}))
+}
+
+// fail 上游报错时输出 response.failed 事件
+func (s *responsesStreamConverter) fail(err error) string {
+	var events strings.Builder
+	s.start(&events)
+	s.failed = true
+
+	var aiError *types.OpenAIError
+	if !errors.As(err, &aiError) {
+		aiError = &types.OpenAIError{
+			Message: err.Error(),
+			Type:    "stream_error",
+		}
+	}
+
+	s.response.Status = types.ResponsesStatusFailed
+	s.response.Error = aiError
+	s.response.Usage = s.relay.provider.GetUsage().ToResponsesUsage()
+
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:     types.ResponsesEventFailed,
+		Response: s.response,
+	}))
+
+	return events.String()
+}
+
+// finish 流结束时补全各个输出项的 done 事件，并输出最终的响应
+func (s *responsesStreamConverter) finish() string {
+	if s.failed {
+		return ""
+	}
+
+	var events strings.Builder
+	s.start(&events)
+
+	if s.message != nil {
+		text := s.messageText.String()
+		outputIndex := s.outputIndex(s.message)
+		contentIndex := 0
+		part := newResponsesOutputText(text)
+
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:         types.ResponsesEventOutputTextDone,
+			OutputIndex:  &outputIndex,
+			ContentIndex: &contentIndex,
+			ItemID:       s.message.ID,
+			Text:         &text,
+		}))
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:         types.ResponsesEventContentPartDone,
+			OutputIndex:  &outputIndex,
+			ContentIndex: &contentIndex,
+			ItemID:       s.message.ID,
+			Part:         part,
+		}))
+
+		s.message.Status = types.ResponsesStatusCompleted
+		s.message.Content = []*types.ResponsesOutputContent{part}
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:        types.ResponsesEventOutputItemDone,
+			OutputIndex: &outputIndex,
+			Item:        s.message,
+		}))
+	}
+
+	for i, item := range s.toolItems {
+		arguments := s.toolCallArgs[i].String()
+		s.toolCalls[i].Function.Arguments = arguments
+		outputIndex := s.outputIndex(item)
+
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:        types.ResponsesEventFunctionCallArgsDone,
+			OutputIndex: &outputIndex,
+			ItemID:      item.ID,
+			Arguments:   &arguments,
+		}))
+
+		item.Status = types.ResponsesStatusCompleted
+		item.Arguments = &arguments
+		events.WriteString(s.event(&types.ResponsesStreamEvent{
+			Type:        types.ResponsesEventOutputItemDone,
+			OutputIndex: &outputIndex,
+			Item:        item,
+		}))
+	}
+
+	eventType := types.ResponsesEventCompleted
+	s.response.Status = types.ResponsesStatusCompleted
+	if reason := getResponsesIncompleteReason(s.finishReason); reason != "" {
+		eventType = types.ResponsesEventIncomplete
+		s.response.Status = types.ResponsesStatusIncomplete
+		s.response.IncompleteDetails = &types.ResponsesIncompleteDetails{Reason: reason}
+	}
+	s.response.Usage = s.relay.provider.GetUsage().ToResponsesUsage()
+
+	events.WriteString(s.event(&types.ResponsesStreamEvent{
+		Type:     eventType,
+		Response: s.response,
+	}))
+
+	s.relay.saveHistory(types.ChatCompletionMessage{
+		Role:      types.ChatMessageRoleAssistant,
+		Content:   s.messageText.String(),
+		ToolCalls: s.toolCalls,
+	})
+
+	return events.String()
+}
+
+func (s *responsesStreamConverter) addOutput(item *types.ResponsesOutputItem) int {
+	s.response.Output = append(s.response.Output, item)
+	return len(s.response.Output) - 1
+}
+
+func (s *responsesStreamConverter) outputIndex(item *types.ResponsesOutputItem) int {
+	for i, output := range s.response.Output {
+		if output == item {
+			return i
+		}
+	}
+	return 0
+}
+
+func (s *responsesStreamConverter) event(event *types.ResponsesStreamEvent) string {
+	event.SequenceNumber = s.sequence
+	s.sequence++
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, body)
+}
+
+func newResponsesOutputText(text string) *types.ResponsesOutputContent {
+	return &types.ResponsesOutputContent{
+		Type:        types.ResponsesContentTypeOutputText,
+		Text:        text,
+		Annotations: []any{},
+	}
+}
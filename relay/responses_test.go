@@ -0,0 +1,299 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	providersBase "one-api/providers/base"
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertResponsesInput(t *testing.T) {
+	getName := "get_weather"
+
+	cases := []struct {
+		name     string
+		input    string
+		expected []types.ChatCompletionMessage
+		err      string
+	}{
+		{
+			name:     "null",
+			input:    `null`,
+			expected: nil,
+		},
+		{
+			name:  "string",
+			input: `"hello"`,
+			expected: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleUser, Content: "hello"},
+			},
+		},
+		{
+			name:  "developer as system",
+			input: `[{"role":"developer","content":"be brief"},{"type":"message","role":"user","content":"hi"}]`,
+			expected: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleSystem, Content: "be brief"},
+				{Role: types.ChatMessageRoleUser, Content: "hi"},
+			},
+		},
+		{
+			name: "content parts",
+			input: `[{"role":"user","content":[
+				{"type":"input_text","text":"what is this"},
+				{"type":"input_image","image_url":"https://example.com/a.png","detail":"low"}
+			]},{"role":"assistant","content":[
+				{"type":"output_text","text":"a cat"},
+				{"type":"refusal","refusal":"no"}
+			]}]`,
+			expected: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleUser, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "what is this"},
+					map[string]any{"type": types.ContentTypeImageURL, "image_url": map[string]any{"url": "https://example.com/a.png", "detail": "low"}},
+				}},
+				{Role: types.ChatMessageRoleAssistant, Content: []any{
+					map[string]any{"type": types.ContentTypeText, "text": "a cat"},
+					map[string]any{"type": types.ContentTypeText, "text": "no"},
+				}},
+			},
+		},
+		{
+			name: "function calls and outputs",
+			input: `[{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"a\"}"},
+				{"type":"function_call","call_id":"call_2","name":"get_weather","arguments":"{\"city\":\"b\"}"},
+				{"type":"function_call_output","call_id":"call_1","output":"sunny"},
+				{"type":"function_call_output","call_id":"call_3","output":{"temp":20}}]`,
+			expected: []types.ChatCompletionMessage{
+				{Role: types.ChatMessageRoleAssistant, ToolCalls: []*types.ChatCompletionToolCalls{
+					{Id: "call_1", Type: "function", Index: 0, Function: &types.ChatCompletionToolCallsFunction{Name: getName, Arguments: `{"city":"a"}`}},
+					{Id: "call_2", Type: "function", Index: 1, Function: &types.ChatCompletionToolCallsFunction{Name: getName, Arguments: `{"city":"b"}`}},
+				}},
+				{Role: types.ChatMessageRoleTool, Content: "sunny", ToolCallID: "call_1", Name: &getName},
+				{Role: types.ChatMessageRoleTool, Content: `{"temp":20}`, ToolCallID: "call_3"},
+			},
+		},
+		{
+			name:  "invalid input",
+			input: `123`,
+			err:   "input is invalid",
+		},
+		{
+			name:  "unsupported item type",
+			input: `[{"type":"reasoning"}]`,
+			err:   "unsupported input item type: reasoning",
+		},
+		{
+			name:  "unsupported content type",
+			input: `[{"role":"user","content":[{"type":"input_audio"}]}]`,
+			err:   "unsupported content type: input_audio",
+		},
+		{
+			name:  "input image without url",
+			input: `[{"role":"user","content":[{"type":"input_image","file_id":"file_1"}]}]`,
+			err:   "input_image only supports image_url",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var input any
+			assert.Nil(t, json.Unmarshal([]byte(tc.input), &input))
+
+			messages, err := convertResponsesInput(input)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, messages)
+		})
+	}
+}
+
+// usageProvider 只实现流式转换用到的 GetUsage
+type usageProvider struct {
+	providersBase.ProviderInterface
+	usage *types.Usage
+}
+
+func (p *usageProvider) GetUsage() *types.Usage {
+	return p.usage
+}
+
+func newTestResponsesStreamConverter(usage *types.Usage) *responsesStreamConverter {
+	store := false
+	relay := &relayResponses{
+		responsesRequest: types.OpenAIResponsesRequest{Store: &store},
+		responseId:       "resp_test",
+	}
+	relay.originalModel = "gpt-test"
+	relay.provider = &usageProvider{usage: usage}
+
+	return newResponsesStreamConverter(relay)
+}
+
+func parseResponsesEvents(t *testing.T, raw string) []*types.ResponsesStreamEvent {
+	var events []*types.ResponsesStreamEvent
+	for _, block := range strings.Split(raw, "\n\n") {
+		if block == "" {
+			continue
+		}
+
+		lines := strings.Split(block, "\n")
+		assert.Len(t, lines, 2)
+		eventType := strings.TrimPrefix(lines[0], "event: ")
+
+		event := &types.ResponsesStreamEvent{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), event))
+		assert.Equal(t, eventType, event.Type)
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestResponsesStreamConverter(t *testing.T) {
+	cases := []struct {
+		name       string
+		chunks     []string
+		eventTypes []string
+		status     string
+		text       string
+		arguments  []string
+	}{
+		{
+			name: "text",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+				`{"choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+				`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			},
+			eventTypes: []string{
+				types.ResponsesEventCreated,
+				types.ResponsesEventInProgress,
+				types.ResponsesEventOutputItemAdded,
+				types.ResponsesEventContentPartAdded,
+				types.ResponsesEventOutputTextDelta,
+				types.ResponsesEventOutputTextDelta,
+				types.ResponsesEventOutputTextDone,
+				types.ResponsesEventContentPartDone,
+				types.ResponsesEventOutputItemDone,
+				types.ResponsesEventCompleted,
+			},
+			status: types.ResponsesStatusCompleted,
+			text:   "Hello",
+		},
+		{
+			name: "tool calls with the same index",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"a","arguments":""}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"x\":"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_2","type":"function","function":{"name":"b","arguments":"{}"}}]}}]}`,
+				`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			},
+			eventTypes: []string{
+				types.ResponsesEventCreated,
+				types.ResponsesEventInProgress,
+				types.ResponsesEventOutputItemAdded,
+				types.ResponsesEventFunctionCallArgsDelta,
+				types.ResponsesEventFunctionCallArgsDelta,
+				types.ResponsesEventOutputItemAdded,
+				types.ResponsesEventFunctionCallArgsDelta,
+				types.ResponsesEventFunctionCallArgsDone,
+				types.ResponsesEventOutputItemDone,
+				types.ResponsesEventFunctionCallArgsDone,
+				types.ResponsesEventOutputItemDone,
+				types.ResponsesEventCompleted,
+			},
+			status:    types.ResponsesStatusCompleted,
+			arguments: []string{`{"x":1}`, `{}`},
+		},
+		{
+			name: "length is incomplete",
+			chunks: []string{
+				`{"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"length"}]}`,
+			},
+			eventTypes: []string{
+				types.ResponsesEventCreated,
+				types.ResponsesEventInProgress,
+				types.ResponsesEventOutputItemAdded,
+				types.ResponsesEventContentPartAdded,
+				types.ResponsesEventOutputTextDelta,
+				types.ResponsesEventOutputTextDone,
+				types.ResponsesEventContentPartDone,
+				types.ResponsesEventOutputItemDone,
+				types.ResponsesEventIncomplete,
+			},
+			status: types.ResponsesStatusIncomplete,
+			text:   "Hi",
+		},
+		{
+			name:   "invalid chunk is skipped",
+			chunks: []string{`not json`},
+			eventTypes: []string{
+				types.ResponsesEventCreated,
+				types.ResponsesEventInProgress,
+				types.ResponsesEventCompleted,
+			},
+			status: types.ResponsesStatusCompleted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usage := &types.Usage{PromptTokens: 10, CompletionTokens: 5}
+			converter := newTestResponsesStreamConverter(usage)
+
+			var raw strings.Builder
+			for _, chunk := range tc.chunks {
+				raw.WriteString(converter.convert(chunk))
+			}
+			raw.WriteString(converter.finish())
+
+			events := parseResponsesEvents(t, raw.String())
+			eventTypes := make([]string, 0, len(events))
+			for i, event := range events {
+				assert.Equal(t, i, event.SequenceNumber)
+				eventTypes = append(eventTypes, event.Type)
+			}
+			assert.Equal(t, tc.eventTypes, eventTypes)
+
+			response := events[len(events)-1].Response
+			assert.Equal(t, tc.status, response.Status)
+			assert.Equal(t, "gpt-test", response.Model)
+			assert.Equal(t, 15, response.Usage.TotalTokens)
+			assert.Equal(t, tc.text, response.GetContent())
+
+			var arguments []string
+			for _, item := range response.Output {
+				if item.Type == types.ResponsesItemTypeFunctionCall {
+					arguments = append(arguments, *item.Arguments)
+				}
+			}
+			assert.Equal(t, tc.arguments, arguments)
+		})
+	}
+}
+
+func TestResponsesStreamConverterFail(t *testing.T) {
+	converter := newTestResponsesStreamConverter(&types.Usage{PromptTokens: 3})
+
+	var raw strings.Builder
+	raw.WriteString(converter.convert(`{"choices":[{"index":0,"delta":{"content":"Hi"}}]}`))
+	raw.WriteString(converter.fail(errors.New("upstream closed")))
+
+	// 失败后不再输出完成事件
+	assert.Equal(t, "", converter.finish())
+
+	events := parseResponsesEvents(t, raw.String())
+	last := events[len(events)-1]
+	assert.Equal(t, types.ResponsesEventFailed, last.Type)
+	assert.Equal(t, types.ResponsesStatusFailed, last.Response.Status)
+	assert.Equal(t, 3, last.Response.Usage.InputTokens)
+}
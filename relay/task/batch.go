@@ -0,0 +1,34 @@
+package task
+
+import (
+	"net/http"
+	"one-api/common/config"
+	"one-api/relay/task/base"
+	"one-api/relay/task/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelayBatchSubmit 创建批处理，请求由任务轮询在后台执行
+func RelayBatchSubmit(c *gin.Context) {
+	taskAdaptor, err := GetTaskAdaptor(config.RelayModeBatch, c)
+	if err != nil {
+		batch.HandleTaskError(c, base.StringTaskError(http.StatusBadRequest, "adaptor_not_found", "adaptor not found", true))
+		return
+	}
+
+	taskErr := taskAdaptor.Init()
+	if taskErr != nil {
+		taskAdaptor.HandleError(taskErr)
+		return
+	}
+
+	taskErr = taskAdaptor.Relay()
+	if taskErr != nil {
+		taskAdaptor.HandleError(taskErr)
+		return
+	}
+
+	// 激活任务
+	ActivateUpdateTaskBulk()
+}
@@ -0,0 +1,107 @@
+package batch
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/task/base"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批处理支持的接口
+var AllowBatchEndpoints = []string{
+	"/v1/chat/completions",
+	"/v1/completions",
+	"/v1/embeddings",
+}
+
+type BatchProperties struct {
+	TokenId int `json:"token_id"`
+	// Executed 已执行的请求数，执行前先保存，结果写入失败时也不会重复执行
+	Executed int `json:"executed,omitempty"`
+}
+
+// Passthrough 指定了渠道的请求仍然直接转发给上游
+func Passthrough(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt("specific_channel_id") > 0 {
+			c.Set("specific_channel_id_ignore", false)
+			relay.RelayOnly(c)
+			return
+		}
+
+		handler(c)
+	}
+}
+
+func StringError(c *gin.Context, httpCode int, message string) {
+	common.AbortWithMessage(c, httpCode, message)
+}
+
+func HandleTaskError(c *gin.Context, err *base.TaskError) {
+	StringError(c, err.StatusCode, err.Message)
+}
+
+func isAllowEndpoint(endpoint string) bool {
+	for _, allowEndpoint := range AllowBatchEndpoints {
+		if endpoint == allowEndpoint {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TaskModel2Batch 批处理的状态保存在任务的 Data 中
+func TaskModel2Batch(task *model.Task) *types.Batch {
+	batch := &types.Batch{}
+	if err := json.Unmarshal(task.Data, batch); err != nil {
+		return nil
+	}
+
+	// 取消请求只修改了任务状态，还未被处理
+	if task.Status == model.TaskStatusCancelling && !isFinalStatus(batch.Status) {
+		batch.Status = types.BatchStatusCancelling
+	}
+
+	return batch
+}
+
+func isFinalStatus(status string) bool {
+	switch status {
+	case types.BatchStatusCompleted, types.BatchStatusFailed, types.BatchStatusExpired, types.BatchStatusCancelled:
+		return true
+	}
+
+	return false
+}
+
+func FileModel2OpenAI(file *model.File) *types.OpenAIFile {
+	return &types.OpenAIFile{
+		ID:        file.FileID,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
+
+// splitLines 拆分 JSONL，忽略空行
+func splitLines(content string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,425 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/middleware"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// batchRateLimitWait 单个请求等待令牌限制恢复的最长时间
+const batchRateLimitWait = 2 * time.Minute
+
+// UpdateTaskStatus 每轮轮询只执行每个批处理的一部分请求，避免占用过多资源
+func (t *BatchTask) UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
+	logger.LogWarn(ctx, fmt.Sprintf("未完成的批处理有: %d", len(taskM)))
+
+	for _, task := range taskM {
+		runner := &batchRunner{
+			ctx:  ctx,
+			task: task,
+		}
+		runner.run()
+	}
+
+	return nil
+}
+
+type batchRunner struct {
+	ctx        context.Context
+	task       *model.Task
+	batch      *types.Batch
+	properties BatchProperties
+	token      *model.Token
+	group      string
+}
+
+func (r *batchRunner) run() {
+	r.batch = TaskModel2Batch(r.task)
+	if r.batch == nil {
+		r.task.Status = model.TaskStatusFailure
+		r.task.FailReason = "批处理数据解析失败"
+		r.task.Progress = 100
+		if err := r.task.Update(); err != nil {
+			logger.SysError("UpdateTask task error: " + err.Error())
+		}
+		r.task.SendFailedEvent(0)
+		return
+	}
+
+	now := utils.GetTimestamp()
+	if r.batch.Status == types.BatchStatusCancelling {
+		r.finish(types.BatchStatusCancelled, nil)
+		return
+	}
+
+	if now > r.batch.ExpiresAt {
+		r.finish(types.BatchStatusExpired, nil)
+		return
+	}
+
+	if err := r.loadToken(); err != nil {
+		r.finish(types.BatchStatusFailed, &types.BatchError{Code: "token_invalid", Message: err.Error()})
+		return
+	}
+
+	content, err := model.GetFileContent(r.batch.InputFileID)
+	lines := splitLines(content)
+	if err != nil || len(lines) == 0 {
+		r.finish(types.BatchStatusFailed, &types.BatchError{Code: "input_file_not_found", Message: "input file not found"})
+		return
+	}
+
+	if r.batch.Status == types.BatchStatusValidating {
+		r.batch.Status = types.BatchStatusInProgress
+		r.batch.InProgressAt = &now
+		r.task.Status = model.TaskStatusInProgress
+		r.task.StartTime = now
+	}
+
+	// 上次已执行但结果没有写入的请求
+	r.markLost(r.properties.Executed)
+
+	start := r.batch.RequestCounts.Completed + r.batch.RequestCounts.Failed
+	end := start + viper.GetInt("batch.chunk_size")
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	if start < end {
+		// 执行前先保存执行范围，请求已经计费，之后写入结果失败也不能重新执行
+		r.properties.Executed = end
+		r.task.Properties, _ = json.Marshal(r.properties)
+		if err := r.save(); err != nil {
+			return
+		}
+
+		if err := r.writeOutputs(r.execute(lines[start:end])); err != nil {
+			logger.LogError(r.ctx, fmt.Sprintf("批处理 %s 写入结果失败: %s", r.batch.ID, err.Error()))
+			r.markLost(end)
+		}
+	}
+
+	if end >= len(lines) {
+		r.finish(types.BatchStatusCompleted, nil)
+		return
+	}
+
+	r.task.Progress = end * 100 / len(lines)
+	if r.task.Progress >= 100 {
+		r.task.Progress = 99
+	}
+	r.save()
+}
+
+func (r *batchRunner) loadToken() error {
+	if err := json.Unmarshal(r.task.Properties, &r.properties); err != nil {
+		return err
+	}
+
+	token, err := model.GetTokenById(r.properties.TokenId)
+	if err != nil {
+		return errors.New("令牌不存在")
+	}
+
+	if _, err := model.ValidateUserToken(token.Key); err != nil {
+		return err
+	}
+
+	userEnabled, err := model.CacheIsUserEnabled(r.task.UserId)
+	if err != nil {
+		return err
+	}
+	if !userEnabled {
+		return errors.New("用户已被封禁")
+	}
+
+	r.group, err = model.CacheGetUserGroup(r.task.UserId)
+	if err != nil {
+		return err
+	}
+	r.token = token
+
+	return nil
+}
+
+// execute 按配置的并发数执行请求，结果保持输入顺序
+func (r *batchRunner) execute(lines []string) []*types.BatchRequestOutput {
+	outputs := make([]*types.BatchRequestOutput, len(lines))
+	limiter := make(chan struct{}, max(viper.GetInt("batch.concurrency"), 1))
+
+	var wg sync.WaitGroup
+	for i, line := range lines {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(i int, line string) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			outputs[i] = r.executeRequest(line)
+		}(i, line)
+	}
+	wg.Wait()
+
+	return outputs
+}
+
+// executeRequest 构造一个请求上下文，走正常的中继流程
+func (r *batchRunner) executeRequest(line string) (output *types.BatchRequestOutput) {
+	output = &types.BatchRequestOutput{
+		ID: "batch_req_" + utils.GetUUID(),
+	}
+
+	var input types.BatchRequestInput
+	if err := json.Unmarshal([]byte(line), &input); err != nil {
+		output.Error = &types.BatchError{Code: "invalid_request", Message: err.Error()}
+		return
+	}
+	output.CustomID = input.CustomID
+
+	// 批处理不支持流式请求
+	var body map[string]any
+	if err := json.Unmarshal(input.Body, &body); err != nil {
+		output.Error = &types.BatchError{Code: "invalid_request", Message: err.Error()}
+		return
+	}
+	delete(body, "stream")
+	delete(body, "stream_options")
+	requestBody, _ := json.Marshal(body)
+
+	// 令牌的模型限制同样适用于批处理中的每个请求
+	modelName, _ := body["model"].(string)
+	if !model.IsModelAllowed(r.token.GetAllowedModels(), modelName) {
+		output.Error = &types.BatchError{Code: "model_not_allowed", Message: fmt.Sprintf("该令牌无权使用模型 %s", modelName), Param: "model"}
+		return
+	}
+
+	requestId := utils.GetTimeString() + utils.GetRandomString(8)
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, requestId)
+	ctx = context.WithValue(ctx, "requestStartTime", time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, input.URL, bytes.NewReader(requestBody))
+	if err != nil {
+		output.Error = &types.BatchError{Code: "invalid_request", Message: err.Error()}
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(logger.RequestIdKey, requestId)
+	middleware.SetTokenContext(c, r.token)
+	c.Set("group", r.group)
+	c.Set("batch_request", true)
+
+	defer func() {
+		if err := recover(); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("batch request panic: %v", err))
+			output.Response = nil
+			output.Error = &types.BatchError{Code: "internal_error", Message: fmt.Sprintf("%v", err)}
+		}
+	}()
+
+	limits := r.token.TokenLimits
+	if !limits.IsEmpty() {
+		if exceeded := r.waitTokenLimit(limits); exceeded != "" {
+			output.Error = &types.BatchError{Code: "rate_limit_exceeded", Message: fmt.Sprintf("令牌已达到 %s 限制，请稍后再试", exceeded)}
+			return
+		}
+		defer func() {
+			model.TokenRequestDone(r.token.Id, limits, c.GetInt("consumed_tokens"))
+		}()
+	}
+
+	defer relay.ReleaseChannelReservation(c)
+	relay.Relay(c)
+
+	responseBody := w.Body.Bytes()
+	if !json.Valid(responseBody) {
+		responseBody, _ = json.Marshal(string(responseBody))
+	}
+
+	output.Response = &types.BatchOutputResponse{
+		StatusCode: w.Code,
+		RequestID:  requestId,
+		Body:       responseBody,
+	}
+
+	return
+}
+
+// waitTokenLimit 令牌的 RPM、TPM、并发限制同样适用于批处理，超出时等待，每日请求数用完或等待过久时返回超出的限制
+func (r *batchRunner) waitTokenLimit(limits model.TokenLimits) string {
+	deadline := time.Now().Add(batchRateLimitWait)
+	for {
+		status := model.TokenRequestStart(r.token.Id, limits)
+		if status.Exceeded == "" {
+			return ""
+		}
+
+		if status.Exceeded == model.TokenLimitDailyRequests || time.Now().Add(status.RetryAfter).After(deadline) {
+			return status.Exceeded
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return status.Exceeded
+		case <-time.After(status.RetryAfter):
+		}
+	}
+}
+
+// writeOutputs 成功的结果写入输出文件，失败的写入错误文件
+func (r *batchRunner) writeOutputs(outputs []*types.BatchRequestOutput) error {
+	var outputContent, errorContent strings.Builder
+	completed, failed := 0, 0
+
+	for _, output := range outputs {
+		line, err := json.Marshal(output)
+		if err != nil {
+			return err
+		}
+
+		if output.Error == nil && output.Response != nil && output.Response.StatusCode == http.StatusOK {
+			outputContent.Write(line)
+			outputContent.WriteString("\n")
+			completed++
+		} else {
+			errorContent.Write(line)
+			errorContent.WriteString("\n")
+			failed++
+		}
+	}
+
+	// 每写入一个文件都立即保存，避免重复创建文件
+	outputFileId, err := r.appendFile(r.batch.OutputFileID, "output", outputContent.String())
+	if err != nil {
+		return err
+	}
+	r.batch.OutputFileID = outputFileId
+	r.batch.RequestCounts.Completed += completed
+	if err := r.save(); err != nil {
+		return err
+	}
+
+	errorFileId, err := r.appendFile(r.batch.ErrorFileID, "error", errorContent.String())
+	if err != nil {
+		return err
+	}
+	r.batch.ErrorFileID = errorFileId
+	r.batch.RequestCounts.Failed += failed
+
+	return r.save()
+}
+
+// markLost 已执行但结果没有写入的请求计为失败，不会重新执行
+func (r *batchRunner) markLost(executed int) {
+	counts := &r.batch.RequestCounts
+	if lost := executed - counts.Completed - counts.Failed; lost > 0 {
+		counts.Failed += lost
+	}
+}
+
+func (r *batchRunner) appendFile(fileId *string, kind, content string) (*string, error) {
+	if content == "" {
+		return fileId, nil
+	}
+
+	if fileId != nil {
+		file := &model.File{FileID: *fileId}
+		return fileId, file.AppendContent(content)
+	}
+
+	file := &model.File{
+		FileID:    "file-" + utils.GetUUID(),
+		UserId:    r.task.UserId,
+		Purpose:   types.FilePurposeBatchOutput,
+		Filename:  fmt.Sprintf("%s_%s.jsonl", r.batch.ID, kind),
+		Bytes:     len(content),
+		Content:   content,
+		CreatedAt: utils.GetTimestamp(),
+	}
+	if err := file.Insert(); err != nil {
+		return nil, err
+	}
+
+	return &file.FileID, nil
+}
+
+func (r *batchRunner) finish(status string, batchError *types.BatchError) {
+	now := utils.GetTimestamp()
+	r.batch.Status = status
+
+	switch status {
+	case types.BatchStatusCompleted:
+		r.batch.FinalizingAt = &now
+		r.batch.CompletedAt = &now
+		r.task.Status = model.TaskStatusSuccess
+	case types.BatchStatusFailed:
+		r.batch.FailedAt = &now
+		r.batch.Errors = &types.BatchErrors{Object: "list", Data: []types.BatchError{*batchError}}
+		r.task.Status = model.TaskStatusFailure
+		r.task.FailReason = batchError.Message
+	case types.BatchStatusExpired:
+		r.batch.ExpiredAt = &now
+		r.task.Status = model.TaskStatusFailure
+		r.task.FailReason = "批处理已过期"
+	case types.BatchStatusCancelled:
+		if r.batch.CancellingAt == nil {
+			r.batch.CancellingAt = &now
+		}
+		r.batch.CancelledAt = &now
+		r.task.Status = model.TaskStatusFailure
+		r.task.FailReason = "批处理已取消"
+	}
+
+	r.task.Progress = 100
+	r.task.FinishTime = now
+	r.save()
+
+	// 用户主动取消不算失败
+	if status == types.BatchStatusFailed || status == types.BatchStatusExpired {
+		r.task.SendFailedEvent(0)
+	}
+}
+
+func (r *batchRunner) save() error {
+	// 执行期间可能收到了取消请求，保留取消状态
+	if !isFinalStatus(r.batch.Status) {
+		latest, err := model.GetTaskByTaskId(model.TaskPlatformBatch, r.task.UserId, r.task.TaskID)
+		if err == nil && latest != nil && latest.Status == model.TaskStatusCancelling {
+			r.task.Status = model.TaskStatusCancelling
+		}
+	}
+
+	data, err := json.Marshal(r.batch)
+	if err != nil {
+		logger.SysError("marshal batch error: " + err.Error())
+		return err
+	}
+	r.task.Data = data
+
+	if err := r.task.Update(); err != nil {
+		logger.SysError("UpdateTask task error: " + err.Error())
+		return err
+	}
+
+	return nil
+}
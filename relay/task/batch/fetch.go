@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"net/http"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RetrieveBatch(c *gin.Context) {
+	task := getUserBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskModel2Batch(task))
+}
+
+func ListBatches(c *gin.Context) {
+	userId := c.GetInt("id")
+
+	limit := utils.String2Int(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	tasks, err := model.GetUserTasksByPlatform(model.TaskPlatformBatch, userId, limit)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := types.OpenAIList[*types.Batch]{
+		Object: "list",
+		Data:   make([]*types.Batch, 0, len(tasks)),
+	}
+	for _, task := range tasks {
+		if batch := TaskModel2Batch(task); batch != nil {
+			response.Data = append(response.Data, batch)
+		}
+	}
+
+	if len(response.Data) > 0 {
+		response.FirstID = response.Data[0].ID
+		response.LastID = response.Data[len(response.Data)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelBatch 只标记任务状态，由任务轮询完成取消
+func CancelBatch(c *gin.Context) {
+	task := getUserBatchTask(c)
+	if task == nil {
+		return
+	}
+
+	batch := TaskModel2Batch(task)
+	if batch == nil {
+		StringError(c, http.StatusInternalServerError, "invalid batch data")
+		return
+	}
+
+	if isFinalStatus(batch.Status) {
+		StringError(c, http.StatusBadRequest, "batch is already "+batch.Status)
+		return
+	}
+
+	err := model.TaskBulkUpdateByID([]int64{task.ID}, map[string]any{
+		"status": model.TaskStatusCancelling,
+	})
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := utils.GetTimestamp()
+	batch.Status = types.BatchStatusCancelling
+	batch.CancellingAt = &now
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func getUserBatchTask(c *gin.Context) *model.Task {
+	task, err := model.GetTaskByTaskId(model.TaskPlatformBatch, c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if task == nil {
+		StringError(c, http.StatusNotFound, "batch not found")
+		return nil
+	}
+
+	return task
+}
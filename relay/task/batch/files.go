@@ -0,0 +1,156 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func UploadFile(c *gin.Context) {
+	userId := c.GetInt("id")
+
+	purpose := c.PostForm("purpose")
+	if purpose != types.FilePurposeBatch {
+		StringError(c, http.StatusBadRequest, "only batch purpose is supported")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		StringError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	maxSize := int64(viper.GetInt("batch.max_file_size")) * 1024 * 1024
+	if fileHeader.Size > maxSize {
+		StringError(c, http.StatusBadRequest, fmt.Sprintf("file size exceeds the limit of %d MB", viper.GetInt("batch.max_file_size")))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		StringError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		StringError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 只做基本的格式校验，具体内容在创建批处理时校验
+	for i, line := range splitLines(string(content)) {
+		if !json.Valid([]byte(line)) {
+			StringError(c, http.StatusBadRequest, fmt.Sprintf("line %d is not a valid JSON", i+1))
+			return
+		}
+	}
+
+	fileModel := &model.File{
+		FileID:    "file-" + utils.GetUUID(),
+		UserId:    userId,
+		Purpose:   purpose,
+		Filename:  fileHeader.Filename,
+		Bytes:     len(content),
+		Content:   string(content),
+		CreatedAt: utils.GetTimestamp(),
+	}
+
+	if err := fileModel.Insert(); err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, FileModel2OpenAI(fileModel))
+}
+
+func ListFiles(c *gin.Context) {
+	userId := c.GetInt("id")
+
+	limit := utils.String2Int(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 10000 {
+		limit = 100
+	}
+
+	files, err := model.GetUserFiles(userId, c.Query("purpose"), limit)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := types.OpenAIList[*types.OpenAIFile]{
+		Object: "list",
+		Data:   make([]*types.OpenAIFile, 0, len(files)),
+	}
+	for _, file := range files {
+		response.Data = append(response.Data, FileModel2OpenAI(file))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, FileModel2OpenAI(file))
+}
+
+func RetrieveFileContent(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	content, err := model.GetFileContent(file.FileID)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", []byte(content))
+}
+
+func DeleteFile(c *gin.Context) {
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	if err := file.Delete(); err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, types.OpenAIFileDeleted{
+		ID:      file.FileID,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+func getUserFile(c *gin.Context) *model.File {
+	file, err := model.GetFileByFileId(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if file == nil {
+		StringError(c, http.StatusNotFound, "file not found")
+		return nil
+	}
+
+	return file
+}
@@ -0,0 +1,146 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/providers/base"
+	taskBase "one-api/relay/task/base"
+	"one-api/types"
+	"time"
+)
+
+const batchMaxRequests = 50000
+
+type BatchTask struct {
+	taskBase.TaskBase
+	Request *types.BatchCreateRequest
+	total   int
+}
+
+func (t *BatchTask) HandleError(err *taskBase.TaskError) {
+	HandleTaskError(t.C, err)
+}
+
+func (t *BatchTask) Init() *taskBase.TaskError {
+	if err := common.UnmarshalBodyReusable(t.C, &t.Request); err != nil {
+		return taskBase.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	if !isAllowEndpoint(t.Request.Endpoint) {
+		return taskBase.StringTaskError(http.StatusBadRequest, "invalid_request", "unsupported endpoint: "+t.Request.Endpoint, true)
+	}
+
+	if t.Request.CompletionWindow != "24h" {
+		return taskBase.StringTaskError(http.StatusBadRequest, "invalid_request", "completion_window only supports 24h", true)
+	}
+
+	file, err := model.GetFileByFileId(t.C.GetInt("id"), t.Request.InputFileID)
+	if err != nil {
+		return taskBase.StringTaskError(http.StatusInternalServerError, "get_file_failed", err.Error(), true)
+	}
+	if file == nil || file.Purpose != types.FilePurposeBatch {
+		return taskBase.StringTaskError(http.StatusBadRequest, "invalid_request", "input file not found", true)
+	}
+
+	content, err := model.GetFileContent(file.FileID)
+	if err != nil {
+		return taskBase.StringTaskError(http.StatusInternalServerError, "get_file_failed", err.Error(), true)
+	}
+
+	if err := t.validateRequests(splitLines(content)); err != nil {
+		return taskBase.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	return nil
+}
+
+func (t *BatchTask) validateRequests(lines []string) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("input file is empty")
+	}
+
+	if len(lines) > batchMaxRequests {
+		return fmt.Errorf("input file exceeds the limit of %d requests", batchMaxRequests)
+	}
+
+	customIds := make(map[string]bool, len(lines))
+	for i, line := range lines {
+		var input types.BatchRequestInput
+		if err := json.Unmarshal([]byte(line), &input); err != nil {
+			return fmt.Errorf("line %d: %s", i+1, err.Error())
+		}
+
+		if input.CustomID == "" {
+			return fmt.Errorf("line %d: custom_id is required", i+1)
+		}
+		if customIds[input.CustomID] {
+			return fmt.Errorf("line %d: duplicate custom_id %s", i+1, input.CustomID)
+		}
+		customIds[input.CustomID] = true
+
+		if input.Method != http.MethodPost {
+			return fmt.Errorf("line %d: method only supports POST", i+1)
+		}
+		if input.URL != t.Request.Endpoint {
+			return fmt.Errorf("line %d: url must be %s", i+1, t.Request.Endpoint)
+		}
+	}
+
+	t.total = len(lines)
+
+	return nil
+}
+
+// SetProvider 批处理中的每个请求都会单独选择渠道
+func (t *BatchTask) SetProvider() *taskBase.TaskError {
+	return nil
+}
+
+func (t *BatchTask) GetProvider() base.ProviderInterface {
+	return nil
+}
+
+func (t *BatchTask) ShouldRetry(err *taskBase.TaskError) bool {
+	return false
+}
+
+func (t *BatchTask) Relay() *taskBase.TaskError {
+	now := time.Now().Unix()
+
+	batch := &types.Batch{
+		ID:               "batch_" + utils.GetUUID(),
+		Object:           "batch",
+		Endpoint:         t.Request.Endpoint,
+		InputFileID:      t.Request.InputFileID,
+		CompletionWindow: t.Request.CompletionWindow,
+		Status:           types.BatchStatusValidating,
+		CreatedAt:        now,
+		ExpiresAt:        now + int64(24*time.Hour/time.Second),
+		RequestCounts: types.BatchRequestCounts{
+			Total: t.total,
+		},
+		Metadata: t.Request.Metadata,
+	}
+
+	t.InitTask()
+	t.Task.TaskID = batch.ID
+	t.Task.Action = "BATCH"
+
+	properties, _ := json.Marshal(BatchProperties{TokenId: t.C.GetInt("token_id")})
+	t.Task.Properties = properties
+
+	data, _ := json.Marshal(batch)
+	t.Task.Data = data
+
+	if err := t.Task.Insert(); err != nil {
+		return taskBase.StringTaskError(http.StatusInternalServerError, "insert_task_failed", err.Error(), true)
+	}
+
+	t.C.JSON(http.StatusOK, batch)
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"one-api/common/config"
 	"one-api/model"
 	"one-api/relay/task/base"
+	"one-api/relay/task/batch"
 	"one-api/relay/task/suno"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,10 @@ func GetTaskAdaptor(relayType int, c *gin.Context) (base.TaskInterface, error) {
 		return &suno.SunoTask{
 			TaskBase: getTaskBase(c, model.TaskPlatformSuno),
 		}, nil
+	case config.RelayModeBatch:
+		return &batch.BatchTask{
+			TaskBase: getTaskBase(c, model.TaskPlatformBatch),
+		}, nil
 	default:
 		return nil, errors.New("adaptor not found")
 	}
@@ -27,6 +32,8 @@ func GetTaskAdaptorByPlatform(platform string) (base.TaskInterface, error) {
 	switch platform {
 	case model.TaskPlatformSuno:
 		relayType = config.RelayModeSuno
+	case model.TaskPlatformBatch:
+		relayType = config.RelayModeBatch
 	}
 
 	return GetTaskAdaptor(relayType, nil)
@@ -227,6 +227,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 				logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(quota))
 				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
 			}
+			task.SendFailedEvent(quota)
 		}
 
 		if responseItem.Status == model.TaskStatusSuccess {
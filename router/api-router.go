@@ -95,6 +95,7 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.PUT("/batch/del_model", controller.BatchDelModelChannels)
 			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
 			channelRoute.DELETE("/:id/tag", controller.DeleteChannelTag)
+			channelRoute.DELETE("/:id/circuit_breaker", controller.ResetChannelCircuitBreaker)
 			channelRoute.DELETE("/:id", controller.DeleteChannel)
 		}
 		channelTagRoute := apiRouter.Group("/channel_tag")
@@ -118,6 +119,35 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
 		}
+		chatCacheRoute := apiRouter.Group("/chat_cache")
+		chatCacheRoute.Use(middleware.AdminAuth())
+		{
+			chatCacheRoute.GET("/", controller.GetChatCacheList)
+			chatCacheRoute.GET("/stats", controller.GetChatCacheStats)
+			chatCacheRoute.DELETE("/stats", controller.ResetChatCacheStats)
+			chatCacheRoute.DELETE("/", controller.FlushChatCache)
+			chatCacheRoute.DELETE("/user/:id", controller.DeleteUserChatCache)
+			chatCacheRoute.DELETE("/:hash", controller.DeleteChatCache)
+		}
+		organizationRoute := apiRouter.Group("/organization")
+		organizationRoute.Use(middleware.UserAuth())
+		{
+			organizationRoute.GET("/", controller.GetUserOrganizations)
+			organizationRoute.POST("/", controller.CreateOrganization)
+			organizationRoute.GET("/:id", controller.GetOrganization)
+			organizationRoute.PUT("/:id", controller.UpdateOrganization)
+			organizationRoute.GET("/:id/member", controller.GetOrganizationMembers)
+			organizationRoute.POST("/:id/member", controller.AddOrganizationMember)
+			organizationRoute.PUT("/:id/member/:user_id", controller.UpdateOrganizationMember)
+			organizationRoute.DELETE("/:id/member/:user_id", controller.RemoveOrganizationMember)
+			organizationRoute.GET("/:id/statistics", controller.GetOrganizationStatistics)
+		}
+		organizationAdminRoute := apiRouter.Group("/organization_admin")
+		organizationAdminRoute.Use(middleware.AdminAuth())
+		{
+			organizationAdminRoute.GET("/", controller.GetOrganizationsList)
+			organizationAdminRoute.PUT("/:id/quota", controller.UpdateOrganizationQuota)
+		}
 		redemptionRoute := apiRouter.Group("/redemption")
 		redemptionRoute.Use(middleware.AdminAuth())
 		{
@@ -135,6 +165,18 @@ func SetApiRouter(router *gin.Engine) {
 		// logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogsList)
 		// logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
+		auditLogRoute := apiRouter.Group("/audit_log")
+		auditLogRoute.Use(middleware.AdminAuth())
+		{
+			auditLogRoute.GET("/", controller.GetAuditLogsList)
+			auditLogRoute.GET("/:id", controller.GetAuditLog)
+		}
+		webhookDeliveryRoute := apiRouter.Group("/webhook_delivery")
+		webhookDeliveryRoute.Use(middleware.AdminAuth())
+		{
+			webhookDeliveryRoute.GET("/", controller.GetWebhookDeliveriesList)
+			webhookDeliveryRoute.GET("/:id", controller.GetWebhookDelivery)
+		}
 		groupRoute := apiRouter.Group("/group")
 		groupRoute.Use(middleware.AdminAuth())
 		{
@@ -16,6 +16,7 @@ func SetRouter(router *gin.Engine, buildFS embed.FS, indexPage []byte) {
 	SetApiRouter(router)
 	SetDashboardRouter(router)
 	SetRelayRouter(router)
+	SetMetricsRouter(router)
 	frontendBaseUrl := viper.GetString("frontend_base_url")
 	if config.IsMasterNode && frontendBaseUrl != "" {
 		frontendBaseUrl = ""
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	if !viper.GetBool("metrics.enabled") {
+		return
+	}
+
+	handlers := []gin.HandlerFunc{}
+	// 设置了用户名和密码时使用 basic auth 保护
+	username := viper.GetString("metrics.username")
+	password := viper.GetString("metrics.password")
+	if username != "" && password != "" {
+		handlers = append(handlers, gin.BasicAuth(gin.Accounts{username: password}))
+	}
+	handlers = append(handlers, gin.WrapH(promhttp.Handler()))
+
+	router.GET("/metrics", handlers...)
+}
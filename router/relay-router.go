@@ -5,6 +5,7 @@ import (
 	"one-api/relay"
 	"one-api/relay/midjourney"
 	"one-api/relay/task"
+	"one-api/relay/task/batch"
 	"one-api/relay/task/suno"
 
 	"github.com/gin-gonic/gin"
@@ -28,31 +29,42 @@ func setOpenAIRouter(router *gin.Engine) {
 		modelsRouter.GET("/:model", relay.RetrieveModel)
 	}
 	relayV1Router := router.Group("/v1")
-	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute())
+	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.TokenRateLimit(), middleware.AuditLog())
 	{
 		relayV1Router.POST("/completions", relay.Relay)
 		relayV1Router.POST("/chat/completions", relay.Relay)
+		relayV1Router.POST("/responses", relay.Relay)
 		// relayV1Router.POST("/edits", controller.Relay)
 		relayV1Router.POST("/images/generations", relay.Relay)
 		relayV1Router.POST("/images/edits", relay.Relay)
 		relayV1Router.POST("/images/variations", relay.Relay)
 		relayV1Router.POST("/embeddings", relay.Relay)
+		relayV1Router.POST("/rerank", relay.Relay)
 		// relayV1Router.POST("/engines/:model/embeddings", controller.RelayEmbeddings)
 		relayV1Router.POST("/audio/transcriptions", relay.Relay)
 		relayV1Router.POST("/audio/translations", relay.Relay)
 		relayV1Router.POST("/audio/speech", relay.Relay)
 		relayV1Router.POST("/moderations", relay.Relay)
+		relayV1Router.GET("/realtime", relay.RelayRealtime)
+
+		// 未指定渠道时由系统管理批处理，指定渠道时直接转发
+		relayV1Router.POST("/files", batch.Passthrough(batch.UploadFile))
+		relayV1Router.GET("/files", batch.Passthrough(batch.ListFiles))
+		relayV1Router.GET("/files/:id", batch.Passthrough(batch.RetrieveFile))
+		relayV1Router.DELETE("/files/:id", batch.Passthrough(batch.DeleteFile))
+		relayV1Router.GET("/files/:id/content", batch.Passthrough(batch.RetrieveFileContent))
+		relayV1Router.POST("/batches", batch.Passthrough(task.RelayBatchSubmit))
+		relayV1Router.GET("/batches", batch.Passthrough(batch.ListBatches))
+		relayV1Router.GET("/batches/:id", batch.Passthrough(batch.RetrieveBatch))
+		relayV1Router.POST("/batches/:id/cancel", batch.Passthrough(batch.CancelBatch))
 
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
 			relayV1Router.Any("/assistants", relay.RelayOnly)
 			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
 			relayV1Router.Any("/threads", relay.RelayOnly)
 			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
 			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
@@ -72,7 +84,7 @@ func setMJRouter(router *gin.Engine) {
 // Path: router/relay-router.go
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", midjourney.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.RelayMJPanicRecover(), middleware.MjAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.RelayMJPanicRecover(), middleware.MjAuth(), middleware.Distribute(), middleware.TokenRateLimit())
 	{
 		relayMjRouter.POST("/submit/action", midjourney.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", midjourney.RelayMidjourney)
@@ -93,7 +105,7 @@ func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 
 func setSunoRouter(router *gin.Engine) {
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.RelaySunoPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.RelaySunoPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.TokenRateLimit())
 	{
 		relaySunoRouter.POST("/submit/:action", task.RelayTaskSubmit)
 		relaySunoRouter.POST("/fetch", suno.GetFetch)
@@ -104,7 +116,7 @@ func setSunoRouter(router *gin.Engine) {
 func setClaudeRouter(router *gin.Engine) {
 	relayClaudeRouter := router.Group("/claude")
 	relayV1Router := relayClaudeRouter.Group("/v1")
-	relayV1Router.Use(middleware.RelayCluadePanicRecover(), middleware.ClaudeAuth(), middleware.Distribute())
+	relayV1Router.Use(middleware.RelayCluadePanicRecover(), middleware.ClaudeAuth(), middleware.Distribute(), middleware.TokenRateLimit(), middleware.AuditLog())
 	{
 		relayV1Router.POST("/messages", relay.RelaycClaudeOnly)
 	}
@@ -113,7 +125,7 @@ func setClaudeRouter(router *gin.Engine) {
 func setGeminiRouter(router *gin.Engine) {
 	relayGeminiRouter := router.Group("/gemini")
 	relayV1Router := relayGeminiRouter.Group("/v1beta")
-	relayV1Router.Use(middleware.RelayGeminiPanicRecover(), middleware.GeminiAuth(), middleware.Distribute())
+	relayV1Router.Use(middleware.RelayGeminiPanicRecover(), middleware.GeminiAuth(), middleware.Distribute(), middleware.TokenRateLimit(), middleware.AuditLog())
 	{
 		relayV1Router.POST("/models/:model", relay.RelaycGeminiOnly)
 	}
@@ -3,9 +3,83 @@ package types
 import "encoding/json"
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+	SearchUnits             int                      `json:"search_units,omitempty"`
+}
+
+type PromptTokensDetails struct {
+	AudioTokens  int `json:"audio_tokens,omitempty"`
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	// 写入缓存的 tokens，OpenAI 没有这个字段，用于 Claude 的 cache_creation_input_tokens
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+}
+
+type CompletionTokensDetails struct {
+	AudioTokens     int `json:"audio_tokens,omitempty"`
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+}
+
+// GetPromptAudioTokens 输入中的音频 tokens
+func (u *Usage) GetPromptAudioTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.AudioTokens
+}
+
+// GetCompletionAudioTokens 输出中的音频 tokens
+func (u *Usage) GetCompletionAudioTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.AudioTokens
+}
+
+func (u *Usage) GetPromptCachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
+
+func (u *Usage) GetPromptCacheWriteTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CacheWriteTokens
+}
+
+func (u *Usage) GetCompletionReasoningTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.ReasoningTokens
+}
+
+// SetPromptCacheTokens 设置缓存命中和写入缓存的 tokens
+func (u *Usage) SetPromptCacheTokens(cachedTokens, cacheWriteTokens int) {
+	if cachedTokens == 0 && cacheWriteTokens == 0 {
+		return
+	}
+	if u.PromptTokensDetails == nil {
+		u.PromptTokensDetails = &PromptTokensDetails{}
+	}
+	u.PromptTokensDetails.CachedTokens = cachedTokens
+	u.PromptTokensDetails.CacheWriteTokens = cacheWriteTokens
+}
+
+func (u *Usage) SetCompletionReasoningTokens(reasoningTokens int) {
+	if reasoningTokens == 0 {
+		return
+	}
+	if u.CompletionTokensDetails == nil {
+		u.CompletionTokensDetails = &CompletionTokensDetails{}
+	}
+	u.CompletionTokensDetails.ReasoningTokens = reasoningTokens
 }
 
 type OpenAIError struct {
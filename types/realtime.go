@@ -0,0 +1,53 @@
+package types
+
+const (
+	RealtimeEventTypeError        = "error"
+	RealtimeEventTypeResponseDone = "response.done"
+)
+
+type RealtimeEvent struct {
+	EventId  string            `json:"event_id,omitempty"`
+	Type     string            `json:"type"`
+	Response *RealtimeResponse `json:"response,omitempty"`
+	Error    *OpenAIError      `json:"error,omitempty"`
+}
+
+type RealtimeResponse struct {
+	Id     string         `json:"id"`
+	Status string         `json:"status"`
+	Usage  *RealtimeUsage `json:"usage,omitempty"`
+}
+
+type RealtimeUsage struct {
+	TotalTokens        int                        `json:"total_tokens"`
+	InputTokens        int                        `json:"input_tokens"`
+	OutputTokens       int                        `json:"output_tokens"`
+	InputTokenDetails  RealtimeInputTokenDetails  `json:"input_token_details"`
+	OutputTokenDetails RealtimeOutputTokenDetails `json:"output_token_details"`
+}
+
+type RealtimeInputTokenDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+	TextTokens   int `json:"text_tokens"`
+	AudioTokens  int `json:"audio_tokens"`
+}
+
+type RealtimeOutputTokenDetails struct {
+	TextTokens  int `json:"text_tokens"`
+	AudioTokens int `json:"audio_tokens"`
+}
+
+func (u *RealtimeUsage) ToOpenAIUsage() *Usage {
+	return &Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+		PromptTokensDetails: &PromptTokensDetails{
+			AudioTokens:  u.InputTokenDetails.AudioTokens,
+			CachedTokens: u.InputTokenDetails.CachedTokens,
+		},
+		CompletionTokensDetails: &CompletionTokensDetails{
+			AudioTokens: u.OutputTokenDetails.AudioTokens,
+		},
+	}
+}
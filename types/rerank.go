@@ -0,0 +1,47 @@
+package types
+
+type RerankRequest struct {
+	Model           string `json:"model" binding:"required"`
+	Query           string `json:"query" binding:"required"`
+	Documents       []any  `json:"documents" binding:"required"`
+	TopN            int    `json:"top_n,omitempty"`
+	ReturnDocuments *bool  `json:"return_documents,omitempty"`
+}
+
+type RerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       any     `json:"document,omitempty"`
+}
+
+type RerankResponse struct {
+	ID      string         `json:"id,omitempty"`
+	Model   string         `json:"model"`
+	Results []RerankResult `json:"results"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+// ParseDocuments 文档可以是字符串，也可以是包含 text 字段的对象
+func (r RerankRequest) ParseDocuments() []string {
+	documents := make([]string, 0, len(r.Documents))
+	for _, document := range r.Documents {
+		switch doc := document.(type) {
+		case string:
+			documents = append(documents, doc)
+		case map[string]any:
+			if text, ok := doc["text"].(string); ok {
+				documents = append(documents, text)
+			}
+		}
+	}
+	return documents
+}
+
+// GetSearchUnits 按 Cohere 的规则计算搜索次数，每 100 个文档计为一次搜索
+func (r RerankRequest) GetSearchUnits() int {
+	units := (len(r.Documents) + 99) / 100
+	if units < 1 {
+		units = 1
+	}
+	return units
+}
@@ -0,0 +1,208 @@
+package types
+
+const (
+	ResponsesItemTypeMessage            = "message"
+	ResponsesItemTypeFunctionCall       = "function_call"
+	ResponsesItemTypeFunctionCallOutput = "function_call_output"
+)
+
+const (
+	ResponsesContentTypeInputText  = "input_text"
+	ResponsesContentTypeInputImage = "input_image"
+	ResponsesContentTypeOutputText = "output_text"
+	ResponsesContentTypeRefusal    = "refusal"
+)
+
+const (
+	ResponsesStatusInProgress = "in_progress"
+	ResponsesStatusCompleted  = "completed"
+	ResponsesStatusIncomplete = "incomplete"
+	ResponsesStatusFailed     = "failed"
+)
+
+const (
+	ResponsesEventCreated               = "response.created"
+	ResponsesEventInProgress            = "response.in_progress"
+	ResponsesEventCompleted             = "response.completed"
+	ResponsesEventIncomplete            = "response.incomplete"
+	ResponsesEventFailed                = "response.failed"
+	ResponsesEventOutputItemAdded       = "response.output_item.added"
+	ResponsesEventOutputItemDone        = "response.output_item.done"
+	ResponsesEventContentPartAdded      = "response.content_part.added"
+	ResponsesEventContentPartDone       = "response.content_part.done"
+	ResponsesEventOutputTextDelta       = "response.output_text.delta"
+	ResponsesEventOutputTextDone        = "response.output_text.done"
+	ResponsesEventFunctionCallArgsDelta = "response.function_call_arguments.delta"
+	ResponsesEventFunctionCallArgsDone  = "response.function_call_arguments.done"
+)
+
+const (
+	ResponsesIncompleteReasonMaxTokens     = "max_output_tokens"
+	ResponsesIncompleteReasonContentFilter = "content_filter"
+)
+
+type OpenAIResponsesRequest struct {
+	Model              string            `json:"model" binding:"required"`
+	Input              any               `json:"input,omitempty"`
+	Instructions       string            `json:"instructions,omitempty"`
+	MaxOutputTokens    int               `json:"max_output_tokens,omitempty"`
+	Temperature        float64           `json:"temperature,omitempty"`
+	TopP               float64           `json:"top_p,omitempty"`
+	Stream             bool              `json:"stream,omitempty"`
+	Tools              []*ResponsesTool  `json:"tools,omitempty"`
+	ToolChoice         any               `json:"tool_choice,omitempty"`
+	ParallelToolCalls  *bool             `json:"parallel_tool_calls,omitempty"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Store              *bool             `json:"store,omitempty"`
+	Text               *ResponsesText    `json:"text,omitempty"`
+	User               string            `json:"user,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// IsStore 默认会保存响应，用于 previous_response_id 续接对话
+func (r *OpenAIResponsesRequest) IsStore() bool {
+	return r.Store == nil || *r.Store
+}
+
+type ResponsesTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+type ResponsesText struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+type ResponsesTextFormat struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema,omitempty"`
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+type ResponsesInputItem struct {
+	Type      string `json:"type,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Content   any    `json:"content,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    any    `json:"output,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+type ResponsesInputContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type OpenAIResponsesResponse struct {
+	ID                 string                      `json:"id"`
+	Object             string                      `json:"object"`
+	CreatedAt          int64                       `json:"created_at"`
+	Status             string                      `json:"status"`
+	Error              *OpenAIError                `json:"error"`
+	IncompleteDetails  *ResponsesIncompleteDetails `json:"incomplete_details"`
+	Instructions       string                      `json:"instructions,omitempty"`
+	MaxOutputTokens    int                         `json:"max_output_tokens,omitempty"`
+	Model              string                      `json:"model"`
+	Output             []*ResponsesOutputItem      `json:"output"`
+	ParallelToolCalls  bool                        `json:"parallel_tool_calls"`
+	PreviousResponseID string                      `json:"previous_response_id,omitempty"`
+	Store              bool                        `json:"store"`
+	Temperature        float64                     `json:"temperature,omitempty"`
+	TopP               float64                     `json:"top_p,omitempty"`
+	ToolChoice         any                         `json:"tool_choice,omitempty"`
+	Tools              []*ResponsesTool            `json:"tools"`
+	Text               *ResponsesText              `json:"text,omitempty"`
+	User               string                      `json:"user,omitempty"`
+	Metadata           map[string]string           `json:"metadata,omitempty"`
+	Usage              *ResponsesUsage             `json:"usage,omitempty"`
+}
+
+type ResponsesIncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+type ResponsesOutputItem struct {
+	Type      string                    `json:"type"`
+	ID        string                    `json:"id"`
+	Status    string                    `json:"status,omitempty"`
+	Role      string                    `json:"role,omitempty"`
+	Content   []*ResponsesOutputContent `json:"content,omitempty"`
+	CallID    string                    `json:"call_id,omitempty"`
+	Name      string                    `json:"name,omitempty"`
+	Arguments *string                   `json:"arguments,omitempty"`
+}
+
+type ResponsesOutputContent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations"`
+}
+
+type ResponsesUsage struct {
+	InputTokens         int                          `json:"input_tokens"`
+	InputTokensDetails  ResponsesInputTokensDetails  `json:"input_tokens_details"`
+	OutputTokens        int                          `json:"output_tokens"`
+	OutputTokensDetails ResponsesOutputTokensDetails `json:"output_tokens_details"`
+	TotalTokens         int                          `json:"total_tokens"`
+}
+
+type ResponsesInputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type ResponsesOutputTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+func (u *Usage) ToResponsesUsage() *ResponsesUsage {
+	if u == nil {
+		return nil
+	}
+
+	return &ResponsesUsage{
+		InputTokens: u.PromptTokens,
+		InputTokensDetails: ResponsesInputTokensDetails{
+			CachedTokens: u.GetPromptCachedTokens(),
+		},
+		OutputTokens: u.CompletionTokens,
+		OutputTokensDetails: ResponsesOutputTokensDetails{
+			ReasoningTokens: u.GetCompletionReasoningTokens(),
+		},
+		TotalTokens: u.PromptTokens + u.CompletionTokens,
+	}
+}
+
+func (r *OpenAIResponsesResponse) GetContent() string {
+	var content string
+	for _, item := range r.Output {
+		for _, part := range item.Content {
+			content += part.Text
+		}
+	}
+	return content
+}
+
+type ResponsesStreamEvent struct {
+	Type           string                   `json:"type"`
+	SequenceNumber int                      `json:"sequence_number"`
+	Response       *OpenAIResponsesResponse `json:"response,omitempty"`
+	OutputIndex    *int                     `json:"output_index,omitempty"`
+	ContentIndex   *int                     `json:"content_index,omitempty"`
+	ItemID         string                   `json:"item_id,omitempty"`
+	Item           *ResponsesOutputItem     `json:"item,omitempty"`
+	Part           *ResponsesOutputContent  `json:"part,omitempty"`
+	Delta          *string                  `json:"delta,omitempty"`
+	Text           *string                  `json:"text,omitempty"`
+	Arguments      *string                  `json:"arguments,omitempty"`
+}